@@ -0,0 +1,36 @@
+package mmapforge
+
+// Backing is the memory a Store keeps its header and records in.
+//
+// The base address must not change for the lifetime of the Backing:
+// stores hand out zero-copy slices and atomic pointers into it, so Grow
+// has to extend the usable range in place. *Region implements Backing
+// for files and *MemoryRegion for anonymous process memory.
+type Backing interface {
+	// Base returns the fixed start address of the backing memory.
+	Base() uintptr
+
+	// Slice returns the byte range [offset, offset+n).
+	Slice(offset, n int) []byte
+
+	// Mapped returns the number of usable bytes from Base.
+	Mapped() int
+
+	// Grow makes at least minSize bytes usable. Must be externally
+	// serialized.
+	Grow(minSize int) error
+
+	// Sync flushes written data to stable storage, if there is any.
+	Sync() error
+
+	// Close releases the memory. All slices into it become invalid.
+	Close() error
+
+	// Name identifies the backing in error messages.
+	Name() string
+}
+
+var (
+	_ Backing = (*Region)(nil)
+	_ Backing = (*MemoryRegion)(nil)
+)
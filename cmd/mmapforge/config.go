@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/CreditWorthy/mmapforge/internal/codegen"
+)
+
+// configNames are looked up in the working directory when -config is not
+// given, in this order.
+var configNames = []string{"mmapforge.yaml", "mmapforge.yml", "mmapforge.toml"}
+
+// fileConfig is the on-disk form of mmapforge.yaml or mmapforge.toml:
+//
+//	header: |
+//	  // Code generated by mmapforge. DO NOT EDIT.
+//	build_tag: linux && amd64
+//	templates:
+//	  - templates/*.tmpl
+//	features:
+//	  record: false
+//	plugins:
+//	  - ./tools/metrics
+//	types:
+//	  Tick:
+//	    store: TickTable
+//	    file: tick_table.go
+//	    skip_tests: true
+type fileConfig struct {
+	Header    string                    `yaml:"header" toml:"header"`
+	BuildTag  string                    `yaml:"build_tag" toml:"build_tag"`
+	Templates []string                  `yaml:"templates" toml:"templates"`
+	Features  map[string]bool           `yaml:"features" toml:"features"`
+	Types     map[string]fileTypeConfig `yaml:"types" toml:"types"`
+	Plugins   []string                  `yaml:"plugins" toml:"plugins"`
+
+	// path is the file the config was read from; template globs are
+	// relative to its directory.
+	path string
+}
+
+type fileTypeConfig struct {
+	Store     string `yaml:"store" toml:"store"`
+	File      string `yaml:"file" toml:"file"`
+	TestFile  string `yaml:"test_file" toml:"test_file"`
+	SkipTests bool   `yaml:"skip_tests" toml:"skip_tests"`
+}
+
+// loadConfig reads the config file at path, or the first of configNames in
+// the working directory when path is empty. Without a config file it
+// returns an empty config.
+func loadConfig(path string) (*fileConfig, error) {
+	if path == "" {
+		for _, name := range configNames {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return &fileConfig{}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fc := &fileConfig{path: path}
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(fc); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), fc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if keys := md.Undecoded(); len(keys) > 0 {
+			return nil, fmt.Errorf("%s: unknown key %s", path, keys[0])
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported config format %q (want .yaml, .yml or .toml)", path, ext)
+	}
+	fc.resolvePlugins()
+	return fc, nil
+}
+
+// resolvePlugins makes relative plugin paths (./tools/x, ../x) relative to
+// the config file instead of the working directory. Import paths are kept.
+func (fc *fileConfig) resolvePlugins() {
+	for i, p := range fc.Plugins {
+		if !strings.HasPrefix(p, "./") && !strings.HasPrefix(p, "../") {
+			continue
+		}
+		p = filepath.Join(filepath.Dir(fc.path), p)
+		if !filepath.IsAbs(p) && !strings.HasPrefix(p, ".") {
+			p = "." + string(filepath.Separator) + p
+		}
+		fc.Plugins[i] = p
+	}
+}
+
+// codegenConfig converts fc into the base codegen config shared by every
+// generated package, parsing the template globs.
+func (fc *fileConfig) codegenConfig() (*codegen.Config, error) {
+	c := &codegen.Config{
+		Header:   strings.TrimRight(fc.Header, "\n"),
+		BuildTag: fc.BuildTag,
+		Features: fc.Features,
+	}
+	for _, pattern := range fc.Templates {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(fc.path), pattern)
+		}
+		t, err := codegen.NewTemplate("external").ParseGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: templates: %w", fc.path, err)
+		}
+		c.Templates = append(c.Templates, t)
+	}
+	if len(fc.Types) > 0 {
+		c.Types = make(map[string]codegen.TypeConfig, len(fc.Types))
+		for name, tc := range fc.Types {
+			c.Types[name] = codegen.TypeConfig(tc)
+		}
+	}
+	return c, nil
+}
+
+// checkTypes reports per-type overrides naming no generated schema struct,
+// which are most likely typos.
+func (fc *fileConfig) checkTypes(graphs []*codegen.Graph) error {
+	known := make(map[string]bool)
+	for _, g := range graphs {
+		for _, n := range g.Nodes {
+			known[n.Name] = true
+		}
+	}
+	var unknown []string
+	for name := range fc.Types {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("%s: types: no mmapforge:schema struct named %s", fc.path, strings.Join(unknown, ", "))
+}
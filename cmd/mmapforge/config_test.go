@@ -0,0 +1,247 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+	"github.com/CreditWorthy/mmapforge/internal/codegen"
+)
+
+const yamlConfig = `header: |
+  // Generated. DO NOT EDIT.
+build_tag: linux
+templates:
+  - tmpl/*.tmpl
+features:
+  record: false
+types:
+  Tick:
+    store: TickTable
+    file: tick_table.go
+    test_file: tick_table_gen_test.go
+    skip_tests: true
+`
+
+const tomlConfig = `header = """
+// Generated. DO NOT EDIT.
+"""
+build_tag = "linux"
+templates = ["tmpl/*.tmpl"]
+
+[features]
+record = false
+
+[types.Tick]
+store = "TickTable"
+file = "tick_table.go"
+test_file = "tick_table_gen_test.go"
+skip_tests = true
+`
+
+func writeConfigFile(t *testing.T, dir, name, src string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_Formats(t *testing.T) {
+	want := &fileConfig{
+		Header:    "// Generated. DO NOT EDIT.\n",
+		BuildTag:  "linux",
+		Templates: []string{"tmpl/*.tmpl"},
+		Features:  map[string]bool{"record": false},
+		Types: map[string]fileTypeConfig{
+			"Tick": {Store: "TickTable", File: "tick_table.go", TestFile: "tick_table_gen_test.go", SkipTests: true},
+		},
+	}
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"mmapforge.yaml": yamlConfig,
+		"mmapforge.yml":  yamlConfig,
+		"mmapforge.toml": tomlConfig,
+	} {
+		path := writeConfigFile(t, dir, name, src)
+		fc, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want.path = path
+		if !reflect.DeepEqual(fc, want) {
+			t.Errorf("%s: config = %+v, want %+v", name, fc, want)
+		}
+	}
+}
+
+func TestLoadConfig_Default(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	fc, err := loadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fc, &fileConfig{}) {
+		t.Errorf("config without a file = %+v, want empty", fc)
+	}
+
+	writeConfigFile(t, dir, "mmapforge.toml", `build_tag = "darwin"`)
+	writeConfigFile(t, dir, "mmapforge.yml", "build_tag: linux\n")
+	fc, err = loadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.path != "mmapforge.yml" || fc.BuildTag != "linux" {
+		t.Errorf("config = %+v, want mmapforge.yml to win over mmapforge.toml", fc)
+	}
+}
+
+func TestLoadConfig_Empty(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "mmapforge.yaml", "")
+	fc, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.path != path || fc.BuildTag != "" {
+		t.Errorf("config = %+v", fc)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, src, want string
+	}{
+		{"a.yaml", "bogus: 1\n", "field bogus not found"},
+		{"b.yaml", "types: [1\n", "b.yaml"},
+		{"c.toml", "bogus = 1\n", "unknown key bogus"},
+		{"d.toml", "types = \n", "d.toml"},
+		{"e.json", "{}", "unsupported config format"},
+	}
+	for _, tt := range tests {
+		path := writeConfigFile(t, dir, tt.name, tt.src)
+		if _, err := loadConfig(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+	if _, err := loadConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected error for a missing config file")
+	}
+}
+
+func TestFileConfig_CodegenConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "tmpl"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfigFile(t, filepath.Join(dir, "tmpl"), "x.tmpl", `{{ define "x" }}package x{{ end }}`)
+	fc, err := loadConfig(writeConfigFile(t, dir, "mmapforge.yaml", yamlConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := fc.codegenConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Header != "// Generated. DO NOT EDIT." || c.BuildTag != "linux" || c.FeatureEnabled("record") {
+		t.Errorf("config = %+v", c)
+	}
+	if len(c.Templates) != 1 || c.Templates[0].Lookup("x") == nil {
+		t.Errorf("templates = %+v, want x from tmpl/x.tmpl", c.Templates)
+	}
+	want := codegen.TypeConfig{Store: "TickTable", File: "tick_table.go", TestFile: "tick_table_gen_test.go", SkipTests: true}
+	if c.Types["Tick"] != want {
+		t.Errorf("Types[Tick] = %+v, want %+v", c.Types["Tick"], want)
+	}
+
+	fc.Templates = []string{filepath.Join(dir, "none", "*.tmpl")}
+	if _, err := fc.codegenConfig(); err == nil || !strings.Contains(err.Error(), "templates") {
+		t.Errorf("err = %v, want templates error", err)
+	}
+}
+
+func TestFileConfig_CheckTypes(t *testing.T) {
+	g, err := codegen.NewGraph(&codegen.Config{Target: t.TempDir()}, []codegen.StructSchema{
+		{Name: "Tick", Fields: []mmapforge.FieldDef{{Name: "price", GoName: "Price", Type: mmapforge.FieldFloat64}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := &fileConfig{path: "mmapforge.yaml", Types: map[string]fileTypeConfig{"Tick": {}}}
+	if err := fc.checkTypes([]*codegen.Graph{g}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	fc.Types["Tik"], fc.Types["Quote"] = fileTypeConfig{}, fileTypeConfig{}
+	err = fc.checkTypes([]*codegen.Graph{g})
+	if err == nil || err.Error() != "mmapforge.yaml: types: no mmapforge:schema struct named Quote, Tik" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestMain_Config(t *testing.T) {
+	root := writeTempModule(t, map[string]string{
+		"ticks/tick.go":           tickSchema,
+		"ticks/tmpl/names.tmpl":   `{{ define "names" }}package ticks{{ end }}`,
+		"ticks/mmapforge.yaml":    "templates: [tmpl/*.tmpl]\ntypes:\n  Tick:\n    store: TickTable\n    skip_tests: true\n",
+		"mmapforge.toml":          "build_tag = \"linux\"\n",
+		"other/mmapforge.yaml":    "types:\n  Nope: {}\n",
+		"broken/mmapforge.yaml":   "features:\n  bogus: true\n",
+		"broken/invalid.toml":     "=",
+		"templates/mmapforge.yml": "templates: [missing/*.tmpl]\n",
+	})
+
+	code, out := stubMain(t, []string{"mmapforge", "-config", "ticks/mmapforge.yaml", "./ticks"})
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %q", code, out)
+	}
+	store, err := os.ReadFile(filepath.Join(root, "ticks", "tick_store.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(store), "type TickTable struct") || strings.Contains(string(store), "//go:build") {
+		t.Errorf("tick_store.go should use the -config file only:\n%s", store)
+	}
+	if _, err := os.Stat(filepath.Join(root, "ticks", "names.go")); err != nil {
+		t.Errorf("names.go from the external template: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "ticks", "tick_store_test.go")); err == nil {
+		t.Error("tick_store_test.go should be skipped")
+	}
+
+	// Without -config, mmapforge.toml in the working directory applies.
+	code, out = stubMain(t, []string{"mmapforge", "-input", "ticks/tick.go"})
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %q", code, out)
+	}
+	store, err = os.ReadFile(filepath.Join(root, "ticks", "tick_store.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(store), "//go:build linux") {
+		t.Errorf("tick_store.go should use mmapforge.toml:\n%s", store)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-config", "other/mmapforge.yaml", "./ticks"}, "no mmapforge:schema struct named Nope"},
+		{[]string{"-config", "other/mmapforge.yaml", "-input", "ticks/tick.go"}, "no mmapforge:schema struct named Nope"},
+		{[]string{"-config", "broken/mmapforge.yaml", "./ticks"}, "unknown feature"},
+		{[]string{"-config", "broken/invalid.toml", "./ticks"}, "invalid.toml"},
+		{[]string{"-config", "templates/mmapforge.yml", "./ticks"}, "templates"},
+		{[]string{"-config", "templates/mmapforge.yml", "-input", "ticks/tick.go"}, "templates"},
+	} {
+		code, out := stubMain(t, append([]string{"mmapforge"}, tt.args...))
+		if code != 1 || !strings.Contains(out, tt.want) {
+			t.Errorf("%v: exit code = %d, stderr = %q, want %q", tt.args, code, out, tt.want)
+		}
+	}
+}
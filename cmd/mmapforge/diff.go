@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// diffEntry is the JSON form of one mmapforge.FieldDiff.
+type diffEntry struct {
+	Kind  string `json:"kind"`
+	Index int    `json:"index"`
+	Count int    `json:"count,omitempty"`
+	Field string `json:"field,omitempty"`
+	Old   any    `json:"old,omitempty"`
+	New   any    `json:"new,omitempty"`
+}
+
+// runDiff implements `mmapforge diff`:
+//
+//	mmapforge diff -schema types.go [-type T] [-json] [-max N] a.mmf b.mmf
+//
+// Like diff(1) it exits non-zero when the stores differ.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	schemaPath := fs.String("schema", "", "Go source file declaring the mmapforge:schema struct")
+	typeName := fs.String("type", "", "Schema struct name (optional if the file declares one)")
+	jsonOut := fs.Bool("json", false, "Print one JSON object per difference")
+	maxDiffs := fs.Int("max", 0, "Stop after N field differences (0: no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: mmapforge diff -schema types.go [-type T] [-json] [-max N] a.mmf b.mmf")
+	}
+
+	layout, err := loadLayout(*schemaPath, *typeName)
+	if err != nil {
+		return err
+	}
+	a, err := mmapforge.OpenStore(fs.Arg(0), layout, mmapforge.WithReadOnly())
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	b, err := mmapforge.OpenStore(fs.Arg(1), layout, mmapforge.WithReadOnly())
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	enc := json.NewEncoder(stdout)
+	changed, records, last := 0, 0, -1
+	tail := ""
+	for d := range mmapforge.DiffStores(a, b) {
+		if d.Err != nil {
+			return d.Err
+		}
+		if d.Kind == mmapforge.DiffChanged {
+			if *maxDiffs > 0 && changed == *maxDiffs {
+				fmt.Fprintf(stderr, "mmapforge: stopped after %d differences (-max)\n", changed)
+				break
+			}
+			changed++
+			if d.Index != last {
+				records++
+				last = d.Index
+			}
+		} else {
+			only := fs.Arg(1)
+			if d.Kind == mmapforge.DiffTruncated {
+				only = fs.Arg(0)
+			}
+			tail = fmt.Sprintf("records %d..%d %s (only in %s)", d.Index, d.Index+d.Count-1, d.Kind, only)
+		}
+
+		if *jsonOut {
+			e := diffEntry{Kind: d.Kind.String(), Index: d.Index, Count: d.Count, Field: d.Field}
+			if d.Kind == mmapforge.DiffChanged {
+				e.Old, e.New = jsonValue(d.Old), jsonValue(d.New)
+			}
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+			continue
+		}
+		if d.Kind == mmapforge.DiffChanged {
+			fmt.Fprintf(stdout, "record %d %s: %s -> %s\n", d.Index, d.Field, formatValue(d.Old), formatValue(d.New))
+		} else {
+			fmt.Fprintln(stdout, tail)
+		}
+	}
+
+	switch {
+	case changed == 0 && tail == "":
+		return nil
+	case tail == "":
+		return fmt.Errorf("stores differ: %d fields in %d records", changed, records)
+	default:
+		return fmt.Errorf("stores differ: %d fields in %d records, %s", changed, records, tail)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// editStore opens path writable and applies fn.
+func editStore(t *testing.T, path string, fn func(s *mmapforge.Store)) {
+	t.Helper()
+	s, err := mmapforge.OpenStore(path, testStoreLayout(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn(s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunDiff_Identical(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	a := writeTestStore(t, dir, "a.mmf", 4)
+	b := writeTestStore(t, dir, "b.mmf", 4)
+	buf := captureStdout(t)
+
+	if err := runDiff([]string{"-schema", schema, a, b}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("output = %q, want empty", buf.String())
+	}
+}
+
+func TestRunDiff_Text(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	a := writeTestStore(t, dir, "a.mmf", 4)
+	b := writeTestStore(t, dir, "b.mmf", 6)
+	editStore(t, b, func(s *mmapforge.Store) {
+		if err := s.SetAny(1, "price", 9.25); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetAny(1, "name", "zz"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetAny(3, "live", true); err != nil {
+			t.Fatal(err)
+		}
+	})
+	buf := captureStdout(t)
+
+	err := runDiff([]string{"-schema", schema, a, b})
+	if err == nil || !strings.Contains(err.Error(), "3 fields in 2 records, records 4..5 appended") {
+		t.Errorf("err = %v", err)
+	}
+	want := "record 1 price: 1.5 -> 9.25\n" +
+		"record 1 name: rb -> zz\n" +
+		"record 3 live: false -> true\n" +
+		"records 4..5 appended (only in " + b + ")\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	err = runDiff([]string{"-schema", schema, b, a})
+	if err == nil || !strings.Contains(err.Error(), "truncated (only in "+b+")") {
+		t.Errorf("reversed err = %v", err)
+	}
+}
+
+func TestRunDiff_JSON(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	a := writeTestStore(t, dir, "a.mmf", 2)
+	b := writeTestStore(t, dir, "b.mmf", 1)
+	editStore(t, a, func(s *mmapforge.Store) {
+		if err := s.SetAny(0, "price", math.Inf(1)); err != nil {
+			t.Fatal(err)
+		}
+	})
+	buf := captureStdout(t)
+
+	if err := runDiff([]string{"-schema", schema, "-json", a, b}); err == nil {
+		t.Fatal("expected stores differ error")
+	}
+	var got []diffEntry
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for dec.More() {
+		var e diffEntry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 2 {
+		t.Fatalf("entries = %+v, want 2", got)
+	}
+	if e := got[0]; e.Kind != "changed" || e.Field != "price" || e.Old != "+Inf" || e.New != 0.0 {
+		t.Errorf("entry 0 = %+v", e)
+	}
+	if e := got[1]; e.Kind != "truncated" || e.Index != 1 || e.Count != 1 {
+		t.Errorf("entry 1 = %+v", e)
+	}
+}
+
+func TestRunDiff_Max(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	a := writeTestStore(t, dir, "a.mmf", 5)
+	b := writeTestStore(t, dir, "b.mmf", 5)
+	editStore(t, b, func(s *mmapforge.Store) {
+		for i := 0; i < 5; i++ {
+			if err := s.SetAny(i, "id", 100+i); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	buf := captureStdout(t)
+	var errBuf bytes.Buffer
+	orig := stderr
+	stderr = &errBuf
+	defer func() { stderr = orig }()
+
+	err := runDiff([]string{"-schema", schema, "-max", "2", a, b})
+	if err == nil || !strings.Contains(err.Error(), "2 fields in 2 records") {
+		t.Errorf("err = %v", err)
+	}
+	if strings.Count(buf.String(), "\n") != 2 || !strings.Contains(errBuf.String(), "stopped after 2") {
+		t.Errorf("stdout = %q, stderr = %q", buf.String(), errBuf.String())
+	}
+}
+
+func TestRunDiff_Errors(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	a := writeTestStore(t, dir, "a.mmf", 1)
+	captureStdout(t)
+	orig := stderr
+	stderr = &bytes.Buffer{}
+	defer func() { stderr = orig }()
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"one file", []string{"-schema", schema, a}, "usage"},
+		{"bad flag", []string{"-bogus"}, "bogus"},
+		{"no schema", []string{a, a}, "-schema is required"},
+		{"missing a", []string{"-schema", schema, dir + "/nope.mmf", a}, "no such file"},
+		{"missing b", []string{"-schema", schema, a, dir + "/nope.mmf"}, "no such file"},
+	}
+	for _, tt := range tests {
+		if err := runDiff(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRunDiff_ReadError(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	a := writeTestStore(t, dir, "a.mmf", 2)
+	b := writeTestStore(t, dir, "b.mmf", 2)
+
+	layout := testStoreLayout(t)
+	f, err := os.OpenFile(b, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := int64(mmapforge.HeaderSize) + int64(layout.RecordSize) + int64(layout.Fields[2].Offset)
+	if _, err := f.WriteAt([]byte{99, 0, 0, 0}, off); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t)
+
+	err = runDiff([]string{"-schema", schema, a, b})
+	if err == nil || !strings.Contains(err.Error(), "file corrupted") {
+		t.Errorf("err = %v, want corruption error", err)
+	}
+}
+
+func TestMain_Diff(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	a := writeTestStore(t, dir, "a.mmf", 1)
+	b := writeTestStore(t, dir, "b.mmf", 2)
+	captureStdout(t)
+
+	code, out := stubMain(t, []string{"mmapforge", "diff", "-schema", schema, a, a})
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0; stderr: %s", code, out)
+	}
+	code, out = stubMain(t, []string{"mmapforge", "diff", "-schema", schema, a, b})
+	if code != 1 || !strings.Contains(out, "mmapforge diff: stores differ") {
+		t.Errorf("exit code = %d, stderr = %q", code, out)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// recordWriter is one output format of dump. Values arrive in field order.
+type recordWriter interface {
+	Write(values []any) error
+	Close() error
+}
+
+// runDump implements `mmapforge dump` (alias `export`):
+//
+//	mmapforge dump -schema types.go -type Tick [-format csv|ndjson|parquet]
+//	    [-fields a,b] [-from N] [-to M] [-where expr]... [-out path] file.mmf
+//
+// A store file carries only a hash of its schema, with no room in the
+// header for the schema itself, so -schema is always required; there is no
+// embedded-schema mode.
+func runDump(args []string) (err error) {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	schemaPath := fs.String("schema", "", "Go source file declaring the mmapforge:schema struct (required: files store only a schema hash)")
+	typeName := fs.String("type", "", "Schema struct name (optional if the file declares one)")
+	format := fs.String("format", "csv", "Output format: csv, ndjson or parquet")
+	fieldList := fs.String("fields", "", "Comma-separated mmap field names (default: all)")
+	from := fs.Int("from", 0, "First record index (inclusive)")
+	to := fs.Int("to", -1, "Last record index (exclusive, default: record count)")
+	out := fs.String("out", "", "Output file (default: stdout)")
+	var where whereFlags
+	fs.Var(&where, "where", "Filter predicate <field><op><value>, op one of == != < <= > >=; repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: mmapforge dump -schema types.go [-type T] [flags] file.mmf (files store only a schema hash, so -schema is required)")
+	}
+
+	layout, err := loadLayout(*schemaPath, *typeName)
+	if err != nil {
+		return err
+	}
+	fields, err := selectFields(layout, *fieldList)
+	if err != nil {
+		return err
+	}
+	preds, err := parsePredicates(layout, where)
+	if err != nil {
+		return err
+	}
+
+	s, err := mmapforge.OpenStore(fs.Arg(0), layout, mmapforge.WithReadOnly())
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	w := stdout
+	if *out != "" {
+		var f *os.File
+		if f, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer func() { err = errors.Join(err, f.Close()) }()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+
+	rw, err := newRecordWriter(*format, bw, fields)
+	if err != nil {
+		return err
+	}
+
+	last := s.Len()
+	if *to >= 0 && *to < last {
+		last = *to
+	}
+	if err := dumpRecords(s, rw, fields, preds, max(*from, 0), last); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// newRecordWriter returns the recordWriter for format.
+func newRecordWriter(format string, w io.Writer, fields []mmapforge.FieldLayout) (recordWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w, fields)
+	case "ndjson", "jsonl":
+		return &ndjsonWriter{w: w, fields: fields}, nil
+	case "parquet":
+		return newParquetWriter(w, fields), nil
+	default:
+		return nil, fmt.Errorf("unknown -format %q (want csv, ndjson or parquet)", format)
+	}
+}
+
+// dumpRecords streams records [from, to) that match preds into rw.
+func dumpRecords(s *mmapforge.Store, rw recordWriter, fields []mmapforge.FieldLayout, preds []*predicate, from, to int) error {
+	values := make([]any, len(fields))
+	for i := from; i < to; i++ {
+		m, err := s.GetRecordMap(i)
+		if err != nil {
+			return errors.Join(err, rw.Close())
+		}
+		if !matchAll(preds, m) {
+			continue
+		}
+		for j, f := range fields {
+			values[j] = m[f.Name]
+		}
+		if err := rw.Write(values); err != nil {
+			return errors.Join(err, rw.Close())
+		}
+	}
+	return rw.Close()
+}
+
+// csvWriter writes a header row of field names, then one row per record.
+// []byte values are base64 encoded.
+type csvWriter struct {
+	w   *csv.Writer
+	row []string
+}
+
+func newCSVWriter(w io.Writer, fields []mmapforge.FieldLayout) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), row: make([]string, len(fields))}
+	for i, f := range fields {
+		cw.row[i] = f.Name
+	}
+	if err := cw.w.Write(cw.row); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(values []any) error {
+	for i, v := range values {
+		cw.row[i] = formatValue(v)
+	}
+	return cw.w.Write(cw.row)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// ndjsonWriter writes one JSON object per line with keys in field order.
+// []byte values are base64 encoded; NaN and ±Inf are written as strings.
+type ndjsonWriter struct {
+	w      io.Writer
+	fields []mmapforge.FieldLayout
+	buf    []byte
+}
+
+func (nw *ndjsonWriter) Write(values []any) error {
+	b := append(nw.buf[:0], '{')
+	for i, v := range values {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendQuote(b, nw.fields[i].Name)
+		b = append(b, ':')
+		enc, err := json.Marshal(jsonValue(v))
+		if err != nil {
+			return err
+		}
+		b = append(b, enc...)
+	}
+	b = append(b, '}', '\n')
+	nw.buf = b
+	_, err := nw.w.Write(b)
+	return err
+}
+
+func (nw *ndjsonWriter) Close() error { return nil }
+
+// jsonValue makes a field value JSON-safe: NaN and ±Inf floats become strings.
+func jsonValue(v any) any {
+	switch x := v.(type) {
+	case float32:
+		return jsonFloat(float64(x), x)
+	case float64:
+		return jsonFloat(x, x)
+	}
+	return v
+}
+
+// jsonFloat returns v unchanged unless x is NaN or infinite, which JSON
+// cannot represent; those become their formatValue string.
+func jsonFloat(x float64, v any) any {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return formatValue(v)
+	}
+	return v
+}
+
+// formatValue renders a field value as text for CSV output.
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case bool:
+		return strconv.FormatBool(x)
+	case int8:
+		return strconv.FormatInt(int64(x), 10)
+	case int16:
+		return strconv.FormatInt(int64(x), 10)
+	case int32:
+		return strconv.FormatInt(int64(x), 10)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint8:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case string:
+		return x
+	case []byte:
+		return base64.StdEncoding.EncodeToString(x)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+var errWriteFailed = errors.New("write failed")
+
+const testSchemaSrc = `package data
+
+// mmapforge:schema version=3
+type Rec struct {
+	ID    uint64  ` + "`mmap:\"id\"`" + `
+	Price float64 ` + "`mmap:\"price\"`" + `
+	Name  string  ` + "`mmap:\"name,8\"`" + `
+	Live  bool    ` + "`mmap:\"live\"`" + `
+}
+`
+
+// writeTestSchema writes a schema file matching testStoreLayout.
+func writeTestSchema(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "types.go")
+	if err := os.WriteFile(path, []byte(testSchemaSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunDump_CSV(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	path := writeTestStore(t, dir, "a.mmf", 3)
+	buf := captureStdout(t)
+
+	if err := runDump([]string{"-schema", schema, path}); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,price,name,live\n0,0,ra,true\n1,1.5,rb,false\n2,3,rc,true\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunDump_NDJSON(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	path := writeTestStore(t, dir, "a.mmf", 5)
+	buf := captureStdout(t)
+
+	err := runDump([]string{"-schema", schema, "-type", "Rec", "-format", "ndjson",
+		"-fields", "name, id", "-from", "1", "-to", "3", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"rb","id":1}` + "\n" + `{"name":"rc","id":2}` + "\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunDump_Where(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	path := writeTestStore(t, dir, "a.mmf", 10)
+	buf := captureStdout(t)
+
+	err := runDump([]string{"-schema", schema, "-fields", "id",
+		"-where", "live==true", "-where", "price>=3", "-where", "id<8", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id\n2\n4\n6\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunDump_ParquetOut(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	path := writeTestStore(t, dir, "a.mmf", 4)
+	out := filepath.Join(dir, "a.parquet")
+
+	if err := runDump([]string{"-schema", schema, "-format", "parquet", "-out", out, path}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := readParquetFooter(t, data)
+	if md[3].(int64) != 4 {
+		t.Errorf("num_rows = %v, want 4", md[3])
+	}
+	if got := readParquetColumn(t, data, md, 1); got[3] != 4.5 {
+		t.Errorf("price column = %v", got)
+	}
+}
+
+func TestRunDump_Errors(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	path := writeTestStore(t, dir, "a.mmf", 1)
+	captureStdout(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no file", []string{"-schema", schema}, "usage"},
+		{"no schema", []string{path}, "-schema is required"},
+		{"bad type", []string{"-schema", schema, "-type", "Nope", path}, `"Nope"`},
+		{"bad field", []string{"-schema", schema, "-fields", "nope", path}, "unknown field"},
+		{"bad where", []string{"-schema", schema, "-where", "id>x", path}, "-where"},
+		{"bad format", []string{"-schema", schema, "-format", "xml", path}, "unknown -format"},
+		{"missing file", []string{"-schema", schema, filepath.Join(dir, "nope.mmf")}, "no such file"},
+		{"bad out", []string{"-schema", schema, "-out", filepath.Join(dir, "x", "y.csv"), path}, "no such file"},
+		{"bad flag", []string{"-bogus"}, "bogus"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errBuf bytes.Buffer
+			orig := stderr
+			stderr = &errBuf
+			defer func() { stderr = orig }()
+
+			err := runDump(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunDump_SchemaMismatch(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "other.go")
+	src := strings.Replace(testSchemaSrc, "`mmap:\"live\"`", "`mmap:\"alive\"`", 1)
+	if err := os.WriteFile(schema, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := writeTestStore(t, dir, "a.mmf", 1)
+	captureStdout(t)
+
+	err := runDump([]string{"-schema", schema, path})
+	if err == nil || !strings.Contains(err.Error(), "schema hash mismatch") {
+		t.Errorf("err = %v, want schema hash mismatch", err)
+	}
+}
+
+func TestNDJSONWriter_Values(t *testing.T) {
+	fields := []mmapforge.FieldLayout{fieldNamed("f"), fieldNamed("g"), fieldNamed("b")}
+	var buf bytes.Buffer
+	nw := &ndjsonWriter{w: &buf, fields: fields}
+	if err := nw.Write([]any{math.NaN(), float32(math.Inf(-1)), []byte{0xff}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := nw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"f":"NaN","g":"-Inf","b":"/w=="}` + "\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{true, "true"},
+		{int8(-8), "-8"},
+		{int16(-16), "-16"},
+		{int32(-32), "-32"},
+		{int64(-64), "-64"},
+		{uint8(8), "8"},
+		{uint16(16), "16"},
+		{uint32(32), "32"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{float32(0.1), "0.1"},
+		{0.25, "0.25"},
+		{"s", "s"},
+		{[]byte("hi"), "aGk="},
+		{struct{}{}, "{}"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.v); got != tt.want {
+			t.Errorf("formatValue(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestCSVWriter_WriteError(t *testing.T) {
+	cw, err := newCSVWriter(failWriter{}, []mmapforge.FieldLayout{fieldNamed("a")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Write([]any{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Close(); !errors.Is(err, errWriteFailed) {
+		t.Errorf("Close err = %v, want errWriteFailed", err)
+	}
+}
+
+func TestMain_Dump(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	path := writeTestStore(t, dir, "a.mmf", 2)
+
+	for _, cmd := range []string{"dump", "export"} {
+		buf := captureStdout(t)
+		code, out := stubMain(t, []string{"mmapforge", cmd, "-schema", schema, "-format", "jsonl", path})
+		if code != 0 {
+			t.Errorf("%s: exit code = %d, want 0; stderr: %s", cmd, code, out)
+		}
+		if strings.Count(buf.String(), "\n") != 2 {
+			t.Errorf("%s: output = %q, want 2 lines", cmd, buf.String())
+		}
+	}
+
+	code, out := stubMain(t, []string{"mmapforge", "dump"})
+	if code != 1 || !strings.Contains(out, "mmapforge dump:") {
+		t.Errorf("exit code = %d, stderr = %q", code, out)
+	}
+}
+
+func fieldNamed(name string) mmapforge.FieldLayout {
+	var f mmapforge.FieldLayout
+	f.Name = name
+	return f
+}
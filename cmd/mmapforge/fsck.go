@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// fsckIssue is one problem found by fsck. Record is -1 for header problems.
+type fsckIssue struct {
+	Record   int64  `json:"record"`
+	Field    string `json:"field,omitempty"`
+	Problem  string `json:"problem"`
+	Repaired bool   `json:"repaired"`
+}
+
+// fsckReport is the result of checking one file.
+type fsckReport struct {
+	Path       string      `json:"path"`
+	Records    uint64      `json:"records_scanned"`
+	Issues     []fsckIssue `json:"issues"`
+	Repaired   int         `json:"repaired"`
+	Unrepaired int         `json:"unrepaired"`
+}
+
+func (r *fsckReport) add(record int64, field string, repaired bool, format string, args ...any) {
+	r.Issues = append(r.Issues, fsckIssue{
+		Record:   record,
+		Field:    field,
+		Problem:  fmt.Sprintf(format, args...),
+		Repaired: repaired,
+	})
+	if repaired {
+		r.Repaired++
+	} else {
+		r.Unrepaired++
+	}
+}
+
+// runFsck implements `mmapforge fsck`:
+//
+//	mmapforge fsck [-schema types.go [-type T]] [-repair] [-json] file.mmf
+//
+// It exits non-zero while any problem is left unrepaired. Run it only on
+// files no other process has open.
+func runFsck(args []string) error {
+	fs := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	schemaPath := fs.String("schema", "", "Go source file declaring the mmapforge:schema struct (enables field checks)")
+	typeName := fs.String("type", "", "Schema struct name (optional if the file declares one)")
+	repair := fs.Bool("repair", false, "Fix what can be fixed in place")
+	jsonOut := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: mmapforge fsck [-schema types.go [-type T]] [-repair] [-json] file.mmf")
+	}
+
+	var layout *mmapforge.RecordLayout
+	if *schemaPath != "" {
+		var err error
+		if layout, err = loadLayout(*schemaPath, *typeName); err != nil {
+			return err
+		}
+	}
+
+	r, err := fsckFile(fs.Arg(0), layout, *repair)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	} else {
+		for _, is := range r.Issues {
+			where := "header"
+			if is.Record >= 0 {
+				where = fmt.Sprintf("record %d", is.Record)
+			}
+			if is.Field != "" {
+				where += fmt.Sprintf(" field %q", is.Field)
+			}
+			status := ""
+			if is.Repaired {
+				status = " (repaired)"
+			}
+			fmt.Fprintf(stdout, "%s: %s%s\n", where, is.Problem, status)
+		}
+		fmt.Fprintf(stdout, "%s: %d records scanned, %d problems, %d repaired\n",
+			r.Path, r.Records, len(r.Issues), r.Repaired)
+	}
+
+	if r.Unrepaired > 0 {
+		return fmt.Errorf("%s: %d unrepaired problems", r.Path, r.Unrepaired)
+	}
+	return nil
+}
+
+// fsckFile maps path without the schema check and validates the header
+// invariants, then scans every live record for stuck seqlocks and, when
+// layout is non-nil, oversized length prefixes and invalid bools. With
+// repair set it truncates the count and capacity to what the file holds,
+// resets stuck seqlocks and zeroes bad fields.
+func fsckFile(path string, layout *mmapforge.RecordLayout, repair bool) (*fsckReport, error) {
+	mode := os.O_RDONLY
+	if repair {
+		mode = os.O_RDWR
+	}
+	f, err := os.OpenFile(path, mode, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+	size := info.Size()
+	if size < mmapforge.HeaderSizeV1 {
+		return nil, errors.Join(
+			fmt.Errorf("%s is too small (%d bytes)", path, size),
+			f.Close(),
+		)
+	}
+
+	region, err := mmapforge.Map(f, int(size), repair, mmapforge.Sequential, int(size))
+	if err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+	defer region.Close()
+
+	hdr := region.Slice(0, min(int(size), mmapforge.HeaderSize))
+	h, err := mmapforge.DecodeHeader(hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &fsckReport{Path: path, Issues: []fsckIssue{}}
+	if h.RecordSize < mmapforge.SeqFieldSize {
+		r.add(-1, "", false, "record size %d is smaller than the %d-byte seqlock", h.RecordSize, mmapforge.SeqFieldSize)
+		return r, nil
+	}
+
+	checkFields := layout != nil
+	if layout != nil {
+		if h.RecordSize != layout.RecordSize {
+			r.add(-1, "", false, "record size %d does not match schema record size %d", h.RecordSize, layout.RecordSize)
+			checkFields = false
+		} else if h.SchemaHash != mmapforge.SchemaHash(layout.Descriptors()) {
+			r.add(-1, "", false, "schema hash %x does not match schema", h.SchemaHash)
+			checkFields = false
+		}
+	}
+
+	dirty := false
+	inFile := uint64(size-int64(h.Size())) / uint64(h.RecordSize)
+	limit := h.Capacity
+	if h.Capacity > inFile {
+		r.add(-1, "", repair, "file size %d is below header size + capacity*record size %d (capacity %d, file holds %d)",
+			size, uint64(h.Size())+h.Capacity*uint64(h.RecordSize), h.Capacity, inFile)
+		limit = inFile
+		if repair {
+			h.Capacity = inFile
+			dirty = true
+		}
+	}
+	if h.RecordCount > limit {
+		r.add(-1, "", repair, "record count %d exceeds the %d records available", h.RecordCount, limit)
+		if repair {
+			h.RecordCount = limit
+			dirty = true
+		}
+	}
+	if dirty {
+		if err := mmapforge.EncodeHeader(hdr, h); err != nil {
+			return nil, err
+		}
+	}
+
+	n := min(h.RecordCount, limit)
+	for i := uint64(0); i < n; i++ {
+		rec := region.Slice(h.Size()+int(i)*int(h.RecordSize), int(h.RecordSize))
+		if seq := binary.LittleEndian.Uint64(rec); seq&1 != 0 {
+			r.add(int64(i), "", repair, "seqlock %d is odd (writer died mid-write)", seq)
+			if repair {
+				binary.LittleEndian.PutUint64(rec, seq+1)
+			}
+		}
+		if checkFields {
+			fsckFields(r, int64(i), rec, layout, repair)
+		}
+	}
+	r.Records = n
+
+	if repair && r.Repaired > 0 {
+		if err := region.Sync(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// fsckFields checks the string/bytes length prefixes and bool bytes of one
+// record, zeroing bad fields when repair is set.
+func fsckFields(r *fsckReport, idx int64, rec []byte, layout *mmapforge.RecordLayout, repair bool) {
+	for _, fl := range layout.Fields {
+		field := rec[fl.Offset : fl.Offset+fl.Size]
+		switch fl.Type {
+		case mmapforge.FieldString, mmapforge.FieldBytes:
+			if n := binary.LittleEndian.Uint32(field); n > fl.MaxSize {
+				r.add(idx, fl.Name, repair, "length %d exceeds max size %d", n, fl.MaxSize)
+				if repair {
+					clear(field)
+				}
+			}
+		case mmapforge.FieldBool:
+			if field[0] > 1 {
+				r.add(idx, fl.Name, repair, "invalid bool byte %#x", field[0])
+				if repair {
+					field[0] = 0
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// corruptStore patches a store written by writeTestStore: record 1 gets an
+// odd seqlock, record 2 an oversized name length, record 3 a bool byte of 7.
+func corruptStore(t *testing.T, path string) {
+	t.Helper()
+	layout := testStoreLayout(t)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rec := func(i int) int64 { return int64(mmapforge.HeaderSize + i*int(layout.RecordSize)) }
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], 5)
+	if _, err := f.WriteAt(b[:], rec(1)); err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(b[:], 99)
+	if _, err := f.WriteAt(b[:4], rec(2)+int64(layout.Fields[2].Offset)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte{7}, rec(3)+int64(layout.Fields[3].Offset)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setHeaderCount overwrites the record count in the header of path.
+func setHeaderCount(t *testing.T, path string, count uint64) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := mmapforge.DecodeHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.RecordCount = count
+	if err := mmapforge.EncodeHeader(data, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFsckFile_Clean(t *testing.T) {
+	path := writeTestStore(t, t.TempDir(), "a.mmf", 10)
+	r, err := fsckFile(path, testStoreLayout(t), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Records != 10 || len(r.Issues) != 0 {
+		t.Errorf("report = %+v, want 10 records and no issues", r)
+	}
+}
+
+func TestFsckFile_Records(t *testing.T) {
+	path := writeTestStore(t, t.TempDir(), "a.mmf", 5)
+	corruptStore(t, path)
+	layout := testStoreLayout(t)
+
+	r, err := fsckFile(path, layout, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Issues) != 3 || r.Unrepaired != 3 || r.Repaired != 0 {
+		t.Fatalf("issues = %+v, want 3 unrepaired", r.Issues)
+	}
+	want := []struct {
+		record int64
+		field  string
+	}{{1, ""}, {2, "name"}, {3, "live"}}
+	for i, w := range want {
+		if r.Issues[i].Record != w.record || r.Issues[i].Field != w.field {
+			t.Errorf("issue %d = %+v, want record %d field %q", i, r.Issues[i], w.record, w.field)
+		}
+	}
+
+	// Without a schema only the seqlock is checked.
+	r, err = fsckFile(path, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Issues) != 1 {
+		t.Errorf("schemaless issues = %+v, want 1", r.Issues)
+	}
+
+	r, err = fsckFile(path, layout, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Repaired != 3 || r.Unrepaired != 0 {
+		t.Errorf("repair report = %+v", r)
+	}
+
+	r, err = fsckFile(path, layout, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Issues) != 0 {
+		t.Errorf("issues after repair = %+v", r.Issues)
+	}
+
+	s, err := mmapforge.OpenStore(path, layout, mmapforge.WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	m, err := s.GetRecordMap(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["name"] != "" || m["id"] != uint64(2) {
+		t.Errorf("record 2 after repair = %v", m)
+	}
+	if live, err := s.GetAny(3, "live"); err != nil || live != false {
+		t.Errorf("record 3 live = %v, %v", live, err)
+	}
+}
+
+func TestFsckFile_Header(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestStore(t, dir, "a.mmf", 5)
+	layout := testStoreLayout(t)
+
+	// Cut the file to hold 10 of its 64 records, and claim 20 live ones.
+	if err := os.Truncate(path, int64(mmapforge.HeaderSize+10*int(layout.RecordSize)+3)); err != nil {
+		t.Fatal(err)
+	}
+	setHeaderCount(t, path, 20)
+
+	r, err := fsckFile(path, layout, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Issues) != 2 || r.Records != 10 {
+		t.Fatalf("report = %+v, want 2 issues and 10 records scanned", r)
+	}
+	if !strings.Contains(r.Issues[0].Problem, "file holds 10") || !strings.Contains(r.Issues[1].Problem, "record count 20 exceeds the 10 records available") {
+		t.Errorf("issues = %+v", r.Issues)
+	}
+
+	if _, err := fsckFile(path, layout, true); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := mmapforge.DecodeHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Capacity != 10 || h.RecordCount != 10 {
+		t.Errorf("header after repair: capacity %d count %d, want 10 and 10", h.Capacity, h.RecordCount)
+	}
+}
+
+func TestFsckFile_SchemaMismatch(t *testing.T) {
+	path := writeTestStore(t, t.TempDir(), "a.mmf", 2)
+	corruptStore(t, path)
+
+	other, err := mmapforge.ComputeLayout([]mmapforge.FieldDef{{Name: "x", Type: mmapforge.FieldUint8}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := fsckFile(path, other, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Unrepaired != 1 || !strings.Contains(r.Issues[0].Problem, "does not match schema record size") {
+		t.Errorf("issues = %+v", r.Issues)
+	}
+
+	renamed := testStoreLayout(t)
+	renamed.Fields[3].Name = "alive"
+	r, err = fsckFile(path, renamed, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Unrepaired != 1 || !strings.Contains(r.Issues[0].Problem, "schema hash") {
+		t.Errorf("issues = %+v", r.Issues)
+	}
+}
+
+func TestFsckFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := fsckFile(dir+"/nope.mmf", nil, false); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	small := writeInput(t, dir, "small.mmf", "tiny")
+	if _, err := fsckFile(small, nil, false); err == nil || !strings.Contains(err.Error(), "too small") {
+		t.Errorf("err = %v, want too small", err)
+	}
+
+	bad := writeInput(t, dir, "bad.mmf", strings.Repeat("x", mmapforge.HeaderSize))
+	if _, err := fsckFile(bad, nil, false); err == nil {
+		t.Error("expected bad magic error")
+	}
+
+	path := writeTestStore(t, dir, "a.mmf", 1)
+	data, _ := os.ReadFile(path)
+	h, _ := mmapforge.DecodeHeader(data)
+	h.RecordSize = 4
+	if err := mmapforge.EncodeHeader(data, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fsckFile(path, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Unrepaired != 1 || !strings.Contains(r.Issues[0].Problem, "smaller than the 8-byte seqlock") {
+		t.Errorf("issues = %+v", r.Issues)
+	}
+}
+
+func TestRunFsck_Text(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	path := writeTestStore(t, dir, "a.mmf", 5)
+	corruptStore(t, path)
+	buf := captureStdout(t)
+
+	err := runFsck([]string{"-schema", schema, path})
+	if err == nil || !strings.Contains(err.Error(), "3 unrepaired problems") {
+		t.Errorf("err = %v, want 3 unrepaired problems", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"record 1: seqlock 5 is odd",
+		`record 2 field "name": length 99 exceeds max size 8`,
+		`record 3 field "live": invalid bool byte 0x7`,
+		"5 records scanned, 3 problems, 0 repaired",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := runFsck([]string{"-schema", schema, "-repair", path}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "(repaired)") || !strings.Contains(buf.String(), "3 repaired") {
+		t.Errorf("repair output = %s", buf.String())
+	}
+}
+
+func TestRunFsck_JSON(t *testing.T) {
+	path := writeTestStore(t, t.TempDir(), "a.mmf", 3)
+	setHeaderCount(t, path, 100)
+	buf := captureStdout(t)
+
+	if err := runFsck([]string{"-json", "-repair", path}); err != nil {
+		t.Fatal(err)
+	}
+	var r fsckReport
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Repaired != 1 || r.Records != 64 || len(r.Issues) != 1 || r.Issues[0].Record != -1 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestRunFsck_Errors(t *testing.T) {
+	dir := t.TempDir()
+	captureStdout(t)
+	orig := stderr
+	stderr = &strings.Builder{}
+	defer func() { stderr = orig }()
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no file", nil, "usage"},
+		{"bad flag", []string{"-bogus"}, "bogus"},
+		{"bad schema", []string{"-schema", dir + "/nope.go", "x.mmf"}, "no such file"},
+		{"missing file", []string{dir + "/nope.mmf"}, "no such file"},
+	}
+	for _, tt := range tests {
+		if err := runFsck(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestMain_Fsck(t *testing.T) {
+	path := writeTestStore(t, t.TempDir(), "a.mmf", 2)
+	captureStdout(t)
+
+	code, out := stubMain(t, []string{"mmapforge", "fsck", path})
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0; stderr: %s", code, out)
+	}
+
+	setHeaderCount(t, path, 1000)
+	code, out = stubMain(t, []string{"mmapforge", "fsck", path})
+	if code != 1 || !strings.Contains(out, "mmapforge fsck:") {
+		t.Errorf("exit code = %d, stderr = %q", code, out)
+	}
+}
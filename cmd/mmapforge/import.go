@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// stdin is the default -in source; replaced in tests.
+var stdin io.Reader = os.Stdin
+
+// maxNDJSONLine bounds a single NDJSON input line.
+const maxNDJSONLine = 64 << 20
+
+// Overflow policies for string and bytes values longer than MaxSize.
+const (
+	overflowError    = "error"
+	overflowTruncate = "truncate"
+)
+
+// importer writes parsed input values into a store.
+type importer struct {
+	s        *mmapforge.Store
+	layout   *mmapforge.RecordLayout
+	truncate bool
+	rows     int
+}
+
+// runImport implements `mmapforge import`:
+//
+//	mmapforge import -schema types.go -type Tick [-format csv|ndjson]
+//	    [-in ticks.csv] -out ticks.mmf [-overflow error|truncate] [-capacity N] [-force]
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	schemaPath := fs.String("schema", "", "Go source file declaring the mmapforge:schema struct")
+	typeName := fs.String("type", "", "Schema struct name (optional if the file declares one)")
+	format := fs.String("format", "", "Input format: csv or ndjson (default: from -in extension, else csv)")
+	in := fs.String("in", "-", "Input file, or - for stdin")
+	out := fs.String("out", "", "Store file to create")
+	overflow := fs.String("overflow", overflowError, "Strings/bytes longer than max size: error or truncate")
+	capacity := fs.Int("capacity", 0, "Records to pre-size the store for (default: input line count)")
+	force := fs.Bool("force", false, "Replace -out if it already exists")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *out == "" {
+		return fmt.Errorf("usage: mmapforge import -schema types.go [-type T] [-in file] -out file.mmf")
+	}
+	if *overflow != overflowError && *overflow != overflowTruncate {
+		return fmt.Errorf("unknown -overflow %q (want error or truncate)", *overflow)
+	}
+	if *format == "" {
+		*format = formatFromExt(*in)
+	}
+	if *format != "csv" && *format != "ndjson" && *format != "jsonl" {
+		return fmt.Errorf("unknown -format %q (want csv or ndjson)", *format)
+	}
+
+	schema, err := findSchema(*schemaPath, *typeName)
+	if err != nil {
+		return err
+	}
+	layout, err := mmapforge.ComputeLayout(schema.Fields)
+	if err != nil {
+		return err
+	}
+
+	r, lines, err := openInput(*in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if *capacity <= 0 {
+		*capacity = lines
+	}
+
+	if *force {
+		if err := os.Remove(*out); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	s, err := mmapforge.CreateStore(*out, layout, schema.SchemaVersion, mmapforge.WithCapacity(*capacity))
+	if err != nil {
+		return err
+	}
+
+	imp := &importer{s: s, layout: layout, truncate: *overflow == overflowTruncate}
+	if *format == "csv" {
+		err = imp.readCSV(r, *in)
+	} else {
+		err = imp.readNDJSON(r, *in)
+	}
+	if err != nil {
+		return errors.Join(err, s.Close(), os.Remove(*out))
+	}
+	if err := s.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "mmapforge: imported %d %s records into %s\n", imp.rows, schema.Name, *out)
+	return nil
+}
+
+// formatFromExt picks the input format from the file extension.
+func formatFromExt(path string) string {
+	if strings.HasSuffix(path, ".ndjson") || strings.HasSuffix(path, ".jsonl") {
+		return "ndjson"
+	}
+	return "csv"
+}
+
+// openInput opens path ("-" for stdin) and counts its lines, an upper
+// bound on the record count used to pre-size the store. Files are scanned
+// and rewound; stdin is buffered in memory.
+func openInput(path string) (io.ReadCloser, int, error) {
+	if path == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, 0, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), bytes.Count(data, []byte{'\n'}) + 1, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	lines := 1
+	buf := make([]byte, 256<<10)
+	for {
+		n, err := f.Read(buf)
+		lines += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, errors.Join(err, f.Close())
+		}
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, errors.Join(err, f.Close())
+	}
+	return f, lines, nil
+}
+
+// readCSV imports CSV with a header row of mmap field names. Empty cells
+// leave the field zero.
+func (imp *importer) readCSV(r io.Reader, name string) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cols := make([]mmapforge.FieldLayout, len(header))
+	for i, h := range header {
+		f, ok := fieldByName(imp.layout, strings.TrimSpace(h))
+		if !ok {
+			return fmt.Errorf("%s: column %q: %w", name, h, mmapforge.ErrUnknownField)
+		}
+		cols[i] = f
+	}
+
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		idx, err := imp.s.Append()
+		if err != nil {
+			return err
+		}
+		for i, v := range rec {
+			if v == "" {
+				continue
+			}
+			if err := imp.set(idx, cols[i], v); err != nil {
+				return fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+		}
+		imp.rows++
+	}
+}
+
+// readNDJSON imports one JSON object per line keyed by mmap field name.
+// Blank lines are skipped; null values and missing keys leave the field zero.
+func (imp *importer) readNDJSON(r io.Reader, name string) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxNDJSONLine)
+
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(sc.Bytes()))
+		dec.UseNumber()
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			return fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+
+		idx, err := imp.s.Append()
+		if err != nil {
+			return err
+		}
+		for _, f := range imp.layout.Fields {
+			v := obj[f.Name]
+			delete(obj, f.Name)
+			if v == nil {
+				continue
+			}
+			if err := imp.set(idx, f, v); err != nil {
+				return fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+		}
+		for k := range obj {
+			return fmt.Errorf("%s:%d: key %q: %w", name, line, k, mmapforge.ErrUnknownField)
+		}
+		imp.rows++
+	}
+	return sc.Err()
+}
+
+// set writes v into field f of record idx. Bytes fields take base64 text,
+// matching dump; string and bytes values over MaxSize are cut when the
+// truncate policy is on and rejected by SetAny otherwise.
+func (imp *importer) set(idx int, f mmapforge.FieldLayout, v any) error {
+	if s, ok := v.(string); ok {
+		switch f.Type {
+		case mmapforge.FieldBytes:
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return fmt.Errorf("field %q: %w: %w", f.Name, mmapforge.ErrInvalidValue, err)
+			}
+			if imp.truncate && len(b) > int(f.MaxSize) {
+				b = b[:f.MaxSize]
+			}
+			v = b
+		case mmapforge.FieldString:
+			if imp.truncate {
+				v = truncateUTF8(s, int(f.MaxSize))
+			}
+		}
+	}
+	return imp.s.SetAny(idx, f.Name, v)
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
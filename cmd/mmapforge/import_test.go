@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// writeInput writes content to dir/name and returns its path.
+func writeInput(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// runImportQuiet runs runImport with stderr discarded.
+func runImportQuiet(t *testing.T, args ...string) error {
+	t.Helper()
+	orig := stderr
+	stderr = &bytes.Buffer{}
+	defer func() { stderr = orig }()
+	return runImport(args)
+}
+
+func openImported(t *testing.T, path string) *mmapforge.Store {
+	t.Helper()
+	s, err := mmapforge.OpenStore(path, testStoreLayout(t), mmapforge.WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestRunImport_CSVRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	src := writeTestStore(t, dir, "a.mmf", 10)
+
+	buf := captureStdout(t)
+	if err := runDump([]string{"-schema", schema, src}); err != nil {
+		t.Fatal(err)
+	}
+	want := buf.String()
+	in := writeInput(t, dir, "a.csv", want)
+
+	out := filepath.Join(dir, "b.mmf")
+	if err := runImportQuiet(t, "-schema", schema, "-in", in, "-out", out); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	if err := runDump([]string{"-schema", schema, out}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("round trip = %q, want %q", buf.String(), want)
+	}
+
+	s := openImported(t, out)
+	if s.Len() != 10 {
+		t.Errorf("Len = %d, want 10", s.Len())
+	}
+}
+
+func TestRunImport_CSVColumns(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	in := writeInput(t, dir, "a.csv", "name, id\nx,7\n,8\n")
+	out := filepath.Join(dir, "a.mmf")
+
+	if err := runImportQuiet(t, "-schema", schema, "-in", in, "-out", out); err != nil {
+		t.Fatal(err)
+	}
+	s := openImported(t, out)
+	m, err := s.GetRecordMap(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["id"] != uint64(8) || m["name"] != "" || m["live"] != false {
+		t.Errorf("record 1 = %v", m)
+	}
+}
+
+func TestRunImport_NDJSON(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	in := writeInput(t, dir, "a.jsonl", `{"id":1,"price":"NaN","name":"a","live":true}
+
+{"id":2,"price":2.5,"name":null}
+{"price":-1e3}
+`)
+	out := filepath.Join(dir, "a.mmf")
+
+	if err := runImportQuiet(t, "-schema", schema, "-in", in, "-out", out); err != nil {
+		t.Fatal(err)
+	}
+	s := openImported(t, out)
+	if s.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", s.Len())
+	}
+	m0, _ := s.GetRecordMap(0)
+	if !math.IsNaN(m0["price"].(float64)) || m0["live"] != true || m0["name"] != "a" {
+		t.Errorf("record 0 = %v", m0)
+	}
+	m2, _ := s.GetRecordMap(2)
+	if m2["price"] != -1000.0 || m2["id"] != uint64(0) {
+		t.Errorf("record 2 = %v", m2)
+	}
+}
+
+func TestRunImport_Stdin(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	orig := stdin
+	stdin = strings.NewReader("id\n1\n2\n3\n")
+	defer func() { stdin = orig }()
+
+	out := filepath.Join(dir, "a.mmf")
+	if err := runImportQuiet(t, "-schema", schema, "-type", "Rec", "-out", out); err != nil {
+		t.Fatal(err)
+	}
+	if s := openImported(t, out); s.Len() != 3 {
+		t.Errorf("Len = %d, want 3", s.Len())
+	}
+}
+
+func TestRunImport_Capacity(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+
+	var b strings.Builder
+	b.WriteString("id\n")
+	for i := 0; i < 200; i++ {
+		b.WriteString("1\n")
+	}
+	in := writeInput(t, dir, "a.csv", b.String())
+
+	out := filepath.Join(dir, "a.mmf")
+	if err := runImportQuiet(t, "-schema", schema, "-in", in, "-out", out); err != nil {
+		t.Fatal(err)
+	}
+	s := openImported(t, out)
+	// 200 rows + header + trailing line: pre-sized, never doubled to 256.
+	if s.Len() != 200 || s.Cap() != 202 {
+		t.Errorf("Len = %d Cap = %d, want 200 and 202", s.Len(), s.Cap())
+	}
+
+	out2 := filepath.Join(dir, "b.mmf")
+	if err := runImportQuiet(t, "-schema", schema, "-in", in, "-out", out2, "-capacity", "1000"); err != nil {
+		t.Fatal(err)
+	}
+	if s := openImported(t, out2); s.Cap() != 1000 {
+		t.Errorf("Cap = %d, want 1000", s.Cap())
+	}
+}
+
+func TestRunImport_Overflow(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	in := writeInput(t, dir, "a.csv", "id,name\n1,ok\n2,abcdefgé\n")
+	out := filepath.Join(dir, "a.mmf")
+
+	err := runImportQuiet(t, "-schema", schema, "-in", in, "-out", out)
+	var fe *mmapforge.FieldError
+	if !errors.Is(err, mmapforge.ErrStringTooLong) || !errors.As(err, &fe) {
+		t.Fatalf("err = %v, want FieldError wrapping ErrStringTooLong", err)
+	}
+	if !strings.Contains(err.Error(), "a.csv:3:") {
+		t.Errorf("err = %v, want input line", err)
+	}
+	if _, err := os.Stat(out); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("partial output left behind: %v", err)
+	}
+
+	if err := runImportQuiet(t, "-schema", schema, "-in", in, "-out", out, "-overflow", "truncate"); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := openImported(t, out).GetRecordMap(1)
+	if m["name"] != "abcdefg" {
+		t.Errorf("name = %q, want %q", m["name"], "abcdefg")
+	}
+}
+
+func TestRunImport_Force(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	in := writeInput(t, dir, "a.csv", "id\n1\n")
+	out := writeTestStore(t, dir, "a.mmf", 5)
+
+	if err := runImportQuiet(t, "-schema", schema, "-in", in, "-out", out); err == nil {
+		t.Fatal("expected error for existing -out")
+	}
+	if err := runImportQuiet(t, "-schema", schema, "-in", in, "-out", out, "-force"); err != nil {
+		t.Fatal(err)
+	}
+	if s := openImported(t, out); s.Len() != 1 {
+		t.Errorf("Len = %d, want 1", s.Len())
+	}
+}
+
+func TestRunImport_Errors(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	out := filepath.Join(dir, "out.mmf")
+	csvIn := func(content string) string { return writeInput(t, t.TempDir(), "in.csv", content) }
+	jsonIn := func(content string) string { return writeInput(t, t.TempDir(), "in.ndjson", content) }
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no out", []string{"-schema", schema}, "usage"},
+		{"extra arg", []string{"-schema", schema, "-out", out, "x"}, "usage"},
+		{"bad flag", []string{"-bogus"}, "bogus"},
+		{"bad overflow", []string{"-schema", schema, "-out", out, "-overflow", "drop"}, "unknown -overflow"},
+		{"bad format", []string{"-schema", schema, "-out", out, "-format", "xml"}, "unknown -format"},
+		{"no schema", []string{"-out", out}, "-schema is required"},
+		{"missing input", []string{"-schema", schema, "-out", out, "-in", filepath.Join(dir, "nope.csv")}, "no such file"},
+		{"unknown column", []string{"-schema", schema, "-out", out, "-in", csvIn("id,nope\n1,2\n")}, `column "nope"`},
+		{"bad number", []string{"-schema", schema, "-out", out, "-in", csvIn("id\n1\nx\n")}, "in.csv:3:"},
+		{"negative uint", []string{"-schema", schema, "-out", out, "-in", csvIn("id\n-1\n")}, "value not convertible"},
+		{"short row", []string{"-schema", schema, "-out", out, "-in", csvIn("id,name\n1\n")}, "wrong number of fields"},
+		{"bad json", []string{"-schema", schema, "-out", out, "-in", jsonIn("{\"id\":1}\n{bad\n")}, "in.ndjson:2:"},
+		{"unknown key", []string{"-schema", schema, "-out", out, "-in", jsonIn(`{"id":1,"x":2}`)}, `key "x"`},
+		{"json type", []string{"-schema", schema, "-out", out, "-in", jsonIn(`{"live":[1]}`)}, "value not convertible"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runImportQuiet(t, tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+			if _, statErr := os.Stat(out); !errors.Is(statErr, os.ErrNotExist) {
+				t.Errorf("output left behind after error")
+			}
+		})
+	}
+}
+
+func TestImporter_SetBytes(t *testing.T) {
+	layout, err := mmapforge.ComputeLayout([]mmapforge.FieldDef{
+		{Name: "raw", Type: mmapforge.FieldBytes, MaxSize: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := mmapforge.CreateStore(filepath.Join(t.TempDir(), "b.mmf"), layout, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	idx, _ := s.Append()
+	f := layout.Fields[0]
+
+	imp := &importer{s: s, layout: layout}
+	if err := imp.set(idx, f, "AQID"); !errors.Is(err, mmapforge.ErrBytesTooLong) {
+		t.Errorf("err = %v, want ErrBytesTooLong", err)
+	}
+	if err := imp.set(idx, f, "!!"); !errors.Is(err, mmapforge.ErrInvalidValue) {
+		t.Errorf("err = %v, want ErrInvalidValue", err)
+	}
+
+	imp.truncate = true
+	if err := imp.set(idx, f, "AQID"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetAny(idx, "raw"); !bytes.Equal(got.([]byte), []byte{1, 2}) {
+		t.Errorf("raw = %v, want [1 2]", got)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 2, "ab"},
+		{"aé", 2, "a"},
+		{"日本", 4, "日"},
+		{"日本", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateUTF8(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncateUTF8(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatFromExt(t *testing.T) {
+	for path, want := range map[string]string{"a.csv": "csv", "-": "csv", "a.ndjson": "ndjson", "a.jsonl": "ndjson"} {
+		if got := formatFromExt(path); got != want {
+			t.Errorf("formatFromExt(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMain_Import(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+	in := writeInput(t, dir, "a.csv", "id\n1\n")
+	out := filepath.Join(dir, "a.mmf")
+
+	code, errOut := stubMain(t, []string{"mmapforge", "import", "-schema", schema, "-in", in, "-out", out})
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0; stderr: %s", code, errOut)
+	}
+	if !strings.Contains(errOut, "imported 1 Rec records") {
+		t.Errorf("stderr = %q, want import summary", errOut)
+	}
+
+	code, errOut = stubMain(t, []string{"mmapforge", "import"})
+	if code != 1 || !strings.Contains(errOut, "mmapforge import:") {
+		t.Errorf("exit code = %d, stderr = %q", code, errOut)
+	}
+}
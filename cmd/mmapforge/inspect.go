@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// inspectReport is the decoded header plus file-level statistics.
+type inspectReport struct {
+	Path          string  `json:"path"`
+	Magic         string  `json:"magic"`
+	FormatVersion uint32  `json:"format_version"`
+	MultiWriter   bool    `json:"multi_writer"`
+	WriterPID     uint64  `json:"writer_pid"`
+	SchemaHash    string  `json:"schema_hash"`
+	SchemaVersion uint32  `json:"schema_version"`
+	RecordSize    uint32  `json:"record_size"`
+	RecordCount   uint64  `json:"record_count"`
+	Capacity      uint64  `json:"capacity"`
+	FileSize      int64   `json:"file_size"`
+	ExpectedSize  uint64  `json:"expected_size"`
+	MappedSize    int64   `json:"mapped_size"`
+	StuckSeqlocks int     `json:"stuck_seqlocks"`
+	FreeRatio     float64 `json:"free_ratio"`
+}
+
+// runInspect implements `mmapforge inspect [-json] file.mmf`.
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	jsonOut := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: mmapforge inspect [-json] file.mmf")
+	}
+
+	r, err := inspectFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	fmt.Fprintf(stdout, "file:            %s\n", r.Path)
+	fmt.Fprintf(stdout, "magic:           %s\n", r.Magic)
+	fmt.Fprintf(stdout, "format version:  %d\n", r.FormatVersion)
+	fmt.Fprintf(stdout, "multi-writer:    %t\n", r.MultiWriter)
+	fmt.Fprintf(stdout, "writer pid:      %d\n", r.WriterPID)
+	fmt.Fprintf(stdout, "schema hash:     %s\n", r.SchemaHash)
+	fmt.Fprintf(stdout, "schema version:  %d\n", r.SchemaVersion)
+	fmt.Fprintf(stdout, "record size:     %d\n", r.RecordSize)
+	fmt.Fprintf(stdout, "record count:    %d\n", r.RecordCount)
+	fmt.Fprintf(stdout, "capacity:        %d\n", r.Capacity)
+	fmt.Fprintf(stdout, "file size:       %d\n", r.FileSize)
+	fmt.Fprintf(stdout, "expected size:   %d\n", r.ExpectedSize)
+	fmt.Fprintf(stdout, "mapped size:     %d\n", r.MappedSize)
+	fmt.Fprintf(stdout, "stuck seqlocks:  %d\n", r.StuckSeqlocks)
+	fmt.Fprintf(stdout, "free ratio:      %.4f\n", r.FreeRatio)
+	return nil
+}
+
+// inspectFile maps path read-only, decodes its header without any schema
+// check and scans the live records for stuck (odd) seqlocks.
+func inspectFile(path string) (*inspectReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+	size := info.Size()
+	if size < mmapforge.HeaderSizeV1 {
+		return nil, errors.Join(
+			fmt.Errorf("%s is too small (%d bytes)", path, size),
+			f.Close(),
+		)
+	}
+
+	region, err := mmapforge.Map(f, int(size), false, mmapforge.Sequential, int(size))
+	if err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+	defer region.Close()
+
+	h, err := mmapforge.DecodeHeader(region.Slice(0, min(int(size), mmapforge.HeaderSize)))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &inspectReport{
+		Path:          path,
+		Magic:         string(h.Magic[:]),
+		FormatVersion: h.FormatVersion,
+		MultiWriter:   h.Flags&mmapforge.FlagMultiWriter != 0,
+		SchemaHash:    hex.EncodeToString(h.SchemaHash[:]),
+		SchemaVersion: h.SchemaVersion,
+		RecordSize:    h.RecordSize,
+		RecordCount:   h.RecordCount,
+		Capacity:      h.Capacity,
+		FileSize:      size,
+		ExpectedSize:  uint64(h.Size()) + h.Capacity*uint64(h.RecordSize),
+		MappedSize:    int64(region.Mapped()),
+	}
+	if h.FormatVersion >= 2 {
+		// The writer record follows the flags word; see Store.Writer.
+		r.WriterPID = binary.LittleEndian.Uint64(region.Slice(72, 8))
+	}
+	if h.Capacity > 0 {
+		r.FreeRatio = float64(h.Capacity-min(h.RecordCount, h.Capacity)) / float64(h.Capacity)
+	}
+
+	if h.RecordSize >= mmapforge.SeqFieldSize {
+		inFile := uint64(size-int64(h.Size())) / uint64(h.RecordSize)
+		for i := uint64(0); i < min(h.RecordCount, inFile); i++ {
+			off := h.Size() + int(i)*int(h.RecordSize)
+			if binary.LittleEndian.Uint64(region.Slice(off, mmapforge.SeqFieldSize))&1 != 0 {
+				r.StuckSeqlocks++
+			}
+		}
+	}
+	return r, nil
+}
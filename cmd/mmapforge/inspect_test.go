@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+func testStoreLayout(t *testing.T) *mmapforge.RecordLayout {
+	t.Helper()
+	layout, err := mmapforge.ComputeLayout([]mmapforge.FieldDef{
+		{Name: "id", GoName: "ID", Type: mmapforge.FieldUint64},
+		{Name: "price", GoName: "Price", Type: mmapforge.FieldFloat64},
+		{Name: "name", GoName: "Name", Type: mmapforge.FieldString, MaxSize: 8},
+		{Name: "live", GoName: "Live", Type: mmapforge.FieldBool},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return layout
+}
+
+// writeTestStore creates a store with n records (id=i, price=i*1.5,
+// name="r<i>", live=i%2==0) and returns its path.
+func writeTestStore(t *testing.T, dir, name string, n int) string {
+	t.Helper()
+	layout := testStoreLayout(t)
+	path := filepath.Join(dir, name)
+	s, err := mmapforge.CreateStore(path, layout, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		idx, err := s.Append()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.WriteUint64(idx, layout.Fields[0].Offset, uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.WriteFloat64(idx, layout.Fields[1].Offset, float64(i)*1.5); err != nil {
+			t.Fatal(err)
+		}
+		nf := layout.Fields[2]
+		if err := s.WriteString(idx, nf.Offset, nf.Size, nf.MaxSize, "r"+string(rune('a'+i%26))); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.WriteBool(idx, layout.Fields[3].Offset, i%2 == 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func captureStdout(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	orig := stdout
+	var buf bytes.Buffer
+	stdout = &buf
+	t.Cleanup(func() { stdout = orig })
+	return &buf
+}
+
+func TestInspectFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestStore(t, dir, "a.mmf", 16)
+
+	s, err := mmapforge.OpenStore(path, testStoreLayout(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SeqBeginWrite(3)
+	s.SeqBeginWrite(5)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := inspectFile(path)
+	if err != nil {
+		t.Fatalf("inspectFile: %v", err)
+	}
+	if r.Magic != mmapforge.MagicString || r.FormatVersion != mmapforge.Version {
+		t.Errorf("magic/version = %s/%d", r.Magic, r.FormatVersion)
+	}
+	if r.SchemaVersion != 3 || r.RecordCount != 16 || r.Capacity != 64 {
+		t.Errorf("report = %+v", r)
+	}
+	if r.WriterPID != 0 {
+		t.Errorf("WriterPID = %d after the writer closed", r.WriterPID)
+	}
+	if r.StuckSeqlocks != 2 {
+		t.Errorf("StuckSeqlocks = %d, want 2", r.StuckSeqlocks)
+	}
+	if r.FreeRatio != 0.75 {
+		t.Errorf("FreeRatio = %v, want 0.75", r.FreeRatio)
+	}
+	if uint64(r.FileSize) != r.ExpectedSize {
+		t.Errorf("FileSize %d != ExpectedSize %d", r.FileSize, r.ExpectedSize)
+	}
+	hash := mmapforge.SchemaHash(testStoreLayout(t).Descriptors())
+	if r.SchemaHash != hex.EncodeToString(hash[:]) {
+		t.Errorf("SchemaHash = %s, want %x", r.SchemaHash, hash)
+	}
+}
+
+func TestInspectFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := inspectFile(filepath.Join(dir, "missing.mmf")); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	small := filepath.Join(dir, "small.mmf")
+	if err := os.WriteFile(small, []byte("MMFG"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inspectFile(small); err == nil || !strings.Contains(err.Error(), "too small") {
+		t.Errorf("err = %v, want too small", err)
+	}
+
+	bad := filepath.Join(dir, "bad.mmf")
+	if err := os.WriteFile(bad, make([]byte, 128), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inspectFile(bad); err == nil {
+		t.Error("expected error for bad magic")
+	}
+}
+
+func TestRunInspect_Text(t *testing.T) {
+	path := writeTestStore(t, t.TempDir(), "a.mmf", 4)
+	out := captureStdout(t)
+
+	if err := runInspect([]string{path}); err != nil {
+		t.Fatalf("runInspect: %v", err)
+	}
+	for _, want := range []string{"record count:    4", "capacity:        64", "stuck seqlocks:  0"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunInspect_JSON(t *testing.T) {
+	path := writeTestStore(t, t.TempDir(), "a.mmf", 4)
+	out := captureStdout(t)
+
+	if err := runInspect([]string{"-json", path}); err != nil {
+		t.Fatalf("runInspect: %v", err)
+	}
+	var r inspectReport
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, out)
+	}
+	if r.RecordCount != 4 || r.Path != path {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestRunInspect_Errors(t *testing.T) {
+	captureStdout(t)
+	if err := runInspect(nil); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("no args err = %v, want usage", err)
+	}
+	if err := runInspect([]string{"-nope"}); err == nil {
+		t.Error("expected flag parse error")
+	}
+	if err := runInspect([]string{"/no/such/file.mmf"}); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestMain_Inspect(t *testing.T) {
+	path := writeTestStore(t, t.TempDir(), "a.mmf", 1)
+	captureStdout(t)
+
+	code, out := stubMain(t, []string{"mmapforge", "inspect", path})
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0; stderr: %s", code, out)
+	}
+
+	code, out = stubMain(t, []string{"mmapforge", "inspect"})
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(out, "mmapforge inspect:") {
+		t.Errorf("stderr = %q, want subcommand error", out)
+	}
+}
+
+// rewriteAsV1 converts the store at path to format version 1 in place: the
+// 64-byte header, with the records moved up behind it.
+func rewriteAsV1(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := mmapforge.DecodeHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := append(make([]byte, mmapforge.HeaderSizeV1), data[h.Size():]...)
+	h.FormatVersion, h.Flags = 1, 0
+	if err := mmapforge.EncodeHeader(out, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInspectFile_V1(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestStore(t, dir, "a.mmf", 16)
+	rewriteAsV1(t, path)
+
+	r, err := inspectFile(path)
+	if err != nil {
+		t.Fatalf("inspectFile: %v", err)
+	}
+	if r.FormatVersion != 1 || r.RecordCount != 16 || r.StuckSeqlocks != 0 {
+		t.Errorf("report = %+v", r)
+	}
+	if uint64(r.FileSize) != r.ExpectedSize {
+		t.Errorf("FileSize %d != ExpectedSize %d", r.FileSize, r.ExpectedSize)
+	}
+
+	fr, err := fsckFile(path, testStoreLayout(t), false)
+	if err != nil {
+		t.Fatalf("fsckFile: %v", err)
+	}
+	if len(fr.Issues) != 0 {
+		t.Errorf("fsck issues on a clean v1 file: %+v", fr.Issues)
+	}
+}
@@ -1,37 +1,90 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/CreditWorthy/mmapforge/internal/codegen"
 )
 
 var exitFunc = os.Exit
 var stderr io.Writer = os.Stderr
+var stdout io.Writer = os.Stdout
+
+// commands are the subcommands selected by the first argument. Without one,
+// mmapforge runs the code generator.
+var commands = map[string]func(args []string) error{
+	"inspect": runInspect,
+	"dump":    runDump,
+	"export":  runDump,
+	"import":  runImport,
+	"fsck":    runFsck,
+	"diff":    runDiff,
+}
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintf(stderr, "mmapforge %s: %v\n", os.Args[1], err)
+				exitFunc(1)
+			}
+			return
+		}
+	}
+
 	input := flag.String("input", "", "Go source file containing mmapforge-annotated structs")
-	output := flag.String("output", "", "Output directory (default: same directory as input)")
+	pkg := flag.String("pkg", "", "Package pattern to generate for, e.g. ./internal/feeds or ./... (patterns may also be given as arguments)")
+	output := flag.String("output", "", "Output directory (default: the directory of the input file or package)")
+	var plugins pluginFlags
+	flag.Var(&plugins, "plugin", "Generator plugin to run after the built-in templates: a Go package (e.g. ./tools/myext) or an executable; repeatable")
+	configPath := flag.String("config", "", "Config file (default: mmapforge.yaml, mmapforge.yml or mmapforge.toml in the working directory, if present)")
+	check := flag.Bool("check", false, "Check schemas against mmapforge.lock without writing anything; fails if a schema changed without a version bump")
 	flag.Parse()
 
-	if *input == "" {
-		fmt.Fprintln(stderr, "mmapforge: -input flag is required")
+	patterns := flag.Args()
+	if *pkg != "" {
+		patterns = append([]string{*pkg}, patterns...)
+	}
+
+	if *input == "" && len(patterns) == 0 {
+		fmt.Fprintln(stderr, "mmapforge: -input flag or a package pattern (e.g. ./...) is required")
 		exitFunc(1)
 		return
 	}
 
-	if err := run(*input, *output); err != nil {
+	fc, err := loadConfig(*configPath)
+	if err == nil {
+		fc.Plugins = append(fc.Plugins, plugins...)
+		if *check {
+			// Plugins only write files; there is nothing for them to check.
+			fc.Plugins = nil
+		}
+	}
+	switch {
+	case err != nil:
+	case *input != "" && len(patterns) > 0:
+		err = fmt.Errorf("-input cannot be combined with package patterns")
+	case *input != "":
+		err = run(fc, *input, *output, *check)
+	default:
+		err = runPackages(fc, patterns, *output, *check)
+	}
+	if err != nil {
 		fmt.Fprintf(stderr, "mmapforge: %v\n", err)
 		exitFunc(1)
 		return
 	}
 }
 
-func run(inputPath, outputDir string) error {
+// run generates stores for the mmapforge:schema structs in inputPath, or
+// only checks them against the lock file if check is set.
+func run(fc *fileConfig, inputPath, outputDir string, check bool) error {
 	schemas, err := codegen.ParseFile(inputPath)
 	if err != nil {
 		return err
@@ -45,14 +98,113 @@ func run(inputPath, outputDir string) error {
 		outputDir = filepath.Dir(inputPath)
 	}
 
-	g, err := codegen.NewGraph(&codegen.Config{
-		Target:  outputDir,
-		Package: schemas[0].Package,
-	}, schemas)
+	base, cleanup, err := baseConfig(fc)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	pkg := schemas[0].Package
+	declared, err := codegen.DeclaredInDir(outputDir, pkg)
+	if err != nil {
+		return err
+	}
+	g, err := newGraph(base, schemas, pkg, outputDir, declared)
+	if err != nil {
+		return err
+	}
+	if err := fc.checkTypes([]*codegen.Graph{g}); err != nil {
+		return err
+	}
+	if check {
+		return checkLocks([]*codegen.Graph{g})
+	}
+	return gen(g)
+}
+
+// runPackages generates stores for every mmapforge:schema struct in the
+// packages matching patterns, next to each package's sources unless
+// outputDir is set. All packages are checked for name collisions before
+// any file is written. If check is set, nothing is written and the
+// packages are only checked against their lock files.
+func runPackages(fc *fileConfig, patterns []string, outputDir string, check bool) error {
+	pkgs, err := codegen.LoadPackages("", patterns...)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) == 0 {
+		return fmt.Errorf("no mmapforge:schema directives found in %s", strings.Join(patterns, " "))
+	}
+	if outputDir != "" && len(pkgs) > 1 {
+		return fmt.Errorf("-output needs a single package; %d packages have schemas", len(pkgs))
+	}
+
+	base, cleanup, err := baseConfig(fc)
 	if err != nil {
 		return err
 	}
+	defer cleanup()
+	graphs := make([]*codegen.Graph, 0, len(pkgs))
+	for _, p := range pkgs {
+		target, declared := p.Dir, p.Declared
+		if outputDir != "" {
+			target = outputDir
+			if declared, err = codegen.DeclaredInDir(outputDir, p.Name); err != nil {
+				return err
+			}
+		}
+		g, err := newGraph(base, p.Schemas, p.Name, target, declared)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Path, err)
+		}
+		graphs = append(graphs, g)
+	}
+	if err := fc.checkTypes(graphs); err != nil {
+		return err
+	}
+	if check {
+		return checkLocks(graphs)
+	}
+	for _, g := range graphs {
+		if err := gen(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// baseConfig returns the codegen config shared by every generated package,
+// with the plugins of fc built and installed as hooks. cleanup removes the
+// built plugins.
+func baseConfig(fc *fileConfig) (*codegen.Config, func(), error) {
+	c, err := fc.codegenConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	hooks, cleanup, err := pluginHooks(fc.Plugins)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Hooks = hooks
+	return c, cleanup, nil
+}
+
+// newGraph builds the codegen graph for one package from the base config
+// and checks generated names against declared.
+func newGraph(base *codegen.Config, schemas []codegen.StructSchema, pkg, target string, declared map[string]string) (*codegen.Graph, error) {
+	c := *base
+	c.Target, c.Package = target, pkg
+	g, err := codegen.NewGraph(&c, schemas)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.CheckCollisions(declared); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// gen writes the files of g and reports each generated type.
+func gen(g *codegen.Graph) error {
 	if err := g.Gen(); err != nil {
 		return err
 	}
@@ -64,3 +216,19 @@ func run(inputPath, outputDir string) error {
 
 	return nil
 }
+
+// checkLocks checks every graph against its lock file and reports each type
+// that passes. The errors of all graphs are returned together.
+func checkLocks(graphs []*codegen.Graph) error {
+	var errs []error
+	for _, g := range graphs {
+		if err := g.CheckLock(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, n := range g.Nodes {
+			fmt.Fprintf(stderr, "mmapforge: %s v%d matches %s\n", n.Name, n.SchemaVersion, codegen.LockFile)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -20,7 +20,7 @@ func writeTempSchema(t *testing.T, dir, src string) string {
 }
 
 func TestRun_ParseFileError(t *testing.T) {
-	err := run("/no/such/file.go", "")
+	err := run(&fileConfig{}, "/no/such/file.go", "", false)
 	if err == nil {
 		t.Fatal("expected error for nonexistent file")
 	}
@@ -31,7 +31,7 @@ func TestRun_NoSchemas(t *testing.T) {
 	path := writeTempSchema(t, dir, `package x
 type Foo struct { A int32 }
 `)
-	err := run(path, "")
+	err := run(&fileConfig{}, path, "", false)
 	if err == nil {
 		t.Fatal("expected error for no schemas")
 	}
@@ -50,7 +50,7 @@ type Dup struct {
 }
 `
 	path := writeTempSchema(t, dir, src)
-	err := run(path, "")
+	err := run(&fileConfig{}, path, "", false)
 	if err == nil {
 		t.Fatal("expected error from NewGraph (duplicate field names)")
 	}
@@ -80,7 +80,7 @@ type Good struct {
 		}
 	}()
 
-	err := run(path, badDir)
+	err := run(&fileConfig{}, path, badDir, false)
 	if err == nil {
 		t.Fatal("expected error from Gen (unwritable output dir)")
 	}
@@ -96,7 +96,7 @@ type Player struct {
 }
 `
 	path := writeTempSchema(t, dir, src)
-	err := run(path, "")
+	err := run(&fileConfig{}, path, "", false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -117,6 +117,40 @@ type Player struct {
 	}
 }
 
+func TestRun_DeclaredCollision(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempSchema(t, dir, tickSchema)
+	stores := filepath.Join(dir, "stores.go")
+	if err := os.WriteFile(stores, []byte("package ticks\n\nvar Stores = 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	other := "package other\n\nfunc OpenAll() {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "doc_other.go"), []byte(other), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := run(&fileConfig{}, path, "", false)
+	if err == nil || !strings.Contains(err.Error(), "Stores generated for the registry collides with Stores declared at "+stores) {
+		t.Errorf("err = %v, want a collision with the declared Stores", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mmapforge_registry.go")); err == nil {
+		t.Error("no file should be written when a collision is found")
+	}
+
+	// Declarations in the -output directory count too.
+	out := t.TempDir()
+	if err := os.WriteFile(filepath.Join(out, "stores.go"), []byte("package ticks\n\nfunc Schemas() {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(stores); err != nil {
+		t.Fatal(err)
+	}
+	err = run(&fileConfig{}, path, out, false)
+	if err == nil || !strings.Contains(err.Error(), "Schemas generated for the registry collides with Schemas declared at") {
+		t.Errorf("-output: err = %v, want a collision with the declared Schemas", err)
+	}
+}
+
 func TestRun_Success_ExplicitOutput(t *testing.T) {
 	dir := t.TempDir()
 	outDir := filepath.Join(dir, "out")
@@ -131,7 +165,7 @@ type Item struct {
 }
 `
 	path := writeTempSchema(t, dir, src)
-	err := run(path, outDir)
+	err := run(&fileConfig{}, path, outDir, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -175,7 +209,7 @@ func TestMain_NoInput(t *testing.T) {
 	if code != 1 {
 		t.Errorf("exit code = %d, want 1", code)
 	}
-	if !strings.Contains(out, "-input flag is required") {
+	if !strings.Contains(out, "-input flag or a package pattern") {
 		t.Errorf("stderr = %q, want input flag message", out)
 	}
 }
@@ -203,3 +237,194 @@ type Z struct {
 		t.Errorf("exit code = %d, want 0; stderr: %s", code, out)
 	}
 }
+
+// writeTempModule writes files (slash-separated, relative to the module
+// root) into a temporary module and makes it the working directory.
+func writeTempModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	files["go.mod"] = "module example.com/feeds\n\ngo 1.24\n"
+	for name, src := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+	return root
+}
+
+const (
+	tickSchema  = "package ticks\n\n// mmapforge:schema version=1\ntype Tick struct {\n\tPrice float64\n}\n"
+	quoteSchema = "package ticks\n\n// mmapforge:schema version=1\ntype Quote struct {\n\tBid float64\n}\n"
+	orderSchema = "package orders\n\n// mmapforge:schema version=1\ntype Order struct {\n\tQty int64\n}\n"
+)
+
+func TestRunPackages(t *testing.T) {
+	root := writeTempModule(t, map[string]string{
+		"ticks/tick.go":   tickSchema,
+		"ticks/quote.go":  quoteSchema,
+		"orders/order.go": orderSchema,
+	})
+	origStderr := stderr
+	defer func() { stderr = origStderr }()
+	var buf bytes.Buffer
+	stderr = &buf
+
+	if err := runPackages(&fileConfig{}, []string{"./..."}, "", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"ticks/tick_store.go", "ticks/quote_store.go", "orders/order_store.go"} {
+		if _, err := os.Stat(filepath.Join(root, f)); err != nil {
+			t.Errorf("%s not generated: %v", f, err)
+		}
+	}
+	if strings.Count(buf.String(), "bytes/record") != 3 {
+		t.Errorf("stderr = %q", buf.String())
+	}
+
+	// A second run skips the generated files and regenerates the same stores.
+	if err := runPackages(&fileConfig{}, []string{"./ticks"}, "", false); err != nil {
+		t.Errorf("regenerate: %v", err)
+	}
+}
+
+func TestRunPackages_Output(t *testing.T) {
+	writeTempModule(t, map[string]string{
+		"ticks/tick.go":   tickSchema,
+		"orders/order.go": orderSchema,
+	})
+	origStderr := stderr
+	defer func() { stderr = origStderr }()
+	stderr = &bytes.Buffer{}
+
+	out := t.TempDir()
+	if err := runPackages(&fileConfig{}, []string{"./ticks"}, out, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "tick_store.go")); err != nil {
+		t.Error(err)
+	}
+
+	err := runPackages(&fileConfig{}, []string{"./..."}, out, false)
+	if err == nil || !strings.Contains(err.Error(), "-output needs a single package") {
+		t.Errorf("err = %v", err)
+	}
+
+	declared := t.TempDir()
+	if err := os.WriteFile(filepath.Join(declared, "stores.go"), []byte("package ticks\n\ntype Stores struct{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err = runPackages(&fileConfig{}, []string{"./ticks"}, declared, false)
+	if err == nil || !strings.Contains(err.Error(), "Stores generated for the registry collides with Stores declared at") {
+		t.Errorf("-output with a declared Stores: err = %v", err)
+	}
+}
+
+func TestRunPackages_Errors(t *testing.T) {
+	writeTempModule(t, map[string]string{
+		"ticks/tick.go":    tickSchema,
+		"ticks/store.go":   "package ticks\n\ntype TickStore struct{}\n",
+		"plain/plain.go":   "package plain\n",
+		"broken/broken.go": "package broken\n\n// mmapforge:schema version=1\ntype Broken struct {\n\tC complex64\n}\n",
+		"layout/layout.go": "package layout\n\n// mmapforge:schema version=1\ntype Dup struct {\n\tA int32 `mmap:\"x\"`\n\tB int32 `mmap:\"x\"`\n}\n",
+		"orders/order.go":  orderSchema,
+		"orders/order2.go": "package orders\n\n// mmapforge:schema version=1\ntype OrderStore struct {\n\tQty int64\n}\n",
+	})
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"./ticks", "TickStore generated for Tick collides with TickStore declared at"},
+		{"./orders", "OrderStore generated for Order collides with schema struct OrderStore"},
+		{"./plain", "no mmapforge:schema directives found in ./plain"},
+		{"./broken", "broken.go"},
+		{"./layout", "example.com/feeds/layout:"},
+		{"./nope", "nope"},
+	}
+	for _, tt := range tests {
+		err := runPackages(&fileConfig{}, []string{tt.pattern}, "", false)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want containing %q", tt.pattern, err, tt.want)
+		}
+	}
+	if _, err := os.Stat("ticks/tick_store.go"); err == nil {
+		t.Error("no file should be written when a collision is found")
+	}
+}
+
+func TestMain_Packages(t *testing.T) {
+	writeTempModule(t, map[string]string{"ticks/tick.go": tickSchema})
+
+	code, out := stubMain(t, []string{"mmapforge", "-pkg", "./ticks"})
+	if code != 0 || !strings.Contains(out, "Tick v1") {
+		t.Errorf("-pkg: exit code = %d, stderr = %q", code, out)
+	}
+	code, out = stubMain(t, []string{"mmapforge", "./..."})
+	if code != 0 {
+		t.Errorf("./...: exit code = %d, stderr = %q", code, out)
+	}
+	code, out = stubMain(t, []string{"mmapforge", "-input", "ticks/tick.go", "./..."})
+	if code != 1 || !strings.Contains(out, "-input cannot be combined") {
+		t.Errorf("-input with patterns: exit code = %d, stderr = %q", code, out)
+	}
+}
+
+func TestMain_Check(t *testing.T) {
+	root := writeTempModule(t, map[string]string{"ticks/tick.go": tickSchema})
+	writeTick := func(src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, "ticks", "tick.go"), []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, out := stubMain(t, []string{"mmapforge", "-check", "./..."})
+	if code != 1 || !strings.Contains(out, "Tick v1: not recorded in mmapforge.lock") {
+		t.Errorf("before generating: exit code = %d, stderr = %q", code, out)
+	}
+	if _, err := os.Stat(filepath.Join(root, "ticks", "tick_store.go")); err == nil {
+		t.Error("-check should not write any file")
+	}
+
+	if code, out := stubMain(t, []string{"mmapforge", "./..."}); code != 0 {
+		t.Fatalf("generate: exit code = %d, stderr = %q", code, out)
+	}
+	// Plugins are not built or run in check mode.
+	code, out = stubMain(t, []string{"mmapforge", "-check", "-plugin", "./no/such/plugin", "./..."})
+	if code != 0 || !strings.Contains(out, "Tick v1 matches mmapforge.lock") {
+		t.Errorf("after generating: exit code = %d, stderr = %q", code, out)
+	}
+	code, out = stubMain(t, []string{"mmapforge", "-check", "-input", "ticks/tick.go"})
+	if code != 0 {
+		t.Errorf("-input: exit code = %d, stderr = %q", code, out)
+	}
+
+	changed := strings.Replace(tickSchema, "Price float64\n", "Price float64\n\tSize uint32\n", 1)
+	writeTick(changed)
+	code, out = stubMain(t, []string{"mmapforge", "-check", "./..."})
+	if code != 1 || !strings.Contains(out, "Tick v1: schema changed without a version bump") {
+		t.Errorf("changed: exit code = %d, stderr = %q", code, out)
+	}
+	code, out = stubMain(t, []string{"mmapforge", "./..."})
+	if code != 1 || !strings.Contains(out, "without a version bump") {
+		t.Errorf("generate changed: exit code = %d, stderr = %q", code, out)
+	}
+
+	writeTick(strings.Replace(changed, "version=1", "version=2", 1))
+	if code, out := stubMain(t, []string{"mmapforge", "./..."}); code != 0 {
+		t.Fatalf("generate v2: exit code = %d, stderr = %q", code, out)
+	}
+	if code, out := stubMain(t, []string{"mmapforge", "-check", "./..."}); code != 0 {
+		t.Errorf("check v2: exit code = %d, stderr = %q", code, out)
+	}
+
+	writeTick(tickSchema)
+	code, out = stubMain(t, []string{"mmapforge", "-check", "./..."})
+	if code != 1 || !strings.Contains(out, "Tick v1: version went backwards (v2 is locked)") {
+		t.Errorf("backwards: exit code = %d, stderr = %q", code, out)
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// A minimal Apache Parquet writer, enough to export mmapforge records
+// without an external dependency: flat schema, REQUIRED columns, PLAIN
+// encoding, no compression, one data page per column per row group.
+// Metadata is serialized with the Thrift compact protocol.
+
+const parquetMagic = "PAR1"
+
+// parquetRowGroupSize is the number of rows buffered per row group.
+var parquetRowGroupSize = 64 * 1024
+
+// parquetPageBytes flushes the row group early once any column has buffered
+// this many bytes, so wide string and bytes columns stay far below the
+// int32 page sizes in the page header.
+var parquetPageBytes = 64 << 20
+
+// parquetMaxPage is the largest page the int32 page sizes can describe.
+// A single value can still exceed the budget above; such a page is refused.
+// Tests lower it.
+var parquetMaxPage = math.MaxInt32
+
+// Parquet physical types.
+const (
+	pqBoolean   = 0
+	pqInt32     = 1
+	pqInt64     = 2
+	pqFloat     = 4
+	pqDouble    = 5
+	pqByteArray = 6
+)
+
+// Parquet converted (legacy logical) types.
+const (
+	pqNone   = -1
+	pqUTF8   = 0
+	pqUint8  = 11
+	pqUint16 = 12
+	pqUint32 = 13
+	pqUint64 = 14
+	pqInt8   = 15
+	pqInt16  = 16
+	pqInt32C = 17
+)
+
+// parquetType maps a FieldType to its physical and converted types.
+func parquetType(ft mmapforge.FieldType) (physical, converted int) {
+	switch ft {
+	case mmapforge.FieldBool:
+		return pqBoolean, pqNone
+	case mmapforge.FieldInt8:
+		return pqInt32, pqInt8
+	case mmapforge.FieldUint8:
+		return pqInt32, pqUint8
+	case mmapforge.FieldInt16:
+		return pqInt32, pqInt16
+	case mmapforge.FieldUint16:
+		return pqInt32, pqUint16
+	case mmapforge.FieldInt32:
+		return pqInt32, pqInt32C
+	case mmapforge.FieldUint32:
+		return pqInt32, pqUint32
+	case mmapforge.FieldInt64:
+		return pqInt64, pqNone
+	case mmapforge.FieldUint64:
+		return pqInt64, pqUint64
+	case mmapforge.FieldFloat32:
+		return pqFloat, pqNone
+	case mmapforge.FieldFloat64:
+		return pqDouble, pqNone
+	case mmapforge.FieldString:
+		return pqByteArray, pqUTF8
+	default:
+		return pqByteArray, pqNone
+	}
+}
+
+// parquetColumn buffers the PLAIN-encoded values of one column for the
+// current row group.
+type parquetColumn struct {
+	field mmapforge.FieldLayout
+	buf   bytes.Buffer
+	bits  byte
+	nbits int
+}
+
+func (c *parquetColumn) add(v any) {
+	var tmp [8]byte
+	switch x := v.(type) {
+	case bool:
+		if x {
+			c.bits |= 1 << c.nbits
+		}
+		c.nbits++
+		if c.nbits == 8 {
+			c.flushBits()
+		}
+	case int8:
+		binary.LittleEndian.PutUint32(tmp[:], uint32(int32(x)))
+		c.buf.Write(tmp[:4])
+	case uint8:
+		binary.LittleEndian.PutUint32(tmp[:], uint32(x))
+		c.buf.Write(tmp[:4])
+	case int16:
+		binary.LittleEndian.PutUint32(tmp[:], uint32(int32(x)))
+		c.buf.Write(tmp[:4])
+	case uint16:
+		binary.LittleEndian.PutUint32(tmp[:], uint32(x))
+		c.buf.Write(tmp[:4])
+	case int32:
+		binary.LittleEndian.PutUint32(tmp[:], uint32(x))
+		c.buf.Write(tmp[:4])
+	case uint32:
+		binary.LittleEndian.PutUint32(tmp[:], x)
+		c.buf.Write(tmp[:4])
+	case int64:
+		binary.LittleEndian.PutUint64(tmp[:], uint64(x))
+		c.buf.Write(tmp[:8])
+	case uint64:
+		binary.LittleEndian.PutUint64(tmp[:], x)
+		c.buf.Write(tmp[:8])
+	case float32:
+		binary.LittleEndian.PutUint32(tmp[:], math.Float32bits(x))
+		c.buf.Write(tmp[:4])
+	case float64:
+		binary.LittleEndian.PutUint64(tmp[:], math.Float64bits(x))
+		c.buf.Write(tmp[:8])
+	case string:
+		binary.LittleEndian.PutUint32(tmp[:], uint32(len(x)))
+		c.buf.Write(tmp[:4])
+		c.buf.WriteString(x)
+	case []byte:
+		binary.LittleEndian.PutUint32(tmp[:], uint32(len(x)))
+		c.buf.Write(tmp[:4])
+		c.buf.Write(x)
+	}
+}
+
+func (c *parquetColumn) flushBits() {
+	if c.nbits > 0 {
+		c.buf.WriteByte(c.bits)
+	}
+	c.bits, c.nbits = 0, 0
+}
+
+// parquetChunk records where a column chunk landed in the file.
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+// parquetRowGroup is the footer metadata of one flushed row group.
+type parquetRowGroup struct {
+	rows   int64
+	chunks []parquetChunk
+}
+
+// parquetWriter streams rows into row groups and writes the footer on Close.
+type parquetWriter struct {
+	w         io.Writer
+	cols      []*parquetColumn
+	rows      int
+	offset    int64
+	rowGroups []parquetRowGroup
+	err       error
+}
+
+func newParquetWriter(w io.Writer, fields []mmapforge.FieldLayout) *parquetWriter {
+	pw := &parquetWriter{w: w}
+	for _, f := range fields {
+		pw.cols = append(pw.cols, &parquetColumn{field: f})
+	}
+	pw.write([]byte(parquetMagic))
+	return pw
+}
+
+func (pw *parquetWriter) write(b []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	pw.err = err
+}
+
+// Write buffers one row; values are in field order.
+func (pw *parquetWriter) Write(values []any) error {
+	for i, v := range values {
+		pw.cols[i].add(v)
+	}
+	pw.rows++
+	if pw.rows >= parquetRowGroupSize || pw.buffered() >= parquetPageBytes {
+		pw.flushRowGroup()
+	}
+	return pw.err
+}
+
+// buffered returns the largest number of bytes buffered by one column.
+func (pw *parquetWriter) buffered() int {
+	n := 0
+	for _, c := range pw.cols {
+		n = max(n, c.buf.Len())
+	}
+	return n
+}
+
+// flushRowGroup writes one data page per column for the buffered rows, or
+// records an error if a page is too large for the page header.
+func (pw *parquetWriter) flushRowGroup() {
+	if pw.rows == 0 {
+		return
+	}
+	for _, c := range pw.cols {
+		c.flushBits()
+		if n := c.buf.Len(); n > parquetMaxPage && pw.err == nil {
+			pw.err = fmt.Errorf("parquet: column %s: page of %d bytes exceeds the %d-byte page limit", c.field.Name, n, parquetMaxPage)
+		}
+	}
+	if pw.err != nil {
+		return
+	}
+	rg := parquetRowGroup{rows: int64(pw.rows)}
+	for _, c := range pw.cols {
+		data := c.buf.Bytes()
+
+		var hdr thriftWriter
+		hdr.i32(1, 0) // type: DATA_PAGE
+		hdr.i32(2, int32(len(data)))
+		hdr.i32(3, int32(len(data)))
+		hdr.structBegin(5) // data_page_header
+		hdr.i32(1, int32(pw.rows))
+		hdr.i32(2, 0) // encoding: PLAIN
+		hdr.i32(3, 3) // definition_level_encoding: RLE
+		hdr.i32(4, 3) // repetition_level_encoding: RLE
+		hdr.structEnd()
+		hdr.stop()
+
+		start := pw.offset
+		pw.write(hdr.Bytes())
+		pw.write(data)
+		rg.chunks = append(rg.chunks, parquetChunk{offset: start, size: pw.offset - start})
+		c.buf.Reset()
+	}
+	pw.rowGroups = append(pw.rowGroups, rg)
+	pw.rows = 0
+}
+
+// Close flushes the last row group and writes the file footer.
+func (pw *parquetWriter) Close() error {
+	pw.flushRowGroup()
+
+	var numRows int64
+	for _, rg := range pw.rowGroups {
+		numRows += rg.rows
+	}
+
+	var m thriftWriter
+	m.i32(1, 1) // version
+	m.listBegin(2, thriftStruct, len(pw.cols)+1)
+	m.elemBegin()
+	m.binary(4, "schema")
+	m.i32(5, int32(len(pw.cols)))
+	m.elemEnd()
+	for _, c := range pw.cols {
+		physical, converted := parquetType(c.field.Type)
+		m.elemBegin()
+		m.i32(1, int32(physical))
+		m.i32(3, 0) // repetition_type: REQUIRED
+		m.binary(4, c.field.Name)
+		if converted != pqNone {
+			m.i32(6, int32(converted))
+		}
+		m.elemEnd()
+	}
+	m.i64(3, numRows)
+	m.listBegin(4, thriftStruct, len(pw.rowGroups))
+	for _, rg := range pw.rowGroups {
+		var total int64
+		for _, ch := range rg.chunks {
+			total += ch.size
+		}
+		m.elemBegin()
+		m.listBegin(1, thriftStruct, len(rg.chunks))
+		for i, ch := range rg.chunks {
+			physical, _ := parquetType(pw.cols[i].field.Type)
+			m.elemBegin()
+			m.i64(2, ch.offset) // file_offset
+			m.structBegin(3)    // meta_data
+			m.i32(1, int32(physical))
+			m.listBegin(2, thriftI32, 1)
+			m.listI32(0) // PLAIN
+			m.listBegin(3, thriftBinary, 1)
+			m.listBinary(pw.cols[i].field.Name)
+			m.i32(4, 0) // codec: UNCOMPRESSED
+			m.i64(5, rg.rows)
+			m.i64(6, ch.size)
+			m.i64(7, ch.size)
+			m.i64(9, ch.offset) // data_page_offset
+			m.structEnd()
+			m.elemEnd()
+		}
+		m.i64(2, total)
+		m.i64(3, rg.rows)
+		m.elemEnd()
+	}
+	m.binary(6, "mmapforge")
+	m.stop()
+
+	footer := m.Bytes()
+	pw.write(footer)
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(footer)))
+	pw.write(n[:])
+	pw.write([]byte(parquetMagic))
+	return pw.err
+}
+
+// Thrift compact protocol type ids.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol. Nested
+// structs push the last field id so deltas stay relative to their parent.
+type thriftWriter struct {
+	bytes.Buffer
+	last  int16
+	stack []int16
+}
+
+func (t *thriftWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.Buffer.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if d := id - t.last; d > 0 && d <= 15 {
+		t.WriteByte(byte(d)<<4 | typ)
+	} else {
+		t.WriteByte(typ)
+		t.uvarint(uint64(uint16((id << 1) ^ (id >> 15))))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.uvarint(uint64(uint32((v << 1) ^ (v >> 31))))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.uvarint(uint64(len(s)))
+	t.WriteString(s)
+}
+
+func (t *thriftWriter) listBegin(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.WriteByte(byte(n)<<4 | elem)
+	} else {
+		t.WriteByte(0xf0 | elem)
+		t.uvarint(uint64(n))
+	}
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.uvarint(uint64(uint32((v << 1) ^ (v >> 31))))
+}
+
+func (t *thriftWriter) listBinary(s string) {
+	t.uvarint(uint64(len(s)))
+	t.WriteString(s)
+}
+
+// structBegin starts a struct-typed field.
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+func (t *thriftWriter) structEnd() { t.elemEnd() }
+
+// elemBegin starts a struct that is a list element (no field header).
+func (t *thriftWriter) elemBegin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) elemEnd() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thriftWriter) stop() { t.WriteByte(0) }
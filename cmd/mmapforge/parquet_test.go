@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// thriftReader decodes Thrift compact structs into map[fieldID]value so the
+// tests can check the footer without a Parquet dependency.
+type thriftReader struct {
+	b []byte
+	p int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.p:])
+	r.p += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.uvarint())
+		r.p += n
+		return string(r.b[r.p-n : r.p])
+	case thriftList:
+		h := r.b[r.p]
+		r.p++
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		out := make([]any, n)
+		for i := range out {
+			out[i] = r.value(h & 0x0f)
+		}
+		return out
+	case thriftStruct:
+		return r.readStruct()
+	default:
+		panic("unexpected thrift type")
+	}
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	out := map[int16]any{}
+	var last int16
+	for {
+		h := r.b[r.p]
+		r.p++
+		if h == 0 {
+			return out
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		out[id] = r.value(h & 0x0f)
+		last = id
+	}
+}
+
+func readParquetFooter(t *testing.T, data []byte) map[int16]any {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte(parquetMagic)) || !bytes.HasSuffix(data, []byte(parquetMagic)) {
+		t.Fatalf("missing PAR1 magic")
+	}
+	n := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{b: data, p: len(data) - 8 - n}
+	md := r.readStruct()
+	if r.p != len(data)-8 {
+		t.Fatalf("footer decoded %d bytes, want %d", r.p-(len(data)-8-n), n)
+	}
+	return md
+}
+
+// readParquetColumn decodes the PLAIN values of column col in every row group.
+func readParquetColumn(t *testing.T, data []byte, md map[int16]any, col int) []any {
+	t.Helper()
+	var out []any
+	for _, rg := range md[4].([]any) {
+		chunk := rg.(map[int16]any)[1].([]any)[col].(map[int16]any)
+		meta := chunk[3].(map[int16]any)
+		r := &thriftReader{b: data, p: int(meta[9].(int64))}
+		page := r.readStruct()
+		n := int(page[5].(map[int16]any)[1].(int64))
+		vals := data[r.p : r.p+int(page[3].(int64))]
+		for i := 0; i < n; i++ {
+			switch meta[1].(int64) {
+			case pqBoolean:
+				out = append(out, vals[i/8]>>(i%8)&1 == 1)
+			case pqInt32:
+				out = append(out, int32(binary.LittleEndian.Uint32(vals[i*4:])))
+			case pqInt64:
+				out = append(out, int64(binary.LittleEndian.Uint64(vals[i*8:])))
+			case pqFloat:
+				out = append(out, math.Float32frombits(binary.LittleEndian.Uint32(vals[i*4:])))
+			case pqDouble:
+				out = append(out, math.Float64frombits(binary.LittleEndian.Uint64(vals[i*8:])))
+			case pqByteArray:
+				l := int(binary.LittleEndian.Uint32(vals))
+				out = append(out, string(vals[4:4+l]))
+				vals = vals[4+l:]
+			}
+		}
+	}
+	return out
+}
+
+func TestParquetWriter(t *testing.T) {
+	fields := testStoreLayout(t).Fields
+	orig := parquetRowGroupSize
+	parquetRowGroupSize = 3
+	defer func() { parquetRowGroupSize = orig }()
+
+	var buf bytes.Buffer
+	pw := newParquetWriter(&buf, fields)
+	for i := 0; i < 7; i++ {
+		if err := pw.Write([]any{uint64(i), float64(i) / 2, strings.Repeat("x", i), i%3 == 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	md := readParquetFooter(t, data)
+	if md[3].(int64) != 7 {
+		t.Errorf("num_rows = %v, want 7", md[3])
+	}
+	if got := len(md[4].([]any)); got != 3 {
+		t.Errorf("row groups = %d, want 3", got)
+	}
+	schema := md[2].([]any)
+	if len(schema) != len(fields)+1 || schema[3].(map[int16]any)[4] != "name" {
+		t.Errorf("schema = %v", schema)
+	}
+	if schema[3].(map[int16]any)[6].(int64) != pqUTF8 {
+		t.Errorf("name converted type = %v, want UTF8", schema[3].(map[int16]any)[6])
+	}
+
+	ids := readParquetColumn(t, data, md, 0)
+	if len(ids) != 7 || ids[6] != int64(6) {
+		t.Errorf("id column = %v", ids)
+	}
+	names := readParquetColumn(t, data, md, 2)
+	if names[4] != "xxxx" {
+		t.Errorf("name column = %v", names)
+	}
+	live := readParquetColumn(t, data, md, 3)
+	if live[0] != true || live[1] != false || live[6] != true {
+		t.Errorf("live column = %v", live)
+	}
+}
+
+func TestParquetWriter_PageBudget(t *testing.T) {
+	fields := testStoreLayout(t).Fields
+	orig := parquetPageBytes
+	parquetPageBytes = 16
+	defer func() { parquetPageBytes = orig }()
+
+	var buf bytes.Buffer
+	pw := newParquetWriter(&buf, fields)
+	for i := 0; i < 5; i++ {
+		if err := pw.Write([]any{uint64(i), 0.5, "abcdefgh", true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each 12-byte name pushes the name column to the budget every other row.
+	data := buf.Bytes()
+	md := readParquetFooter(t, data)
+	if got := len(md[4].([]any)); got != 3 {
+		t.Errorf("row groups = %d, want 3", got)
+	}
+	if names := readParquetColumn(t, data, md, 2); len(names) != 5 || names[4] != "abcdefgh" {
+		t.Errorf("name column = %v", names)
+	}
+}
+
+func TestParquetWriter_PageTooLarge(t *testing.T) {
+	orig := parquetMaxPage
+	parquetMaxPage = 8
+	defer func() { parquetMaxPage = orig }()
+
+	var buf bytes.Buffer
+	pw := newParquetWriter(&buf, testStoreLayout(t).Fields)
+	if err := pw.Write([]any{uint64(1), 1.0, "abcdefgh", true}); err != nil {
+		t.Fatal(err)
+	}
+	err := pw.Close()
+	if err == nil || !strings.Contains(err.Error(), "column name: page of 12 bytes exceeds the 8-byte page limit") {
+		t.Errorf("Close err = %v, want a page limit error", err)
+	}
+	if buf.Len() != len(parquetMagic) {
+		t.Errorf("wrote %d bytes after the page error, want only the magic", buf.Len())
+	}
+}
+
+func TestParquetWriter_AllTypes(t *testing.T) {
+	types := []mmapforge.FieldType{
+		mmapforge.FieldBool, mmapforge.FieldInt8, mmapforge.FieldUint8, mmapforge.FieldInt16,
+		mmapforge.FieldUint16, mmapforge.FieldInt32, mmapforge.FieldUint32, mmapforge.FieldInt64,
+		mmapforge.FieldUint64, mmapforge.FieldFloat32, mmapforge.FieldFloat64, mmapforge.FieldString,
+		mmapforge.FieldBytes,
+	}
+	row := []any{true, int8(-1), uint8(2), int16(-3), uint16(4), int32(-5), uint32(6), int64(-7),
+		uint64(8), float32(9.5), 10.5, "s", []byte("b")}
+	want := []any{true, int32(-1), int32(2), int32(-3), int32(4), int32(-5), int32(6), int64(-7),
+		int64(8), float32(9.5), 10.5, "s", "b"}
+
+	fields := make([]mmapforge.FieldLayout, len(types))
+	for i, ft := range types {
+		fields[i].Name = ft.String()
+		fields[i].Type = ft
+	}
+
+	var buf bytes.Buffer
+	pw := newParquetWriter(&buf, fields)
+	if err := pw.Write(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	md := readParquetFooter(t, buf.Bytes())
+	for i := range types {
+		got := readParquetColumn(t, buf.Bytes(), md, i)
+		if len(got) != 1 || got[0] != want[i] {
+			t.Errorf("column %s = %v, want %v", types[i], got, want[i])
+		}
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite testdata golden files")
+
+// TestParquetWriter_Golden pins the writer's output to
+// testdata/all_types.parquet. The golden file was checked with an
+// independent reader (github.com/xitongsys/parquet-go v1.6.2), which finds
+// 2 rows of REQUIRED columns (INT32 with INT_8 ... UINT_32 annotations,
+// INT64, INT64/UINT_64, FLOAT, DOUBLE, BYTE_ARRAY/UTF8, BYTE_ARRAY) and
+// decodes them to the rows below; it reports the physical value of
+// unsigned columns, so the maximum uint32 and uint64 print as -1:
+//
+//	{"Bool":true,"Int8":-1,"Uint8":2,"Int16":-3,"Uint16":4,"Int32":-5,"Uint32":6,"Int64":-7,"Uint64":8,"Float32":9.5,"Float64":10.5,"String":"s","Bytes":"b"}
+//	{"Bool":false,"Int8":127,"Uint8":255,"Int16":32767,"Uint16":65535,"Int32":2147483647,"Uint32":-1,"Int64":9223372036854775807,"Uint64":-1,"Float32":-1.25,"Float64":1e+300,"String":"héllo","Bytes":"\u0000\u0001"}
+//
+// Rerun with -update only for an intended format change, and check the new
+// file with an independent reader before committing it.
+func TestParquetWriter_Golden(t *testing.T) {
+	types := []mmapforge.FieldType{
+		mmapforge.FieldBool, mmapforge.FieldInt8, mmapforge.FieldUint8, mmapforge.FieldInt16,
+		mmapforge.FieldUint16, mmapforge.FieldInt32, mmapforge.FieldUint32, mmapforge.FieldInt64,
+		mmapforge.FieldUint64, mmapforge.FieldFloat32, mmapforge.FieldFloat64, mmapforge.FieldString,
+		mmapforge.FieldBytes,
+	}
+	rows := [][]any{
+		{true, int8(-1), uint8(2), int16(-3), uint16(4), int32(-5), uint32(6), int64(-7),
+			uint64(8), float32(9.5), 10.5, "s", []byte("b")},
+		{false, int8(math.MaxInt8), uint8(math.MaxUint8), int16(math.MaxInt16), uint16(math.MaxUint16),
+			int32(math.MaxInt32), uint32(math.MaxUint32), int64(math.MaxInt64), uint64(math.MaxUint64),
+			float32(-1.25), 1e300, "héllo", []byte{0, 1}},
+	}
+	fields := make([]mmapforge.FieldLayout, len(types))
+	for i, ft := range types {
+		fields[i].Name = ft.String()
+		fields[i].Type = ft
+	}
+
+	var buf bytes.Buffer
+	pw := newParquetWriter(&buf, fields)
+	for _, row := range rows {
+		if err := pw.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "all_types.parquet")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("parquet output differs from %s (%d bytes, want %d)", golden, buf.Len(), len(want))
+	}
+}
+
+func TestParquetWriter_ManyColumns(t *testing.T) {
+	fields := make([]mmapforge.FieldLayout, 20)
+	for i := range fields {
+		fields[i].Name = strings.Repeat("c", i+1)
+		fields[i].Type = mmapforge.FieldInt64
+	}
+	row := make([]any, len(fields))
+	for i := range row {
+		row[i] = int64(i)
+	}
+
+	var buf bytes.Buffer
+	pw := newParquetWriter(&buf, fields)
+	if err := pw.Write(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	md := readParquetFooter(t, buf.Bytes())
+	if got := readParquetColumn(t, buf.Bytes(), md, 19); got[0] != int64(19) {
+		t.Errorf("column 19 = %v", got)
+	}
+}
+
+func TestThriftWriter_LongFieldDelta(t *testing.T) {
+	var w thriftWriter
+	w.i32(1, 7)
+	w.i64(20, -3)
+	w.stop()
+
+	r := &thriftReader{b: w.Bytes()}
+	got := r.readStruct()
+	if got[1] != int64(7) || got[20] != int64(-3) {
+		t.Errorf("decoded = %v", got)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errWriteFailed }
+
+func TestParquetWriter_WriteError(t *testing.T) {
+	pw := newParquetWriter(failWriter{}, testStoreLayout(t).Fields)
+	if err := pw.Write([]any{uint64(1), 1.0, "a", true}); err == nil {
+		t.Error("expected write error")
+	}
+	if err := pw.Close(); err == nil {
+		t.Error("expected close error")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/CreditWorthy/mmapforge/internal/codegen"
+)
+
+// Mockable function
+var execCommand = exec.Command
+
+// pluginFlags collects repeated -plugin flags. Plugins run in order.
+type pluginFlags []string
+
+func (p *pluginFlags) String() string { return strings.Join(*p, ",") }
+
+func (p *pluginFlags) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+// pluginHooks turns each plugin into a codegen hook. A plugin is either an
+// executable file, run as is, or a Go package (./tools/myext, an import
+// path) built with `go build` into a temporary directory that cleanup
+// removes.
+func pluginHooks(plugins []string) (hooks []codegen.Hook, cleanup func(), err error) {
+	cleanup = func() {}
+	if len(plugins) == 0 {
+		return nil, cleanup, nil
+	}
+	dir, err := os.MkdirTemp("", "mmapforge-plugin-")
+	if err != nil {
+		return nil, cleanup, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	for i, p := range plugins {
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() && fi.Mode()&0111 != 0 {
+			abs, err := filepath.Abs(p)
+			if err != nil {
+				cleanup()
+				return nil, func() {}, err
+			}
+			hooks = append(hooks, codegen.Plugin(abs))
+			continue
+		}
+		name := filepath.Base(p)
+		if strings.HasPrefix(name, ".") {
+			name = "plugin"
+		}
+		sub := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.Mkdir(sub, 0700); err != nil {
+			cleanup()
+			return nil, func() {}, err
+		}
+		exe := filepath.Join(sub, name)
+		out, err := execCommand("go", "build", "-o", exe, p).CombinedOutput()
+		if err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("build plugin %s: %w\n%s", p, err, strings.TrimSpace(string(out)))
+		}
+		hooks = append(hooks, codegen.Plugin(exe))
+	}
+	return hooks, cleanup, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// echoPlugin is a self-contained plugin reporting the types it was given.
+const echoPlugin = `package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+func main() {
+	var req struct {
+		Package string
+		Types   []struct{ StoreName string ` + "`json:\"store_name\"`" + ` }
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		os.Exit(1)
+	}
+	src := "package " + req.Package + "\n\nconst PluginStore = \"" + req.Types[0].StoreName + "\"\n"
+	json.NewEncoder(os.Stdout).Encode(map[string]any{
+		"files": []map[string]string{{"name": "stores_gen.go", "content": src}},
+	})
+}
+`
+
+func TestPluginFlags(t *testing.T) {
+	var p pluginFlags
+	if err := p.Set("./a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Set("./b"); err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "./a,./b" {
+		t.Errorf("String() = %q", p.String())
+	}
+}
+
+func TestPluginHooks_None(t *testing.T) {
+	hooks, cleanup, err := pluginHooks(nil)
+	if err != nil || hooks != nil {
+		t.Errorf("hooks = %v, err = %v", hooks, err)
+	}
+	cleanup()
+}
+
+func TestMain_Plugin(t *testing.T) {
+	root := writeTempModule(t, map[string]string{
+		"ticks/tick.go":        tickSchema,
+		"tools/echo/main.go":   echoPlugin,
+		"tools/broken/main.go": "package main\n\nfunc main() { undefined() }\n",
+		"ticks/mmapforge.yaml": "plugins: [../tools/echo]\ntypes:\n  Tick:\n    store: TickTable\n",
+	})
+
+	code, out := stubMain(t, []string{"mmapforge", "-plugin", "./tools/echo", "./ticks"})
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %q", code, out)
+	}
+	src, err := os.ReadFile(filepath.Join(root, "ticks", "stores_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), `const PluginStore = "TickStore"`) {
+		t.Errorf("stores_gen.go = %s", src)
+	}
+
+	// Plugins listed in a config file are relative to it.
+	code, out = stubMain(t, []string{"mmapforge", "-config", "ticks/mmapforge.yaml", "-input", "ticks/tick.go"})
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %q", code, out)
+	}
+	src, err = os.ReadFile(filepath.Join(root, "ticks", "stores_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), `const PluginStore = "TickTable"`) {
+		t.Errorf("stores_gen.go = %s", src)
+	}
+
+	code, out = stubMain(t, []string{"mmapforge", "-plugin", "./tools/broken", "./ticks"})
+	if code != 1 || !strings.Contains(out, "build plugin ./tools/broken") || !strings.Contains(out, "undefined") {
+		t.Errorf("broken plugin: exit code = %d, stderr = %q", code, out)
+	}
+	code, out = stubMain(t, []string{"mmapforge", "-plugin", "./tools/broken", "-input", "ticks/tick.go"})
+	if code != 1 || !strings.Contains(out, "build plugin") {
+		t.Errorf("broken plugin with -input: exit code = %d, stderr = %q", code, out)
+	}
+}
+
+func TestMain_PluginExecutable(t *testing.T) {
+	root := writeTempModule(t, map[string]string{
+		"ticks/tick.go": tickSchema,
+		"plug.sh":       "#!/bin/sh\ncat >/dev/null\necho '{\"files\":[{\"name\":\"notes.txt\",\"content\":\"hi\"}]}'\n",
+		"fail.sh":       "#!/bin/sh\necho '{\"error\":\"nope\"}'\n",
+	})
+	for _, name := range []string{"plug.sh", "fail.sh"} {
+		if err := os.Chmod(filepath.Join(root, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, out := stubMain(t, []string{"mmapforge", "-plugin", "plug.sh", "./ticks"})
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %q", code, out)
+	}
+	if b, err := os.ReadFile(filepath.Join(root, "ticks", "notes.txt")); err != nil || string(b) != "hi" {
+		t.Errorf("notes.txt = %q, %v", b, err)
+	}
+
+	code, out = stubMain(t, []string{"mmapforge", "-plugin", "plug.sh", "-plugin", "fail.sh", "./ticks"})
+	if code != 1 || !strings.Contains(out, "plugin fail.sh: nope") {
+		t.Errorf("exit code = %d, stderr = %q", code, out)
+	}
+}
+
+func TestFileConfig_ResolvePlugins(t *testing.T) {
+	fc := &fileConfig{
+		path:    filepath.Join("conf", "mmapforge.yaml"),
+		Plugins: []string{"./tools/x", "../y", "example.com/z", "/abs/w"},
+	}
+	fc.resolvePlugins()
+	want := []string{"./conf/tools/x", "./y", "example.com/z", "/abs/w"}
+	if !reflect.DeepEqual(fc.Plugins, want) {
+		t.Errorf("Plugins = %q, want %q", fc.Plugins, want)
+	}
+
+	fc = &fileConfig{path: "/etc/mmapforge.yaml", Plugins: []string{"./x"}}
+	fc.resolvePlugins()
+	if fc.Plugins[0] != "/etc/x" {
+		t.Errorf("Plugins = %q", fc.Plugins)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CreditWorthy/mmapforge"
+	"github.com/CreditWorthy/mmapforge/internal/codegen"
+)
+
+// loadLayout parses the Go source file schemaPath and returns the record
+// layout of the annotated struct typeName. typeName may be empty when the
+// file declares exactly one schema.
+func loadLayout(schemaPath, typeName string) (*mmapforge.RecordLayout, error) {
+	schema, err := findSchema(schemaPath, typeName)
+	if err != nil {
+		return nil, err
+	}
+	return mmapforge.ComputeLayout(schema.Fields)
+}
+
+// findSchema parses schemaPath and returns the mmapforge:schema struct
+// named typeName, or the only one when typeName is empty.
+func findSchema(schemaPath, typeName string) (*codegen.StructSchema, error) {
+	if schemaPath == "" {
+		return nil, fmt.Errorf("-schema is required")
+	}
+	schemas, err := codegen.ParseFile(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *codegen.StructSchema
+	names := make([]string, 0, len(schemas))
+	for i := range schemas {
+		names = append(names, schemas[i].Name)
+		if schemas[i].Name == typeName || (typeName == "" && len(schemas) == 1) {
+			found = &schemas[i]
+		}
+	}
+	if found == nil {
+		if typeName == "" {
+			return nil, fmt.Errorf("%s declares %d schemas [%s]; use -type to pick one", schemaPath, len(schemas), strings.Join(names, ", "))
+		}
+		return nil, fmt.Errorf("no mmapforge:schema type %q in %s", typeName, schemaPath)
+	}
+	return found, nil
+}
+
+// selectFields resolves a comma-separated list of mmap field names against
+// layout. An empty list selects every field in layout order.
+func selectFields(layout *mmapforge.RecordLayout, list string) ([]mmapforge.FieldLayout, error) {
+	if list == "" {
+		return layout.Fields, nil
+	}
+	var out []mmapforge.FieldLayout
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		f, ok := fieldByName(layout, name)
+		if !ok {
+			return nil, fmt.Errorf("field %q: %w", name, mmapforge.ErrUnknownField)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func fieldByName(layout *mmapforge.RecordLayout, name string) (mmapforge.FieldLayout, bool) {
+	for _, f := range layout.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return mmapforge.FieldLayout{}, false
+}
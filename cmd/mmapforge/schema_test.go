@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadLayout(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestSchema(t, dir)
+
+	layout, err := loadLayout(schema, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testStoreLayout(t)
+	if layout.RecordSize != want.RecordSize || len(layout.Fields) != len(want.Fields) {
+		t.Fatalf("layout = %+v, want %+v", layout, want)
+	}
+	for i := range want.Fields {
+		if layout.Fields[i].Name != want.Fields[i].Name || layout.Fields[i].Offset != want.Fields[i].Offset {
+			t.Errorf("field %d = %+v, want %+v", i, layout.Fields[i], want.Fields[i])
+		}
+	}
+
+	if _, err := loadLayout(schema, "Rec"); err != nil {
+		t.Errorf("loadLayout by name: %v", err)
+	}
+}
+
+func TestLoadLayout_Errors(t *testing.T) {
+	dir := t.TempDir()
+	two := filepath.Join(dir, "two.go")
+	src := testSchemaSrc + strings.Replace(testSchemaSrc[len("package data\n"):], "Rec", "Other", 1)
+	if err := os.WriteFile(two, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, path, typ, want string
+	}{
+		{"no path", "", "", "-schema is required"},
+		{"missing", filepath.Join(dir, "nope.go"), "", "no such file"},
+		{"ambiguous", two, "", "declares 2 schemas [Rec, Other]"},
+		{"unknown type", two, "Nope", `no mmapforge:schema type "Nope"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadLayout(tt.path, tt.typ)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	layout := testStoreLayout(t)
+
+	all, err := selectFields(layout, "")
+	if err != nil || len(all) != len(layout.Fields) {
+		t.Errorf("selectFields(\"\") = %v, %v", all, err)
+	}
+
+	got, err := selectFields(layout, "live, id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != "live" || got[1].Name != "id" {
+		t.Errorf("selectFields = %+v", got)
+	}
+
+	if _, err := selectFields(layout, "id,nope"); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
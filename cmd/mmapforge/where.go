@@ -0,0 +1,171 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// predicate is one parsed -where expression: <field><op><value>.
+type predicate struct {
+	field   mmapforge.FieldLayout
+	op      string
+	operand any
+}
+
+// predicateOps lists the supported operators, two-character ones first so
+// "<=" is not read as "<".
+var predicateOps = []string{"==", "!=", "<=", ">=", "<", ">", "="}
+
+// whereFlags collects repeated -where flags. All predicates must match.
+type whereFlags []string
+
+func (w *whereFlags) String() string { return strings.Join(*w, " AND ") }
+
+func (w *whereFlags) Set(v string) error {
+	*w = append(*w, v)
+	return nil
+}
+
+// parsePredicates parses every -where expression against layout.
+func parsePredicates(layout *mmapforge.RecordLayout, exprs []string) ([]*predicate, error) {
+	preds := make([]*predicate, 0, len(exprs))
+	for _, e := range exprs {
+		p, err := parsePredicate(layout, e)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, p)
+	}
+	return preds, nil
+}
+
+// parsePredicate parses "price>=10", "symbol==AAPL" or "stale=false".
+// The operand is parsed according to the field type up front.
+func parsePredicate(layout *mmapforge.RecordLayout, expr string) (*predicate, error) {
+	pos := strings.IndexAny(expr, "=!<>")
+	if pos <= 0 {
+		return nil, fmt.Errorf("-where %q: expected <field><op><value>", expr)
+	}
+	name := strings.TrimSpace(expr[:pos])
+	rest := expr[pos:]
+
+	var op string
+	for _, o := range predicateOps {
+		if strings.HasPrefix(rest, o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("-where %q: unknown operator", expr)
+	}
+	if op == "=" {
+		op = "=="
+		rest = "=" + rest
+	}
+	raw := strings.TrimSpace(rest[len(op):])
+
+	f, ok := fieldByName(layout, name)
+	if !ok {
+		return nil, fmt.Errorf("-where %q: field %q: %w", expr, name, mmapforge.ErrUnknownField)
+	}
+
+	operand, err := parseOperand(f.Type, raw)
+	if err != nil {
+		return nil, fmt.Errorf("-where %q: %w", expr, err)
+	}
+	if (f.Type == mmapforge.FieldBool || f.Type == mmapforge.FieldBytes) && op != "==" && op != "!=" {
+		return nil, fmt.Errorf("-where %q: %s fields only support == and !=", expr, f.Type)
+	}
+	return &predicate{field: f, op: op, operand: operand}, nil
+}
+
+// parseOperand parses raw into the comparison type for ft: int64, uint64,
+// float64, bool or string.
+func parseOperand(ft mmapforge.FieldType, raw string) (any, error) {
+	switch ft {
+	case mmapforge.FieldBool:
+		return strconv.ParseBool(raw)
+	case mmapforge.FieldInt8, mmapforge.FieldInt16, mmapforge.FieldInt32, mmapforge.FieldInt64:
+		return strconv.ParseInt(raw, 10, 64)
+	case mmapforge.FieldUint8, mmapforge.FieldUint16, mmapforge.FieldUint32, mmapforge.FieldUint64:
+		return strconv.ParseUint(raw, 10, 64)
+	case mmapforge.FieldFloat32, mmapforge.FieldFloat64:
+		return strconv.ParseFloat(raw, 64)
+	default:
+		return raw, nil
+	}
+}
+
+// match reports whether the record value v satisfies p.
+func (p *predicate) match(v any) bool {
+	var c int
+	switch x := normalize(v).(type) {
+	case bool:
+		c = 1
+		if x == p.operand.(bool) {
+			c = 0
+		}
+	case int64:
+		c = cmp.Compare(x, p.operand.(int64))
+	case uint64:
+		c = cmp.Compare(x, p.operand.(uint64))
+	case float64:
+		c = cmp.Compare(x, p.operand.(float64))
+	case string:
+		c = strings.Compare(x, p.operand.(string))
+	}
+
+	switch p.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// matchAll reports whether the record map m satisfies every predicate.
+func matchAll(preds []*predicate, m map[string]any) bool {
+	for _, p := range preds {
+		if !p.match(m[p.field.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize widens a field value to the comparison type used by parseOperand.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint8:
+		return uint64(x)
+	case uint16:
+		return uint64(x)
+	case uint32:
+		return uint64(x)
+	case float32:
+		return float64(x)
+	case []byte:
+		return string(x)
+	default:
+		return v
+	}
+}
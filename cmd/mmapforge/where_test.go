@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+func whereTestLayout(t *testing.T) *mmapforge.RecordLayout {
+	t.Helper()
+	layout, err := mmapforge.ComputeLayout([]mmapforge.FieldDef{
+		{Name: "i8", Type: mmapforge.FieldInt8},
+		{Name: "u16", Type: mmapforge.FieldUint16},
+		{Name: "f32", Type: mmapforge.FieldFloat32},
+		{Name: "s", Type: mmapforge.FieldString, MaxSize: 8},
+		{Name: "b", Type: mmapforge.FieldBytes, MaxSize: 8},
+		{Name: "ok", Type: mmapforge.FieldBool},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return layout
+}
+
+func TestPredicate_Match(t *testing.T) {
+	layout := whereTestLayout(t)
+	rec := map[string]any{
+		"i8":  int8(-3),
+		"u16": uint16(500),
+		"f32": float32(1.5),
+		"s":   "abc",
+		"b":   []byte("xy"),
+		"ok":  true,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"i8==-3", true},
+		{"i8 < -3", false},
+		{"i8<=-3", true},
+		{"i8>-4", true},
+		{"u16>=500", true},
+		{"u16!=500", false},
+		{"u16=500", true},
+		{"f32<2", true},
+		{"f32>1.5", false},
+		{"s==abc", true},
+		{"s>abb", true},
+		{"s<abc", false},
+		{"b==xy", true},
+		{"b!=xy", false},
+		{"ok==true", true},
+		{"ok!=true", false},
+		{"ok=false", false},
+	}
+	for _, tt := range tests {
+		p, err := parsePredicate(layout, tt.expr)
+		if err != nil {
+			t.Errorf("parsePredicate(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := p.match(rec[p.field.Name]); got != tt.want {
+			t.Errorf("%q match = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParsePredicate_Errors(t *testing.T) {
+	layout := whereTestLayout(t)
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"i8", "expected <field><op><value>"},
+		{"==1", "expected <field><op><value>"},
+		{"i8!1", "unknown operator"},
+		{"nope==1", "unknown field"},
+		{"i8==x", "invalid syntax"},
+		{"u16==-1", "invalid syntax"},
+		{"f32==x", "invalid syntax"},
+		{"ok==maybe", "invalid syntax"},
+		{"ok<true", "only support == and !="},
+		{"b>x", "only support == and !="},
+	}
+	for _, tt := range tests {
+		_, err := parsePredicate(layout, tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parsePredicate(%q) err = %v, want containing %q", tt.expr, err, tt.want)
+		}
+	}
+
+	if _, err := parsePredicate(layout, "nope==1"); !errors.Is(err, mmapforge.ErrUnknownField) {
+		t.Errorf("err = %v, want ErrUnknownField", err)
+	}
+}
+
+func TestParsePredicates(t *testing.T) {
+	layout := whereTestLayout(t)
+	var w whereFlags
+	for _, e := range []string{"i8>0", "ok==true"} {
+		if err := w.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.String() != "i8>0 AND ok==true" {
+		t.Errorf("String() = %q", w.String())
+	}
+
+	preds, err := parsePredicates(layout, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !matchAll(preds, map[string]any{"i8": int8(1), "ok": true}) {
+		t.Error("expected match")
+	}
+	if matchAll(preds, map[string]any{"i8": int8(1), "ok": false}) {
+		t.Error("expected no match")
+	}
+
+	if _, err := parsePredicates(layout, []string{"i8>0", "bad"}); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -6,11 +6,27 @@ var Magic = [4]byte{'M', 'M', 'F', 'G'}
 // MagicString is the string form of Magic for display purposes.
 const MagicString = "MMFG"
 
-// Version is the current binary format version.
-const Version uint32 = 1
+// Version is the current binary format version, written by CreateStore.
+// Version 2 grew the header from 64 to 128 bytes to make room for Flags;
+// version 1 files are still opened, read and written as version 1.
+const Version uint32 = 2
 
-// HeaderSize is the fixed size of the file header in bytes.
-const HeaderSize = 64
+// Header flags (Header.Flags, format version 2). Readers reject files
+// carrying flags they do not know.
+const (
+	// FlagMultiWriter marks a store whose writers use the WithMultiWriter
+	// seqlock protocol. Every writer that opens it follows that protocol.
+	FlagMultiWriter uint32 = 1 << iota
+
+	knownFlags = FlagMultiWriter
+)
+
+// HeaderSize is the size of the file header in bytes for the current
+// format version.
+const HeaderSize = 128
+
+// HeaderSizeV1 is the size of the file header of format version 1 files.
+const HeaderSizeV1 = 64
 
 // StoreReserveVA is the default virtual address reservation for Store files (1 GB).
 const StoreReserveVA = 1 << 30
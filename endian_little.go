@@ -0,0 +1,8 @@
+//go:build !(armbe || arm64be || m68k || mips || mips64 || mips64p32 || ppc || ppc64 || s390 || s390x || shbe || sparc || sparc64)
+
+package mmapforge
+
+// littleEndian reports whether the host stores integers little-endian, the
+// byte order of the file format. Code that reads or writes fields in host
+// order (TypedStore, the atomic field operations) refuses to run otherwise.
+const littleEndian = true
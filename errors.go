@@ -1,6 +1,10 @@
 package mmapforge
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // Sentinel errors returned by Store and Region operations.
 var (
@@ -15,4 +19,57 @@ var (
 	ErrInvalidBool    = errors.New("mmapforge: invalid bool value")
 	ErrTypeMismatch   = errors.New("mmapforge: field type changed during migration")
 	ErrLocked         = errors.New("mmapforge: store is locked by another writer")
+	ErrUnknownField   = errors.New("mmapforge: unknown field")
+	ErrInvalidValue   = errors.New("mmapforge: value not convertible to field type")
+	ErrWriterStalled  = errors.New("mmapforge: writer stalled holding a record")
+	ErrRecordBusy     = errors.New("mmapforge: record is being written")
+	ErrMisaligned     = errors.New("mmapforge: field not aligned for atomic access")
 )
+
+// FieldError describes a failure reading or writing one field of one record.
+// It wraps one of the sentinel errors above (ErrStringTooLong, ErrBytesTooLong,
+// ErrOutOfBounds, ErrCorrupted, ErrInvalidBool, ErrInvalidValue, ErrMisaligned) so callers can use both
+// errors.Is on the sentinel and errors.As on the *FieldError.
+type FieldError struct {
+	// Field is the mmap field name, empty if the offset matches no field in the layout.
+	Field string
+
+	// GoName is the Go struct field name, empty if unknown.
+	GoName string
+
+	// Index is the record index being accessed.
+	Index int
+
+	// Offset is the byte offset of the field within the record.
+	Offset uint32
+
+	// Len is the offending length for string and bytes fields.
+	Len int
+
+	// Max is the declared max size for string and bytes fields.
+	Max uint32
+
+	// Err is the underlying cause.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "mmapforge: record %d: ", e.Index)
+	if e.Field != "" {
+		fmt.Fprintf(&b, "field %q at offset %d: ", e.Field, e.Offset)
+	} else {
+		fmt.Fprintf(&b, "field at offset %d: ", e.Offset)
+	}
+	b.WriteString(e.Err.Error())
+	if e.Max != 0 {
+		fmt.Fprintf(&b, " (len=%d max=%d)", e.Len, e.Max)
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying cause.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
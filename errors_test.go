@@ -0,0 +1,43 @@
+package mmapforge
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFieldError_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *FieldError
+		want string
+	}{
+		{
+			name: "named with limits",
+			err:  &FieldError{Field: "name", GoName: "Name", Index: 3, Offset: 8, Len: 40, Max: 32, Err: ErrStringTooLong},
+			want: `mmapforge: record 3: field "name" at offset 8: mmapforge: string exceeds max size (len=40 max=32)`,
+		},
+		{
+			name: "unnamed without limits",
+			err:  &FieldError{Index: 0, Offset: 16, Err: ErrOutOfBounds},
+			want: `mmapforge: record 0: field at offset 16: mmapforge: index out of bounds`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldError_Unwrap(t *testing.T) {
+	var err error = &FieldError{Field: "id", Err: ErrCorrupted}
+	if !errors.Is(err, ErrCorrupted) {
+		t.Error("errors.Is(err, ErrCorrupted) = false, want true")
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "id" {
+		t.Errorf("errors.As = %v, want *FieldError for id", fe)
+	}
+}
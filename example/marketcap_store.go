@@ -3,6 +3,8 @@
 package example
 
 import (
+	"context"
+
 	mmapforge "github.com/CreditWorthy/mmapforge"
 )
 
@@ -34,6 +36,17 @@ func NewMarketCapStore(path string, opts ...mmapforge.StoreOption) (*MarketCapSt
 	return &MarketCapStore{Store: s}, nil
 }
 
+// NewMarketCapStoreInMemory creates a MarketCap store in anonymous memory
+// instead of a file, for tests and ephemeral caches.
+func NewMarketCapStoreInMemory(opts ...mmapforge.StoreOption) (*MarketCapStore, error) {
+	layout := MarketCapLayout()
+	s, err := mmapforge.CreateStoreInMemory(layout, 1, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketCapStore{Store: s}, nil
+}
+
 // OpenMarketCapStore opens an existing MarketCap store at the given path.
 func OpenMarketCapStore(path string, opts ...mmapforge.StoreOption) (*MarketCapStore, error) {
 	layout := MarketCapLayout()
@@ -46,17 +59,26 @@ func OpenMarketCapStore(path string, opts ...mmapforge.StoreOption) (*MarketCapS
 
 // GetID returns the ID field for the record at idx.
 func (s *MarketCapStore) GetID(idx int) (uint64, error) {
-	for {
+	return s.GetIDCtx(context.Background(), idx)
+}
+
+// GetIDCtx is GetID, giving up with ctx's error if ctx is done
+// while a writer holds the record.
+func (s *MarketCapStore) GetIDCtx(ctx context.Context, idx int) (uint64, error) {
+	for attempt := 0; ; attempt++ {
 		seq := s.SeqReadBegin(idx)
-		if seq&1 != 0 {
-			continue
+		if seq&1 == 0 {
+			v, err := s.ReadUint64(idx, 8)
+			if err != nil {
+				return v, err
+			}
+			if s.SeqReadValid(idx, seq) {
+				return v, nil
+			}
 		}
-		v, err := s.ReadUint64(idx, 8)
-		if err != nil {
-			return v, err
-		}
-		if s.SeqReadValid(idx, seq) {
-			return v, nil
+		if err := s.SeqReadRetry(ctx, idx, attempt); err != nil {
+			var zero uint64
+			return zero, err
 		}
 	}
 }
@@ -64,24 +86,56 @@ func (s *MarketCapStore) GetID(idx int) (uint64, error) {
 // SetID sets the ID field for the record at idx.
 func (s *MarketCapStore) SetID(idx int, val uint64) error {
 	s.SeqBeginWrite(idx)
-	err := s.WriteUint64(idx, 8, val)
-	s.SeqEndWrite(idx)
-	return err
+	defer s.SeqEndWrite(idx)
+	return s.WriteUint64(idx, 8, val)
+}
+
+// LoadID atomically loads the ID field for the record at idx,
+// without retrying around writers.
+func (s *MarketCapStore) LoadID(idx int) (uint64, error) {
+	return s.AtomicLoadUint64(idx, 8)
+}
+
+// StoreID atomically stores the ID field for the record at idx.
+// See mmapforge.Store.AtomicStoreUint64 for how it interacts with the seqlock.
+func (s *MarketCapStore) StoreID(idx int, val uint64) error {
+	return s.AtomicStoreUint64(idx, 8, val)
+}
+
+// AddID atomically adds delta to the ID field for the record at
+// idx and returns the new value.
+func (s *MarketCapStore) AddID(idx int, delta uint64) (uint64, error) {
+	return s.AtomicAddUint64(idx, 8, delta)
+}
+
+// CompareAndSwapID atomically sets the ID field for the record at
+// idx to new if it equals old, and reports whether it did.
+func (s *MarketCapStore) CompareAndSwapID(idx int, old, new uint64) (bool, error) {
+	return s.AtomicCompareAndSwapUint64(idx, 8, old, new)
 }
 
 // GetPrice returns the Price field for the record at idx.
 func (s *MarketCapStore) GetPrice(idx int) (float64, error) {
-	for {
+	return s.GetPriceCtx(context.Background(), idx)
+}
+
+// GetPriceCtx is GetPrice, giving up with ctx's error if ctx is done
+// while a writer holds the record.
+func (s *MarketCapStore) GetPriceCtx(ctx context.Context, idx int) (float64, error) {
+	for attempt := 0; ; attempt++ {
 		seq := s.SeqReadBegin(idx)
-		if seq&1 != 0 {
-			continue
-		}
-		v, err := s.ReadFloat64(idx, 16)
-		if err != nil {
-			return v, err
+		if seq&1 == 0 {
+			v, err := s.ReadFloat64(idx, 16)
+			if err != nil {
+				return v, err
+			}
+			if s.SeqReadValid(idx, seq) {
+				return v, nil
+			}
 		}
-		if s.SeqReadValid(idx, seq) {
-			return v, nil
+		if err := s.SeqReadRetry(ctx, idx, attempt); err != nil {
+			var zero float64
+			return zero, err
 		}
 	}
 }
@@ -89,24 +143,32 @@ func (s *MarketCapStore) GetPrice(idx int) (float64, error) {
 // SetPrice sets the Price field for the record at idx.
 func (s *MarketCapStore) SetPrice(idx int, val float64) error {
 	s.SeqBeginWrite(idx)
-	err := s.WriteFloat64(idx, 16, val)
-	s.SeqEndWrite(idx)
-	return err
+	defer s.SeqEndWrite(idx)
+	return s.WriteFloat64(idx, 16, val)
 }
 
 // GetVolume returns the Volume field for the record at idx.
 func (s *MarketCapStore) GetVolume(idx int) (float64, error) {
-	for {
+	return s.GetVolumeCtx(context.Background(), idx)
+}
+
+// GetVolumeCtx is GetVolume, giving up with ctx's error if ctx is done
+// while a writer holds the record.
+func (s *MarketCapStore) GetVolumeCtx(ctx context.Context, idx int) (float64, error) {
+	for attempt := 0; ; attempt++ {
 		seq := s.SeqReadBegin(idx)
-		if seq&1 != 0 {
-			continue
+		if seq&1 == 0 {
+			v, err := s.ReadFloat64(idx, 24)
+			if err != nil {
+				return v, err
+			}
+			if s.SeqReadValid(idx, seq) {
+				return v, nil
+			}
 		}
-		v, err := s.ReadFloat64(idx, 24)
-		if err != nil {
-			return v, err
-		}
-		if s.SeqReadValid(idx, seq) {
-			return v, nil
+		if err := s.SeqReadRetry(ctx, idx, attempt); err != nil {
+			var zero float64
+			return zero, err
 		}
 	}
 }
@@ -114,24 +176,32 @@ func (s *MarketCapStore) GetVolume(idx int) (float64, error) {
 // SetVolume sets the Volume field for the record at idx.
 func (s *MarketCapStore) SetVolume(idx int, val float64) error {
 	s.SeqBeginWrite(idx)
-	err := s.WriteFloat64(idx, 24, val)
-	s.SeqEndWrite(idx)
-	return err
+	defer s.SeqEndWrite(idx)
+	return s.WriteFloat64(idx, 24, val)
 }
 
 // GetMarketCap returns the MarketCap field for the record at idx.
 func (s *MarketCapStore) GetMarketCap(idx int) (float64, error) {
-	for {
+	return s.GetMarketCapCtx(context.Background(), idx)
+}
+
+// GetMarketCapCtx is GetMarketCap, giving up with ctx's error if ctx is done
+// while a writer holds the record.
+func (s *MarketCapStore) GetMarketCapCtx(ctx context.Context, idx int) (float64, error) {
+	for attempt := 0; ; attempt++ {
 		seq := s.SeqReadBegin(idx)
-		if seq&1 != 0 {
-			continue
-		}
-		v, err := s.ReadFloat64(idx, 32)
-		if err != nil {
-			return v, err
+		if seq&1 == 0 {
+			v, err := s.ReadFloat64(idx, 32)
+			if err != nil {
+				return v, err
+			}
+			if s.SeqReadValid(idx, seq) {
+				return v, nil
+			}
 		}
-		if s.SeqReadValid(idx, seq) {
-			return v, nil
+		if err := s.SeqReadRetry(ctx, idx, attempt); err != nil {
+			var zero float64
+			return zero, err
 		}
 	}
 }
@@ -139,24 +209,32 @@ func (s *MarketCapStore) GetMarketCap(idx int) (float64, error) {
 // SetMarketCap sets the MarketCap field for the record at idx.
 func (s *MarketCapStore) SetMarketCap(idx int, val float64) error {
 	s.SeqBeginWrite(idx)
-	err := s.WriteFloat64(idx, 32, val)
-	s.SeqEndWrite(idx)
-	return err
+	defer s.SeqEndWrite(idx)
+	return s.WriteFloat64(idx, 32, val)
 }
 
 // GetStale returns the Stale field for the record at idx.
 func (s *MarketCapStore) GetStale(idx int) (bool, error) {
-	for {
+	return s.GetStaleCtx(context.Background(), idx)
+}
+
+// GetStaleCtx is GetStale, giving up with ctx's error if ctx is done
+// while a writer holds the record.
+func (s *MarketCapStore) GetStaleCtx(ctx context.Context, idx int) (bool, error) {
+	for attempt := 0; ; attempt++ {
 		seq := s.SeqReadBegin(idx)
-		if seq&1 != 0 {
-			continue
+		if seq&1 == 0 {
+			v, err := s.ReadBool(idx, 40)
+			if err != nil {
+				return v, err
+			}
+			if s.SeqReadValid(idx, seq) {
+				return v, nil
+			}
 		}
-		v, err := s.ReadBool(idx, 40)
-		if err != nil {
-			return v, err
-		}
-		if s.SeqReadValid(idx, seq) {
-			return v, nil
+		if err := s.SeqReadRetry(ctx, idx, attempt); err != nil {
+			var zero bool
+			return zero, err
 		}
 	}
 }
@@ -164,9 +242,103 @@ func (s *MarketCapStore) GetStale(idx int) (bool, error) {
 // SetStale sets the Stale field for the record at idx.
 func (s *MarketCapStore) SetStale(idx int, val bool) error {
 	s.SeqBeginWrite(idx)
-	err := s.WriteBool(idx, 40, val)
-	s.SeqEndWrite(idx)
-	return err
+	defer s.SeqEndWrite(idx)
+	return s.WriteBool(idx, 40, val)
+}
+
+// MarketCapView is a zero-copy view of a MarketCap record, passed to View. It
+// is only valid inside the View callback, and so are the strings and byte
+// slices it returns.
+type MarketCapView struct {
+	rec mmapforge.RecordView
+}
+
+// ID returns the ID field.
+func (v MarketCapView) ID() uint64 {
+	return v.rec.Uint64(8)
+}
+
+// Price returns the Price field.
+func (v MarketCapView) Price() float64 {
+	return v.rec.Float64(16)
+}
+
+// Volume returns the Volume field.
+func (v MarketCapView) Volume() float64 {
+	return v.rec.Float64(24)
+}
+
+// MarketCap returns the MarketCap field.
+func (v MarketCapView) MarketCap() float64 {
+	return v.rec.Float64(32)
+}
+
+// Stale returns the Stale field.
+func (v MarketCapView) Stale() bool {
+	return v.rec.Bool(40)
+}
+
+// View calls fn with a view of the record at idx and returns fn's error.
+// fn reads any number of fields without copying or allocating, and the
+// seqlock is checked once around the whole callback. fn runs again if a
+// writer got in the way, so it must not act on what it reads until View
+// returns; see mmapforge.Store.ViewRecord.
+func (s *MarketCapStore) View(idx int, fn func(v MarketCapView) error) error {
+	return s.ViewCtx(context.Background(), idx, fn)
+}
+
+// ViewCtx is View, giving up with ctx's error if ctx is done while a writer
+// holds the record.
+func (s *MarketCapStore) ViewCtx(ctx context.Context, idx int, fn func(v MarketCapView) error) error {
+	return s.ViewRecord(ctx, idx, func(rec mmapforge.RecordView) error {
+		return fn(MarketCapView{rec: rec})
+	})
+}
+
+// MarketCapUpdater writes MarketCap fields for Update. It is only valid inside the
+// Update callback it was passed to.
+type MarketCapUpdater struct {
+	s   *MarketCapStore
+	idx int
+}
+
+// SetID sets the ID field.
+func (u *MarketCapUpdater) SetID(val uint64) error {
+	s, idx := u.s, u.idx
+	return s.WriteUint64(idx, 8, val)
+}
+
+// SetPrice sets the Price field.
+func (u *MarketCapUpdater) SetPrice(val float64) error {
+	s, idx := u.s, u.idx
+	return s.WriteFloat64(idx, 16, val)
+}
+
+// SetVolume sets the Volume field.
+func (u *MarketCapUpdater) SetVolume(val float64) error {
+	s, idx := u.s, u.idx
+	return s.WriteFloat64(idx, 24, val)
+}
+
+// SetMarketCap sets the MarketCap field.
+func (u *MarketCapUpdater) SetMarketCap(val float64) error {
+	s, idx := u.s, u.idx
+	return s.WriteFloat64(idx, 32, val)
+}
+
+// SetStale sets the Stale field.
+func (u *MarketCapUpdater) SetStale(val bool) error {
+	s, idx := u.s, u.idx
+	return s.WriteBool(idx, 40, val)
+}
+
+// Update calls fn with an updater for the record at idx, inside a single
+// seqlock write: readers see all of the fields fn sets or none of them. It
+// returns fn's error; fields set before fn returned stay written.
+func (s *MarketCapStore) Update(idx int, fn func(u *MarketCapUpdater) error) error {
+	s.SeqBeginWrite(idx)
+	defer s.SeqEndWrite(idx)
+	return fn(&MarketCapUpdater{s: s, idx: idx})
 }
 
 // MarketCapRecord holds all fields of a MarketCap record.
@@ -180,38 +352,185 @@ type MarketCapRecord struct {
 
 // Get reads all fields atomically for the record at idx.
 func (s *MarketCapStore) Get(idx int) (*MarketCapRecord, error) {
-	for {
+	return s.GetCtx(context.Background(), idx)
+}
+
+// GetCtx is Get, giving up with ctx's error if ctx is done while a writer
+// holds the record.
+func (s *MarketCapStore) GetCtx(ctx context.Context, idx int) (*MarketCapRecord, error) {
+	rec := &MarketCapRecord{}
+	if err := s.GetIntoCtx(ctx, idx, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// GetInto is Get, reading into rec instead of allocating a record, so a
+// hot loop can reuse one. Like the getters, string and []byte fields
+// point into the mapping. On error rec's contents are unspecified.
+func (s *MarketCapStore) GetInto(idx int, rec *MarketCapRecord) error {
+	return s.GetIntoCtx(context.Background(), idx, rec)
+}
+
+// GetIntoCtx is GetInto, giving up with ctx's error if ctx is done while a
+// writer holds the record.
+func (s *MarketCapStore) GetIntoCtx(ctx context.Context, idx int, rec *MarketCapRecord) error {
+	for attempt := 0; ; attempt++ {
 		seq := s.SeqReadBegin(idx)
-		if seq&1 != 0 {
-			continue
-		}
-		rec := &MarketCapRecord{}
-		var err error
-		rec.ID, err = s.ReadUint64(idx, 8)
-		if err != nil {
-			return nil, err
+		if seq&1 == 0 {
+			var err error
+			rec.ID, err = s.ReadUint64(idx, 8)
+			if err != nil {
+				return err
+			}
+			rec.Price, err = s.ReadFloat64(idx, 16)
+			if err != nil {
+				return err
+			}
+			rec.Volume, err = s.ReadFloat64(idx, 24)
+			if err != nil {
+				return err
+			}
+			rec.MarketCap, err = s.ReadFloat64(idx, 32)
+			if err != nil {
+				return err
+			}
+			rec.Stale, err = s.ReadBool(idx, 40)
+			if err != nil {
+				return err
+			}
+			if s.SeqReadValid(idx, seq) {
+				return nil
+			}
 		}
-		rec.Price, _ = s.ReadFloat64(idx, 16)
-		rec.Volume, _ = s.ReadFloat64(idx, 24)
-		rec.MarketCap, _ = s.ReadFloat64(idx, 32)
-		rec.Stale, _ = s.ReadBool(idx, 40)
-		if s.SeqReadValid(idx, seq) {
-			return rec, nil
+		if err := s.SeqReadRetry(ctx, idx, attempt); err != nil {
+			return err
 		}
 	}
 }
 
-// Set writes all fields atomically for the record at idx.
+// Set writes all fields atomically for the record at idx. Every field is
+// validated first, so an error leaves the record untouched.
 func (s *MarketCapStore) Set(idx int, rec *MarketCapRecord) error {
+	if err := s.CheckWrite(idx, 0, 0, 0); err != nil {
+		return err
+	}
+	s.SeqBeginWrite(idx)
+	defer s.SeqEndWrite(idx)
+	if err := s.WriteUint64(idx, 8, rec.ID); err != nil {
+		return err
+	}
+	if err := s.WriteFloat64(idx, 16, rec.Price); err != nil {
+		return err
+	}
+	if err := s.WriteFloat64(idx, 24, rec.Volume); err != nil {
+		return err
+	}
+	if err := s.WriteFloat64(idx, 32, rec.MarketCap); err != nil {
+		return err
+	}
+	if err := s.WriteBool(idx, 40, rec.Stale); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarketCapFieldMask selects MarketCap fields for SetFields.
+type MarketCapFieldMask uint64
+
+// MarketCapFieldMask bits, one per field.
+const (
+	MarketCapFieldID        MarketCapFieldMask = 1 << 0
+	MarketCapFieldPrice     MarketCapFieldMask = 1 << 1
+	MarketCapFieldVolume    MarketCapFieldMask = 1 << 2
+	MarketCapFieldMarketCap MarketCapFieldMask = 1 << 3
+	MarketCapFieldStale     MarketCapFieldMask = 1 << 4
+
+	// MarketCapAllFields selects every field.
+	MarketCapAllFields MarketCapFieldMask = 1<<5 - 1
+)
+
+// SetFields writes the fields of rec selected by mask to the record at idx,
+// inside a single seqlock write, and leaves the others untouched. The
+// selected fields are validated first, so an error leaves the record
+// untouched.
+func (s *MarketCapStore) SetFields(idx int, rec *MarketCapRecord, mask MarketCapFieldMask) error {
+	if err := s.CheckWrite(idx, 0, 0, 0); err != nil {
+		return err
+	}
 	s.SeqBeginWrite(idx)
+	defer s.SeqEndWrite(idx)
+	if mask&MarketCapFieldID != 0 {
+		if err := s.WriteUint64(idx, 8, rec.ID); err != nil {
+			return err
+		}
+	}
+	if mask&MarketCapFieldPrice != 0 {
+		if err := s.WriteFloat64(idx, 16, rec.Price); err != nil {
+			return err
+		}
+	}
+	if mask&MarketCapFieldVolume != 0 {
+		if err := s.WriteFloat64(idx, 24, rec.Volume); err != nil {
+			return err
+		}
+	}
+	if mask&MarketCapFieldMarketCap != 0 {
+		if err := s.WriteFloat64(idx, 32, rec.MarketCap); err != nil {
+			return err
+		}
+	}
+	if mask&MarketCapFieldStale != 0 {
+		if err := s.WriteBool(idx, 40, rec.Stale); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TryUpdate reads the record at idx, passes it to fn and writes it back, all
+// inside one seqlock write, so concurrent writers on a WithMultiWriter store
+// cannot interleave. If another writer holds the record it returns an error
+// wrapping mmapforge.ErrRecordBusy without calling fn. The record fn gets is
+// a copy, so fn may keep it. If fn panics, or leaves a string or []byte
+// field longer than its max size, the record is left unchanged.
+func (s *MarketCapStore) TryUpdate(idx int, fn func(*MarketCapRecord)) error {
+	if err := s.SeqTryBeginWrite(idx); err != nil {
+		return err
+	}
+	defer s.SeqEndWrite(idx)
+	rec := &MarketCapRecord{}
+	var err error
+	if rec.ID, err = s.ReadUint64(idx, 8); err != nil {
+		return err
+	}
+	if rec.Price, err = s.ReadFloat64(idx, 16); err != nil {
+		return err
+	}
+	if rec.Volume, err = s.ReadFloat64(idx, 24); err != nil {
+		return err
+	}
+	if rec.MarketCap, err = s.ReadFloat64(idx, 32); err != nil {
+		return err
+	}
+	if rec.Stale, err = s.ReadBool(idx, 40); err != nil {
+		return err
+	}
+	fn(rec)
 	if err := s.WriteUint64(idx, 8, rec.ID); err != nil {
-		s.SeqEndWrite(idx)
 		return err
 	}
-	_ = s.WriteFloat64(idx, 16, rec.Price)
-	_ = s.WriteFloat64(idx, 24, rec.Volume)
-	_ = s.WriteFloat64(idx, 32, rec.MarketCap)
-	_ = s.WriteBool(idx, 40, rec.Stale)
-	s.SeqEndWrite(idx)
+	if err := s.WriteFloat64(idx, 16, rec.Price); err != nil {
+		return err
+	}
+	if err := s.WriteFloat64(idx, 24, rec.Volume); err != nil {
+		return err
+	}
+	if err := s.WriteFloat64(idx, 32, rec.MarketCap); err != nil {
+		return err
+	}
+	if err := s.WriteBool(idx, 40, rec.Stale); err != nil {
+		return err
+	}
 	return nil
 }
@@ -171,6 +171,36 @@ func BenchmarkMarketCap_BulkGet(b *testing.B) {
 	}
 }
 
+func BenchmarkMarketCap_BulkGetInto(b *testing.B) {
+	s := benchMarketCapStore(b)
+	defer s.Close()
+	var rec MarketCapRecord
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if readErr := s.GetInto(i%benchRecords, &rec); readErr != nil {
+			b.Fatal(readErr)
+		}
+	}
+}
+
+func BenchmarkMarketCap_View(b *testing.B) {
+	s := benchMarketCapStore(b)
+	defer s.Close()
+	var sum float64
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if readErr := s.View(i%benchRecords, func(v MarketCapView) error {
+			sum += v.Price() * v.Volume()
+			return nil
+		}); readErr != nil {
+			b.Fatal(readErr)
+		}
+	}
+	_ = sum
+}
+
 func BenchmarkMarketCap_BulkSet(b *testing.B) {
 	s := benchMarketCapStore(b)
 	defer s.Close()
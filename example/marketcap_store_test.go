@@ -5,9 +5,13 @@
 package example
 
 import (
+	"context"
+	"errors"
 	"path/filepath"
 	"sync"
 	"testing"
+
+	mmapforge "github.com/CreditWorthy/mmapforge"
 )
 
 func TestMarketCapStore_CreateClose(t *testing.T) {
@@ -36,6 +40,21 @@ func TestMarketCapStore_NewError(t *testing.T) {
 	}
 }
 
+func TestMarketCapStore_InMemory(t *testing.T) {
+	s, err := NewMarketCapStoreInMemory()
+	if err != nil {
+		t.Fatalf("NewMarketCapStoreInMemory: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Append(); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", s.Len())
+	}
+}
+
 func TestMarketCapStore_OpenError(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "nonexistent.mmf")
 	if _, err := OpenMarketCapStore(path); err == nil {
@@ -151,6 +170,276 @@ func TestMarketCapStore_GetOutOfBounds(t *testing.T) {
 	}
 }
 
+func TestMarketCapStore_Atomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmf")
+	s, err := NewMarketCapStore(path)
+	if err != nil {
+		t.Fatalf("NewMarketCapStore: %v", err)
+	}
+	defer s.Close()
+
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	{
+		seq := s.SeqReadBegin(idx)
+		if err := s.StoreID(idx, uint64(18000000000000)); err != nil {
+			t.Fatalf("StoreID: %v", err)
+		}
+		if s.SeqReadValid(idx, seq) {
+			t.Error("StoreID did not bump the seqlock")
+		}
+		if got, err := s.LoadID(idx); err != nil || got != uint64(18000000000000) {
+			t.Errorf("LoadID = %v, %v; want %v", got, err, uint64(18000000000000))
+		}
+		if got, err := s.AddID(idx, 1); err != nil || got != uint64(18000000000000)+1 {
+			t.Errorf("AddID = %v, %v; want %v", got, err, uint64(18000000000000)+1)
+		}
+		if ok, err := s.CompareAndSwapID(idx, uint64(18000000000000), 0); err != nil || ok {
+			t.Errorf("CompareAndSwapID with a stale old value = %v, %v", ok, err)
+		}
+		if ok, err := s.CompareAndSwapID(idx, uint64(18000000000000)+1, uint64(18000000000000)); err != nil || !ok {
+			t.Errorf("CompareAndSwapID = %v, %v", ok, err)
+		}
+		if got, err := s.GetID(idx); err != nil || got != uint64(18000000000000) {
+			t.Errorf("GetID = %v, %v; want %v", got, err, uint64(18000000000000))
+		}
+		if _, err := s.AddID(idx+1, 1); err == nil {
+			t.Error("AddID out of bounds: expected error")
+		}
+	}
+
+}
+
+func TestMarketCapStore_GetStalledWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmf")
+	s, err := NewMarketCapStore(path, mmapforge.WithReadBackoff(mmapforge.ReadBackoff{Spins: 1, MaxRetries: 100}))
+	if err != nil {
+		t.Fatalf("NewMarketCapStore: %v", err)
+	}
+	defer s.Close()
+
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	s.SeqBeginWrite(idx)
+	defer s.SeqEndWrite(idx)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.GetID(idx); !errors.Is(err, mmapforge.ErrWriterStalled) {
+		t.Errorf("GetID with a stuck writer: err = %v, want ErrWriterStalled", err)
+	}
+	if _, err := s.GetIDCtx(ctx, idx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetIDCtx with a canceled context: err = %v, want context.Canceled", err)
+	}
+
+	if _, err := s.GetPrice(idx); !errors.Is(err, mmapforge.ErrWriterStalled) {
+		t.Errorf("GetPrice with a stuck writer: err = %v, want ErrWriterStalled", err)
+	}
+	if _, err := s.GetPriceCtx(ctx, idx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetPriceCtx with a canceled context: err = %v, want context.Canceled", err)
+	}
+
+	if _, err := s.GetVolume(idx); !errors.Is(err, mmapforge.ErrWriterStalled) {
+		t.Errorf("GetVolume with a stuck writer: err = %v, want ErrWriterStalled", err)
+	}
+	if _, err := s.GetVolumeCtx(ctx, idx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetVolumeCtx with a canceled context: err = %v, want context.Canceled", err)
+	}
+
+	if _, err := s.GetMarketCap(idx); !errors.Is(err, mmapforge.ErrWriterStalled) {
+		t.Errorf("GetMarketCap with a stuck writer: err = %v, want ErrWriterStalled", err)
+	}
+	if _, err := s.GetMarketCapCtx(ctx, idx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetMarketCapCtx with a canceled context: err = %v, want context.Canceled", err)
+	}
+
+	if _, err := s.GetStale(idx); !errors.Is(err, mmapforge.ErrWriterStalled) {
+		t.Errorf("GetStale with a stuck writer: err = %v, want ErrWriterStalled", err)
+	}
+	if _, err := s.GetStaleCtx(ctx, idx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetStaleCtx with a canceled context: err = %v, want context.Canceled", err)
+	}
+
+	if _, err := s.GetCtx(ctx, idx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetCtx with a canceled context: err = %v, want context.Canceled", err)
+	}
+}
+
+func TestMarketCapStore_Update(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmf")
+	s, err := NewMarketCapStore(path)
+	if err != nil {
+		t.Fatalf("NewMarketCapStore: %v", err)
+	}
+	defer s.Close()
+
+	setAll := func(u *MarketCapUpdater) error {
+		if err := u.SetID(uint64(18000000000000)); err != nil {
+			return err
+		}
+		if err := u.SetPrice(float64(2.5)); err != nil {
+			return err
+		}
+		if err := u.SetVolume(float64(2.5)); err != nil {
+			return err
+		}
+		if err := u.SetMarketCap(float64(2.5)); err != nil {
+			return err
+		}
+		if err := u.SetStale(true); err != nil {
+			return err
+		}
+		return nil
+	}
+	if err := s.Update(0, setAll); err == nil {
+		t.Error("Update(0) on empty store: expected error")
+	}
+
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	seq := s.SeqReadBegin(idx)
+	if err := s.Update(idx, setAll); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got := s.SeqReadBegin(idx); got != seq+2 {
+		t.Errorf("seq after Update = %d, want %d (one write)", got, seq+2)
+	}
+
+	{
+		got, err := s.GetID(idx)
+		if err != nil {
+			t.Fatalf("GetID: %v", err)
+		}
+		if got != uint64(18000000000000) {
+			t.Errorf("GetID after Update = %v, want %v", got, uint64(18000000000000))
+		}
+	}
+
+	{
+		got, err := s.GetPrice(idx)
+		if err != nil {
+			t.Fatalf("GetPrice: %v", err)
+		}
+		if got != float64(2.5) {
+			t.Errorf("GetPrice after Update = %v, want %v", got, float64(2.5))
+		}
+	}
+
+	{
+		got, err := s.GetVolume(idx)
+		if err != nil {
+			t.Fatalf("GetVolume: %v", err)
+		}
+		if got != float64(2.5) {
+			t.Errorf("GetVolume after Update = %v, want %v", got, float64(2.5))
+		}
+	}
+
+	{
+		got, err := s.GetMarketCap(idx)
+		if err != nil {
+			t.Fatalf("GetMarketCap: %v", err)
+		}
+		if got != float64(2.5) {
+			t.Errorf("GetMarketCap after Update = %v, want %v", got, float64(2.5))
+		}
+	}
+
+	{
+		got, err := s.GetStale(idx)
+		if err != nil {
+			t.Fatalf("GetStale: %v", err)
+		}
+		if got != true {
+			t.Errorf("GetStale after Update = %v, want %v", got, true)
+		}
+	}
+
+	errStop := errors.New("stop")
+	if err := s.Update(idx, func(*MarketCapUpdater) error { return errStop }); !errors.Is(err, errStop) {
+		t.Errorf("Update: err = %v, want the callback's error", err)
+	}
+	if got := s.SeqReadBegin(idx); got&1 != 0 {
+		t.Errorf("seq after a failed Update = %d, want even", got)
+	}
+}
+
+func TestMarketCapStore_View(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmf")
+	s, err := NewMarketCapStore(path)
+	if err != nil {
+		t.Fatalf("NewMarketCapStore: %v", err)
+	}
+	defer s.Close()
+
+	noop := func(MarketCapView) error { return nil }
+	if err := s.View(0, noop); err == nil {
+		t.Error("View(0) on empty store: expected error")
+	}
+
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := s.SetID(idx, uint64(18000000000000)); err != nil {
+		t.Fatalf("SetID: %v", err)
+	}
+	if err := s.SetPrice(idx, float64(2.5)); err != nil {
+		t.Fatalf("SetPrice: %v", err)
+	}
+	if err := s.SetVolume(idx, float64(2.5)); err != nil {
+		t.Fatalf("SetVolume: %v", err)
+	}
+	if err := s.SetMarketCap(idx, float64(2.5)); err != nil {
+		t.Fatalf("SetMarketCap: %v", err)
+	}
+	if err := s.SetStale(idx, true); err != nil {
+		t.Fatalf("SetStale: %v", err)
+	}
+	err = s.View(idx, func(v MarketCapView) error {
+		if got := v.ID(); got != uint64(18000000000000) {
+			t.Errorf("view ID = %v, want %v", got, uint64(18000000000000))
+		}
+		if got := v.Price(); got != float64(2.5) {
+			t.Errorf("view Price = %v, want %v", got, float64(2.5))
+		}
+		if got := v.Volume(); got != float64(2.5) {
+			t.Errorf("view Volume = %v, want %v", got, float64(2.5))
+		}
+		if got := v.MarketCap(); got != float64(2.5) {
+			t.Errorf("view MarketCap = %v, want %v", got, float64(2.5))
+		}
+		if got := v.Stale(); got != true {
+			t.Errorf("view Stale = %v, want %v", got, true)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: %v", err)
+	}
+
+	errStop := errors.New("stop")
+	if err := s.View(idx, func(MarketCapView) error { return errStop }); !errors.Is(err, errStop) {
+		t.Errorf("View: err = %v, want the callback's error", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.SeqBeginWrite(idx)
+	err = s.ViewCtx(ctx, idx, noop)
+	s.SeqEndWrite(idx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ViewCtx with a canceled context: err = %v, want context.Canceled", err)
+	}
+}
+
 func TestMarketCapStore_SetOutOfBounds(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "test.mmf")
 	s, err := NewMarketCapStore(path)
@@ -180,6 +469,56 @@ func TestMarketCapStore_SetOutOfBounds(t *testing.T) {
 	}
 }
 
+func TestMarketCapStore_UseAfterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmf")
+	s, err := NewMarketCapStore(path)
+	if err != nil {
+		t.Fatalf("NewMarketCapStore: %v", err)
+	}
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if err := s.SetID(idx, uint64(18000000000000)); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("SetID after Close: err = %v, want ErrClosed", err)
+	}
+	if _, err := s.GetID(idx); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("GetID after Close: err = %v, want ErrClosed", err)
+	}
+
+	if err := s.SetPrice(idx, float64(2.5)); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("SetPrice after Close: err = %v, want ErrClosed", err)
+	}
+	if _, err := s.GetPrice(idx); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("GetPrice after Close: err = %v, want ErrClosed", err)
+	}
+
+	if err := s.SetVolume(idx, float64(2.5)); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("SetVolume after Close: err = %v, want ErrClosed", err)
+	}
+	if _, err := s.GetVolume(idx); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("GetVolume after Close: err = %v, want ErrClosed", err)
+	}
+
+	if err := s.SetMarketCap(idx, float64(2.5)); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("SetMarketCap after Close: err = %v, want ErrClosed", err)
+	}
+	if _, err := s.GetMarketCap(idx); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("GetMarketCap after Close: err = %v, want ErrClosed", err)
+	}
+
+	if err := s.SetStale(idx, true); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("SetStale after Close: err = %v, want ErrClosed", err)
+	}
+	if _, err := s.GetStale(idx); !errors.Is(err, mmapforge.ErrClosed) {
+		t.Errorf("GetStale after Close: err = %v, want ErrClosed", err)
+	}
+}
+
 func TestMarketCapStore_BulkGetSet(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "test.mmf")
 	s, err := NewMarketCapStore(path)
@@ -256,6 +595,230 @@ func TestMarketCapStore_BulkSetOutOfBounds(t *testing.T) {
 	}
 }
 
+func TestMarketCapStore_GetInto(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmf")
+	s, err := NewMarketCapStore(path)
+	if err != nil {
+		t.Fatalf("NewMarketCapStore: %v", err)
+	}
+	defer s.Close()
+
+	var got MarketCapRecord
+	if err := s.GetInto(0, &got); err == nil {
+		t.Error("GetInto(0) on empty store: expected error")
+	}
+
+	want := &MarketCapRecord{
+		ID:        uint64(18000000000000),
+		Price:     float64(2.5),
+		Volume:    float64(2.5),
+		MarketCap: float64(2.5),
+		Stale:     true,
+	}
+	for range 2 {
+		idx, err := s.Append()
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		if err := s.Set(idx, want); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if err := s.GetInto(idx, &got); err != nil {
+			t.Fatalf("GetInto(%d): %v", idx, err)
+		}
+
+		if got.ID != uint64(18000000000000) {
+			t.Errorf("GetInto ID = %v, want %v", got.ID, uint64(18000000000000))
+		}
+		if got.Price != float64(2.5) {
+			t.Errorf("GetInto Price = %v, want %v", got.Price, float64(2.5))
+		}
+		if got.Volume != float64(2.5) {
+			t.Errorf("GetInto Volume = %v, want %v", got.Volume, float64(2.5))
+		}
+		if got.MarketCap != float64(2.5) {
+			t.Errorf("GetInto MarketCap = %v, want %v", got.MarketCap, float64(2.5))
+		}
+		if got.Stale != true {
+			t.Errorf("GetInto Stale = %v, want %v", got.Stale, true)
+		}
+	}
+}
+
+func TestMarketCapStore_SetFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmf")
+	s, err := NewMarketCapStore(path)
+	if err != nil {
+		t.Fatalf("NewMarketCapStore: %v", err)
+	}
+	defer s.Close()
+
+	want := &MarketCapRecord{
+		ID:        uint64(18000000000000),
+		Price:     float64(2.5),
+		Volume:    float64(2.5),
+		MarketCap: float64(2.5),
+		Stale:     true,
+	}
+	if err := s.SetFields(0, want, MarketCapAllFields); err == nil {
+		t.Error("SetFields(0) on empty store: expected error")
+	}
+
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := s.SetFields(idx, want, MarketCapAllFields); err != nil {
+		t.Fatalf("SetFields: %v", err)
+	}
+	if err := s.SetFields(idx, &MarketCapRecord{}, 0); err != nil {
+		t.Fatalf("SetFields with an empty mask: %v", err)
+	}
+	got, err := s.Get(idx)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if got.ID != uint64(18000000000000) {
+		t.Errorf("ID = %v, want %v", got.ID, uint64(18000000000000))
+	}
+
+	if got.Price != float64(2.5) {
+		t.Errorf("Price = %v, want %v", got.Price, float64(2.5))
+	}
+
+	if got.Volume != float64(2.5) {
+		t.Errorf("Volume = %v, want %v", got.Volume, float64(2.5))
+	}
+
+	if got.MarketCap != float64(2.5) {
+		t.Errorf("MarketCap = %v, want %v", got.MarketCap, float64(2.5))
+	}
+
+	if got.Stale != true {
+		t.Errorf("Stale = %v, want %v", got.Stale, true)
+	}
+
+	if err := s.SetFields(idx, &MarketCapRecord{}, MarketCapFieldID); err != nil {
+		t.Fatalf("SetFields(MarketCapFieldID): %v", err)
+	}
+	{
+		got, err := s.GetID(idx)
+		var zero uint64
+		if err != nil || got != zero {
+			t.Errorf("GetID after clearing it = %v, %v; want the zero value", got, err)
+		}
+	}
+	if err := s.SetFields(idx, &MarketCapRecord{}, MarketCapFieldPrice); err != nil {
+		t.Fatalf("SetFields(MarketCapFieldPrice): %v", err)
+	}
+	{
+		got, err := s.GetPrice(idx)
+		var zero float64
+		if err != nil || got != zero {
+			t.Errorf("GetPrice after clearing it = %v, %v; want the zero value", got, err)
+		}
+	}
+	if err := s.SetFields(idx, &MarketCapRecord{}, MarketCapFieldVolume); err != nil {
+		t.Fatalf("SetFields(MarketCapFieldVolume): %v", err)
+	}
+	{
+		got, err := s.GetVolume(idx)
+		var zero float64
+		if err != nil || got != zero {
+			t.Errorf("GetVolume after clearing it = %v, %v; want the zero value", got, err)
+		}
+	}
+	if err := s.SetFields(idx, &MarketCapRecord{}, MarketCapFieldMarketCap); err != nil {
+		t.Fatalf("SetFields(MarketCapFieldMarketCap): %v", err)
+	}
+	{
+		got, err := s.GetMarketCap(idx)
+		var zero float64
+		if err != nil || got != zero {
+			t.Errorf("GetMarketCap after clearing it = %v, %v; want the zero value", got, err)
+		}
+	}
+	if err := s.SetFields(idx, &MarketCapRecord{}, MarketCapFieldStale); err != nil {
+		t.Fatalf("SetFields(MarketCapFieldStale): %v", err)
+	}
+	{
+		got, err := s.GetStale(idx)
+		var zero bool
+		if err != nil || got != zero {
+			t.Errorf("GetStale after clearing it = %v, %v; want the zero value", got, err)
+		}
+	}
+}
+
+func TestMarketCapStore_TryUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmf")
+	s, err := NewMarketCapStore(path, mmapforge.WithMultiWriter())
+	if err != nil {
+		t.Fatalf("NewMarketCapStore: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.TryUpdate(0, func(*MarketCapRecord) {}); err == nil {
+		t.Error("TryUpdate(0) on empty store: expected error")
+	}
+
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	err = s.TryUpdate(idx, func(rec *MarketCapRecord) {
+		rec.ID = uint64(18000000000000)
+		rec.Price = float64(2.5)
+		rec.Volume = float64(2.5)
+		rec.MarketCap = float64(2.5)
+		rec.Stale = true
+	})
+	if err != nil {
+		t.Fatalf("TryUpdate: %v", err)
+	}
+	got, err := s.Get(idx)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if got.ID != uint64(18000000000000) {
+		t.Errorf("after TryUpdate ID = %v, want %v", got.ID, uint64(18000000000000))
+	}
+
+	if got.Price != float64(2.5) {
+		t.Errorf("after TryUpdate Price = %v, want %v", got.Price, float64(2.5))
+	}
+
+	if got.Volume != float64(2.5) {
+		t.Errorf("after TryUpdate Volume = %v, want %v", got.Volume, float64(2.5))
+	}
+
+	if got.MarketCap != float64(2.5) {
+		t.Errorf("after TryUpdate MarketCap = %v, want %v", got.MarketCap, float64(2.5))
+	}
+
+	if got.Stale != true {
+		t.Errorf("after TryUpdate Stale = %v, want %v", got.Stale, true)
+	}
+
+	s.SeqBeginWrite(idx)
+	called := false
+	err = s.TryUpdate(idx, func(*MarketCapRecord) { called = true })
+	s.SeqEndWrite(idx)
+	if !errors.Is(err, mmapforge.ErrRecordBusy) || called {
+		t.Errorf("TryUpdate on a held record: err = %v, called = %v; want ErrRecordBusy", err, called)
+	}
+
+	func() {
+		defer func() { _ = recover() }()
+		_ = s.TryUpdate(idx, func(*MarketCapRecord) { panic("boom") })
+	}()
+	if err := s.TryUpdate(idx, func(*MarketCapRecord) {}); err != nil {
+		t.Errorf("TryUpdate after a panicking fn: %v", err)
+	}
+}
+
 func TestMarketCapStore_MultipleRecords(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "test.mmf")
 	s, err := NewMarketCapStore(path)
@@ -0,0 +1,54 @@
+// Code generated by mmapforge. DO NOT EDIT.
+
+package example
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	mmapforge "github.com/CreditWorthy/mmapforge"
+)
+
+// Stores holds an open store for every schema type of the package.
+type Stores struct {
+	MarketCap *MarketCapStore
+}
+
+// OpenAll opens the store of every schema type in dir, creating dir and any
+// store file that does not exist yet. If a store fails to open, the stores
+// opened so far are closed. Files are named after the lowercased type, such
+// as marketcap.mmf.
+func OpenAll(dir string, opts ...mmapforge.StoreOption) (*Stores, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Stores{}
+	var err error
+
+	s.MarketCap, err = OpenMarketCapStore(filepath.Join(dir, "marketcap.mmf"), opts...)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.MarketCap, err = NewMarketCapStore(filepath.Join(dir, "marketcap.mmf"), opts...)
+	}
+	if err != nil {
+		return nil, errors.Join(err, s.CloseAll())
+	}
+	return s, nil
+}
+
+// CloseAll closes every open store and returns all close errors joined.
+func (s *Stores) CloseAll() error {
+	var errs []error
+	if s.MarketCap != nil {
+		errs = append(errs, s.MarketCap.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Schemas describes every schema type of the package.
+func Schemas() []mmapforge.SchemaInfo {
+	return []mmapforge.SchemaInfo{
+		mmapforge.NewSchemaInfo("MarketCap", 1, MarketCapLayout()),
+	}
+}
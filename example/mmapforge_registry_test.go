@@ -0,0 +1,62 @@
+//go:build unix
+
+// Code generated by mmapforge. DO NOT EDIT.
+
+package example
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry_OpenAll(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "stores")
+	s, err := OpenAll(dir)
+	if err != nil {
+		t.Fatalf("OpenAll: %v", err)
+	}
+	if _, err := s.MarketCap.Append(); err != nil {
+		t.Fatalf("MarketCap.Append: %v", err)
+	}
+	if err := s.CloseAll(); err != nil {
+		t.Fatalf("CloseAll: %v", err)
+	}
+
+	s, err = OpenAll(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.CloseAll()
+	if s.MarketCap.Len() != 1 {
+		t.Errorf("MarketCap.Len = %d, want 1", s.MarketCap.Len())
+	}
+}
+
+func TestRegistry_OpenAllError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "marketcap.mmf"), []byte("bad"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenAll(dir); err == nil {
+		t.Fatal("expected error opening a corrupt store")
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenAll(file); err == nil {
+		t.Fatal("expected error when dir is a file")
+	}
+}
+
+func TestRegistry_Schemas(t *testing.T) {
+	schemas := Schemas()
+	if len(schemas) != 1 {
+		t.Fatalf("len(Schemas()) = %d, want 1", len(schemas))
+	}
+	if s := schemas[0]; s.Name != "MarketCap" || s.Version != 1 || s.RecordSize != 48 {
+		t.Errorf("Schemas()[0] = %+v", s)
+	}
+}
@@ -0,0 +1,72 @@
+//go:build unix
+
+package example
+
+import (
+	"path/filepath"
+	"testing"
+
+	mmapforge "github.com/CreditWorthy/mmapforge"
+)
+
+func TestTypedStore_WritesGeneratedStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "marketcap.mmf")
+	want := MarketCap{ID: 7, Price: 189.5, Volume: -3, MarketCap: 1e12, Stale: true}
+
+	ts, err := mmapforge.Open[MarketCap](path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := ts.Append(&want); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := ts.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenMarketCapStore(path)
+	if err != nil {
+		t.Fatalf("OpenMarketCapStore: %v", err)
+	}
+	defer s.Close()
+	got, err := s.Get(0)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if *got != MarketCapRecord(want) {
+		t.Errorf("Get = %+v, want %+v", *got, want)
+	}
+}
+
+func TestGeneratedStore_WritesTypedStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "marketcap.mmf")
+	want := MarketCapRecord{ID: 7, Price: 189.5, Volume: -3, MarketCap: 1e12, Stale: true}
+
+	s, err := NewMarketCapStore(path)
+	if err != nil {
+		t.Fatalf("NewMarketCapStore: %v", err)
+	}
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := s.Set(idx, &want); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := mmapforge.Open[MarketCap](path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer ts.Close()
+	got, err := ts.Get(0)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got != MarketCap(want) {
+		t.Errorf("Get = %+v, want %+v", got, want)
+	}
+}
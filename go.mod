@@ -1,3 +1,14 @@
 module github.com/CreditWorthy/mmapforge
 
 go 1.24
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/tools v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/mod v0.27.0 // indirect
+	golang.org/x/sync v0.16.0 // indirect
+)
@@ -6,7 +6,12 @@ import (
 	"fmt"
 )
 
-// Header is the 64-byte metadata block at the start of every mmapforge file.
+// Header is the metadata block at the start of every mmapforge file: 64
+// bytes in format version 1, 128 in version 2.
+//
+// Both versions share the first 64 bytes. Version 2 adds Flags at bytes
+// 64-68; bytes 68-128 are reserved for per-file state that is updated in
+// place (see Store.Writer) and are never touched by EncodeHeader.
 type Header struct {
 	Magic         [4]byte
 	FormatVersion uint32
@@ -15,12 +20,26 @@ type Header struct {
 	RecordSize    uint32
 	RecordCount   uint64
 	Capacity      uint64
+	Flags         uint32
 }
 
-// EncodeHeader writes h into the first 64 bytes of dst.
+// Size returns the header size in bytes for h's format version, which is
+// also where the first record starts.
+func (h *Header) Size() int {
+	if h.FormatVersion == 1 {
+		return HeaderSizeV1
+	}
+	return HeaderSize
+}
+
+// EncodeHeader writes h into the first h.Size() bytes of dst.
 func EncodeHeader(dst []byte, h *Header) error {
-	if len(dst) < HeaderSize {
-		return fmt.Errorf("mmapforge: header encode: buffer too small (%d < %d)", len(dst), HeaderSize)
+	size := h.Size()
+	if len(dst) < size {
+		return fmt.Errorf("mmapforge: header encode: buffer too small (%d < %d)", len(dst), size)
+	}
+	if h.FormatVersion == 1 && h.Flags != 0 {
+		return fmt.Errorf("mmapforge: header encode: format version 1 has no flags (got %#x)", h.Flags)
 	}
 	copy(dst[0:4], Magic[:])
 	binary.LittleEndian.PutUint32(dst[4:8], h.FormatVersion)
@@ -29,26 +48,40 @@ func EncodeHeader(dst []byte, h *Header) error {
 	binary.LittleEndian.PutUint32(dst[44:48], h.RecordSize)
 	binary.LittleEndian.PutUint64(dst[48:56], h.RecordCount)
 	binary.LittleEndian.PutUint64(dst[56:64], h.Capacity)
+	if size > HeaderSizeV1 {
+		binary.LittleEndian.PutUint32(dst[64:68], h.Flags)
+	}
 	return nil
 }
 
-// DecodeHeader reads the first 64 bytes of src into a Header.
+// DecodeHeader reads a Header from the start of src. src must hold the
+// whole header for the file's format version.
 func DecodeHeader(src []byte) (*Header, error) {
-	if len(src) < HeaderSize {
-		return nil, fmt.Errorf("mmapforge: header decode: buffer too small (%d < %d)", len(src), HeaderSize)
+	if len(src) < HeaderSizeV1 {
+		return nil, fmt.Errorf("mmapforge: header decode: buffer too small (%d < %d)", len(src), HeaderSizeV1)
 	}
 	h := &Header{}
 	if !bytes.Equal(src[0:4], Magic[:]) {
 		return nil, fmt.Errorf("mmapforge: header decode: %w (got %q)", ErrBadMagic, src[0:4])
 	}
+	copy(h.Magic[:], src[0:4])
 	h.FormatVersion = binary.LittleEndian.Uint32(src[4:8])
-	if h.FormatVersion != Version {
+	if h.FormatVersion != 1 && h.FormatVersion != Version {
 		return nil, fmt.Errorf("mmapforge: header decode: unsupported format version %d", h.FormatVersion)
 	}
+	if size := h.Size(); len(src) < size {
+		return nil, fmt.Errorf("mmapforge: header decode: buffer too small for format version %d (%d < %d)", h.FormatVersion, len(src), size)
+	}
 	copy(h.SchemaHash[:], src[8:40])
 	h.SchemaVersion = binary.LittleEndian.Uint32(src[40:44])
 	h.RecordSize = binary.LittleEndian.Uint32(src[44:48])
 	h.RecordCount = binary.LittleEndian.Uint64(src[48:56])
 	h.Capacity = binary.LittleEndian.Uint64(src[56:64])
+	if h.FormatVersion >= 2 {
+		h.Flags = binary.LittleEndian.Uint32(src[64:68])
+		if unknown := h.Flags &^ knownFlags; unknown != 0 {
+			return nil, fmt.Errorf("mmapforge: header decode: unsupported flags %#x", unknown)
+		}
+	}
 	return h, nil
 }
@@ -1,7 +1,9 @@
 package mmapforge
 
 import (
+	"encoding/binary"
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -76,6 +78,9 @@ func TestDecodeHeader_RoundTrip(t *testing.T) {
 		t.Fatalf("decode: %v", err)
 	}
 
+	if got.Magic != Magic {
+		t.Errorf("Magic = %q, want %q", got.Magic, Magic)
+	}
 	if got.FormatVersion != h.FormatVersion {
 		t.Errorf("FormatVersion = %d, want %d", got.FormatVersion, h.FormatVersion)
 	}
@@ -96,6 +101,79 @@ func TestDecodeHeader_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestDecodeHeader_Flags(t *testing.T) {
+	h := validHeader()
+	h.Flags = FlagMultiWriter
+	buf := make([]byte, HeaderSize)
+	if err := EncodeHeader(buf, h); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if v := binary.LittleEndian.Uint32(buf[4:8]); v != Version {
+		t.Errorf("version word = %#x with a flag set, want %d", v, Version)
+	}
+	got, err := DecodeHeader(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.FormatVersion != Version || got.Flags != FlagMultiWriter {
+		t.Errorf("version, flags = %d, %#x; want %d, %#x", got.FormatVersion, got.Flags, Version, FlagMultiWriter)
+	}
+
+	binary.LittleEndian.PutUint32(buf[64:68], 0x8000)
+	if _, err := DecodeHeader(buf); err == nil || !strings.Contains(err.Error(), "unsupported flags 0x8000") {
+		t.Errorf("unknown flags: err = %v", err)
+	}
+}
+
+func TestHeader_ReservedBytesUntouched(t *testing.T) {
+	buf := make([]byte, HeaderSize)
+	for i := 68; i < HeaderSize; i++ {
+		buf[i] = 0xEE
+	}
+	if err := EncodeHeader(buf, validHeader()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 68; i < HeaderSize; i++ {
+		if buf[i] != 0xEE {
+			t.Fatalf("byte %d = %#x, EncodeHeader must leave bytes 68-128 alone", i, buf[i])
+		}
+	}
+}
+
+func TestHeader_V1(t *testing.T) {
+	h := validHeader()
+	h.FormatVersion = 1
+	if h.Size() != HeaderSizeV1 {
+		t.Fatalf("Size = %d, want %d", h.Size(), HeaderSizeV1)
+	}
+	buf := make([]byte, HeaderSizeV1)
+	if err := EncodeHeader(buf, h); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	got, err := DecodeHeader(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if *got != *h {
+		t.Errorf("decoded %+v, want %+v", got, h)
+	}
+
+	h.Flags = FlagMultiWriter
+	if err := EncodeHeader(buf, h); err == nil || !strings.Contains(err.Error(), "format version 1 has no flags") {
+		t.Errorf("v1 with flags: err = %v", err)
+	}
+}
+
+func TestDecodeHeader_V2TooShort(t *testing.T) {
+	buf := make([]byte, HeaderSize)
+	if err := EncodeHeader(buf, validHeader()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeHeader(buf[:HeaderSizeV1]); err == nil || !strings.Contains(err.Error(), "buffer too small for format version 2") {
+		t.Errorf("err = %v", err)
+	}
+}
+
 func FuzzDecodeHeader(f *testing.F) {
 	f.Add(make([]byte, 64))
 	f.Fuzz(func(t *testing.T, data []byte) {
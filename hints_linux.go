@@ -0,0 +1,11 @@
+//go:build linux
+
+package mmapforge
+
+import "syscall"
+
+// MAP_POPULATE <- prefault the page tables while mapping
+const mapPopulate = syscall.MAP_POPULATE
+
+// MADV_HUGEPAGE <- ask for transparent huge pages
+const madvHugepage = syscall.MADV_HUGEPAGE
@@ -0,0 +1,9 @@
+//go:build unix && !linux
+
+package mmapforge
+
+// no MAP_POPULATE outside Linux <- prefault falls back to MADV_WILLNEED
+const mapPopulate = 0
+
+// no transparent huge pages outside Linux
+const madvHugepage = -1
@@ -1,5 +1,13 @@
 package codegen
 
+import (
+	"fmt"
+	"go/build/constraint"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
 // DefaultHeader is used when Config.Header is empty.
 const DefaultHeader = "// Code generated by mmapforge. DO NOT EDIT."
 
@@ -21,6 +29,34 @@ type Config struct {
 	// Hooks holds an optional list of Hooks to apply on the graph
 	// before/after code generation.
 	Hooks []Hook
+
+	// BuildTag is an optional build constraint expression, such as
+	// "linux && amd64", added as a //go:build line to every generated file.
+	BuildTag string
+
+	// Features turns optional generated code on or off by feature name,
+	// overriding the Default of the matching entry in AllFeatures.
+	Features map[string]bool
+
+	// Types holds per-type overrides keyed by schema struct name.
+	Types map[string]TypeConfig
+}
+
+// TypeConfig overrides code generation for a single schema type.
+type TypeConfig struct {
+	// Store replaces the generated store name (default <Name>Store).
+	// The constructors follow it: New<Store> and Open<Store>.
+	Store string
+
+	// File replaces the generated store file name (default <name>_store.go).
+	File string
+
+	// TestFile replaces the generated test file name. It defaults to File
+	// with a _test suffix.
+	TestFile string
+
+	// SkipTests disables the generated test file.
+	SkipTests bool
 }
 
 func (c *Config) header() string {
@@ -29,3 +65,47 @@ func (c *Config) header() string {
 	}
 	return DefaultHeader
 }
+
+// TestBuildTag returns the build constraint of generated test files, which
+// need a unix mmap on top of the configured BuildTag.
+func (c *Config) TestBuildTag() string {
+	if c.BuildTag == "" {
+		return "unix"
+	}
+	return "unix && (" + c.BuildTag + ")"
+}
+
+// validate checks the build tag, feature names and per-type overrides.
+func (c *Config) validate() error {
+	if c.BuildTag != "" {
+		if _, err := constraint.Parse("//go:build " + c.BuildTag); err != nil {
+			return fmt.Errorf("mmapforge: build tag %q: %w", c.BuildTag, err)
+		}
+	}
+	for name := range c.Features {
+		if _, ok := lookupFeature(name); !ok {
+			return fmt.Errorf("mmapforge: unknown feature %q", name)
+		}
+	}
+	for name, tc := range c.Types {
+		if tc.Store != "" && !token.IsIdentifier(tc.Store) {
+			return fmt.Errorf("mmapforge: type %s: store name %q is not a Go identifier", name, tc.Store)
+		}
+		if tc.File != "" && !goFileName(tc.File, false) {
+			return fmt.Errorf("mmapforge: type %s: file %q must be a .go file name without directories", name, tc.File)
+		}
+		if tc.TestFile != "" && !goFileName(tc.TestFile, true) {
+			return fmt.Errorf("mmapforge: type %s: test file %q must be a _test.go file name without directories", name, tc.TestFile)
+		}
+	}
+	return nil
+}
+
+// goFileName reports whether name is a bare Go file name, and a test file
+// exactly when test is set.
+func goFileName(name string, test bool) bool {
+	if filepath.Base(name) != name || !strings.HasSuffix(name, ".go") {
+		return false
+	}
+	return strings.HasSuffix(name, "_test.go") == test
+}
@@ -1,6 +1,9 @@
 package codegen
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestDefaultHeader(t *testing.T) {
 	if DefaultHeader == "" {
@@ -21,3 +24,37 @@ func TestConfig_header_Default(t *testing.T) {
 		t.Errorf("header() = %q, want %q", got, DefaultHeader)
 	}
 }
+
+func TestConfig_validate(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Config
+		want string
+	}{
+		{"empty", Config{}, ""},
+		{"valid", Config{
+			BuildTag: "linux && !386",
+			Features: map[string]bool{"record": false},
+			Types:    map[string]TypeConfig{"Tick": {Store: "TickTable", File: "ticks.go", TestFile: "ticks_x_test.go"}},
+		}, ""},
+		{"build tag", Config{BuildTag: "linux &&"}, "build tag"},
+		{"feature", Config{Features: map[string]bool{"bogus": true}}, `unknown feature "bogus"`},
+		{"store", Config{Types: map[string]TypeConfig{"Tick": {Store: "Tick Table"}}}, "not a Go identifier"},
+		{"file dir", Config{Types: map[string]TypeConfig{"Tick": {File: "sub/tick.go"}}}, "without directories"},
+		{"file ext", Config{Types: map[string]TypeConfig{"Tick": {File: "tick.txt"}}}, "must be a .go file"},
+		{"file test", Config{Types: map[string]TypeConfig{"Tick": {File: "tick_test.go"}}}, "must be a .go file"},
+		{"test file", Config{Types: map[string]TypeConfig{"Tick": {TestFile: "tick.go"}}}, "must be a _test.go file"},
+	}
+	for _, tt := range tests {
+		err := tt.c.validate()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+}
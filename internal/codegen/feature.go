@@ -0,0 +1,59 @@
+package codegen
+
+// Feature is an optional part of the generated code that can be turned on
+// or off through Config.Features.
+type Feature struct {
+	// Name is the key used in Config.Features and by templates.
+	Name string
+
+	// Description summarizes what the feature generates.
+	Description string
+
+	// Default reports whether the feature is generated when the config
+	// does not mention it.
+	Default bool
+}
+
+var (
+	// FeatureRecord generates the <Name>Record struct with the whole-record
+	// Get and Set methods.
+	FeatureRecord = Feature{
+		Name:        "record",
+		Description: "Record struct with whole-record Get and Set",
+		Default:     true,
+	}
+
+	// FeatureRegistry generates mmapforge_registry.go with the Stores
+	// struct, OpenAll, CloseAll and Schemas for all types of a package.
+	FeatureRegistry = Feature{
+		Name:        "registry",
+		Description: "Package-wide OpenAll, CloseAll and Schemas",
+		Default:     true,
+	}
+
+	// AllFeatures lists the features known to the generator.
+	AllFeatures = []Feature{
+		FeatureRecord,
+		FeatureRegistry,
+	}
+)
+
+func lookupFeature(name string) (Feature, bool) {
+	for _, f := range AllFeatures {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Feature{}, false
+}
+
+// FeatureEnabled reports whether the named feature is generated.
+func (c *Config) FeatureEnabled(name string) bool {
+	if c != nil {
+		if on, ok := c.Features[name]; ok {
+			return on
+		}
+	}
+	f, _ := lookupFeature(name)
+	return f.Default
+}
@@ -0,0 +1,32 @@
+package codegen
+
+import "testing"
+
+func TestConfig_FeatureEnabled(t *testing.T) {
+	if !(&Config{}).FeatureEnabled("record") {
+		t.Error("record should be enabled by default")
+	}
+	if (&Config{Features: map[string]bool{"record": false}}).FeatureEnabled("record") {
+		t.Error("record should be disabled by the config")
+	}
+	if (&Config{}).FeatureEnabled("nope") {
+		t.Error("unknown features should be disabled")
+	}
+	var c *Config
+	if !c.FeatureEnabled("record") {
+		t.Error("nil config should use the default")
+	}
+}
+
+func TestAllFeatures_Unique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, f := range AllFeatures {
+		if f.Name == "" || f.Description == "" {
+			t.Errorf("feature %+v needs a name and description", f)
+		}
+		if seen[f.Name] {
+			t.Errorf("duplicate feature %q", f.Name)
+		}
+		seen[f.Name] = true
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"go/format"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"text/template/parse"
 
 	"github.com/CreditWorthy/mmapforge"
@@ -46,6 +48,9 @@ func NewGraph(c *Config, schemas []StructSchema) (*Graph, error) {
 	if c.Target == "" {
 		return nil, fmt.Errorf("mmapforge: codegen: target directory is required")
 	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
 
 	g := &Graph{
 		Config: c,
@@ -77,9 +82,168 @@ func NewGraph(c *Config, schemas []StructSchema) (*Graph, error) {
 		})
 	}
 
+	if err := g.CheckCollisions(nil); err != nil {
+		return nil, err
+	}
 	return g, nil
 }
 
+// CheckCollisions reports generated identifiers and file names that clash
+// with each other or with a schema struct name, including the files of
+// external templates executed for the graph, generated identifiers that
+// clash with declared, a map of hand-written top-level identifiers to their
+// positions, and store methods generated twice for one type.
+func (g *Graph) CheckCollisions(declared map[string]string) error {
+	pkg := g.PackageName()
+	idents := make(map[string]string)
+	for _, n := range g.Nodes {
+		if prev, ok := idents[n.Name]; ok {
+			return fmt.Errorf("mmapforge: package %s: schema struct %s declared twice (%s)", pkg, n.Name, prev)
+		}
+		idents[n.Name] = "schema struct " + n.Name
+	}
+	claim := func(id, owner string) error {
+		if prev, ok := idents[id]; ok {
+			return fmt.Errorf("mmapforge: package %s: %s collides with %s", pkg, owner, prev)
+		}
+		if pos, ok := declared[id]; ok {
+			return fmt.Errorf("mmapforge: package %s: %s collides with %s declared at %s", pkg, owner, id, pos)
+		}
+		idents[id] = owner
+		return nil
+	}
+
+	files := make(map[string]string)
+	claimFile := func(name, owner string) error {
+		name = strings.ToLower(name)
+		if prev, ok := files[name]; ok {
+			return fmt.Errorf("mmapforge: package %s: file %s for %s collides with %s", pkg, name, owner, prev)
+		}
+		files[name] = owner
+		return nil
+	}
+
+	for _, n := range g.Nodes {
+		for _, id := range n.GeneratedNames() {
+			if err := claim(id, id+" generated for "+n.Name); err != nil {
+				return err
+			}
+		}
+		methods := make(map[string]bool)
+		for _, m := range n.MethodNames() {
+			if methods[m] {
+				return fmt.Errorf("mmapforge: package %s: method %s.%s is generated twice; rename a field", pkg, n.StoreName(), m)
+			}
+			methods[m] = true
+		}
+	}
+	for _, id := range g.GeneratedNames() {
+		if err := claim(id, id+" generated for the registry"); err != nil {
+			return err
+		}
+	}
+	for _, f := range g.outputFiles() {
+		if err := claimFile(f.name, f.owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputFile is a file generate writes into the target directory.
+type outputFile struct {
+	name  string // file name, relative to the target
+	owner string // what writes it, for error messages
+}
+
+// outputFiles returns the files generate writes for g, in order: each
+// type's files, the graph templates' files, the extra external templates'
+// files and the lock file.
+func (g *Graph) outputFiles() []outputFile {
+	var files []outputFile
+	for _, n := range g.Nodes {
+		for _, tmpl := range TypeTemplates {
+			if tmpl.Cond == nil || tmpl.Cond(n) {
+				files = append(files, outputFile{tmpl.Format(n), n.Name})
+			}
+		}
+	}
+	for _, tmpl := range GraphTemplates {
+		if tmpl.Skip == nil || !tmpl.Skip(g) {
+			files = append(files, outputFile{tmpl.Format, "the " + tmpl.Name + " template"})
+		}
+	}
+	for _, name := range g.extraTemplates() {
+		files = append(files, outputFile{extraFile(name), "the external " + name + " template"})
+	}
+	return append(files, outputFile{LockFile, "the lock file"})
+}
+
+// builtinTemplates is the parsed set of built-in templates, used to tell
+// which external templates override one.
+var builtinTemplates = sync.OnceValue(func() *Template {
+	return MustParse(NewTemplate("mmapforge").ParseFS(templateDir, "template/*.tmpl"))
+})
+
+// extraTemplates returns, in order, the external templates that are
+// executed once for the graph: every non-empty one that neither overrides a
+// built-in template nor is a helper named "helper/...".
+func (g *Graph) extraTemplates() []string {
+	var extra []string
+	seen := make(map[string]bool)
+	for _, ext := range g.Templates {
+		for _, tmpl := range ext.Templates() {
+			name := tmpl.Name()
+			if parse.IsEmptyTree(tmpl.Tree.Root) || seen[name] ||
+				strings.HasPrefix(name, "helper/") || builtinTemplates().Lookup(name) != nil {
+				continue
+			}
+			seen[name] = true
+			extra = append(extra, name)
+		}
+	}
+	return extra
+}
+
+// extraFile returns the file an external template writes: its name with
+// any .tmpl suffix dropped and slashes replaced by underscores, plus ".go".
+func extraFile(name string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(name, ".tmpl"), "/", "_") + ".go"
+}
+
+// Header returns the file header for generated code.
+func (g *Graph) Header() string {
+	return g.Config.header()
+}
+
+// PackageName returns the Go package of the generated code: the configured
+// Package, or else the package of the first schema.
+func (g *Graph) PackageName() string {
+	if g.Package == "" && len(g.Nodes) > 0 {
+		return g.Nodes[0].Package
+	}
+	return g.Package
+}
+
+// SkipTests reports whether every type skips its generated tests.
+func (g *Graph) SkipTests() bool {
+	for _, n := range g.Nodes {
+		if !n.Options().SkipTests {
+			return false
+		}
+	}
+	return true
+}
+
+// GeneratedNames returns the package-level identifiers the graph templates
+// declare.
+func (g *Graph) GeneratedNames() []string {
+	if !g.FeatureEnabled(FeatureRegistry.Name) {
+		return nil
+	}
+	return []string{"Stores", "OpenAll", "Schemas"}
+}
+
 // Gen generates all artifacts. Hooks wrap the core generation
 func (g *Graph) Gen() error {
 	var gen Generator = GenerateFunc(generateFunc)
@@ -94,8 +258,21 @@ func generate(g *Graph) error {
 		return fmt.Errorf("mmapforge: create target dir: %w", err)
 	}
 
+	// Refuse to generate over a schema change that needs a version bump.
+	lockPath := filepath.Join(g.Target, LockFile)
+	lock, err := ReadLock(lockPath)
+	if err != nil {
+		return err
+	}
+	if err := lock.Verify(g, false); err != nil {
+		return err
+	}
+
 	initTemplates()
 
+	// External templates override built-in ones of the same name. Any other
+	// template, except helpers named "helper/...", is executed once for the
+	// graph into <name>.go, with slashes in the name replaced by underscores.
 	for _, ext := range g.Templates {
 		templates.Funcs(ext.FuncMap)
 		for _, tmpl := range ext.Templates() {
@@ -137,6 +314,21 @@ func generate(g *Graph) error {
 		}
 	}
 
+	for _, name := range g.extraTemplates() {
+		b := bytes.NewBuffer(nil)
+		if err := templates.ExecuteTemplate(b, name, g); err != nil {
+			return fmt.Errorf("mmapforge: execute %q: %w", name, err)
+		}
+		path := filepath.Join(g.Target, extraFile(name))
+		if err := writeFormattedFunc(path, b.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	lock.Record(g)
+	if err := writeFileFunc(lockPath, lock.Bytes(), 0644); err != nil {
+		return fmt.Errorf("mmapforge: write lock: %w", err)
+	}
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/CreditWorthy/mmapforge"
@@ -641,3 +642,332 @@ func TestWriteFormatted_WriteError(t *testing.T) {
 		t.Fatal("expected write error")
 	}
 }
+
+func TestNewGraph_Collisions(t *testing.T) {
+	field := []mmapforge.FieldDef{{Name: "X", Type: mmapforge.FieldUint32}}
+	tests := []struct {
+		name    string
+		schemas []string
+		want    string
+	}{
+		{"generated vs schema", []string{"Tick", "TickStore"}, "TickStore generated for Tick collides with schema struct TickStore"},
+		{"record type", []string{"Tick", "TickRecord"}, "TickRecord generated for Tick collides with schema struct TickRecord"},
+		{"duplicate schema", []string{"Tick", "Tick"}, "schema struct Tick declared twice"},
+		{"file name", []string{"Tick", "TICK"}, "file tick_store.go for TICK collides with Tick"},
+	}
+	for _, tt := range tests {
+		var schemas []StructSchema
+		for _, n := range tt.schemas {
+			schemas = append(schemas, StructSchema{Name: n, Package: "feeds", Fields: field})
+		}
+		_, err := NewGraph(&Config{Target: t.TempDir()}, schemas)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNewGraph_MethodCollisions(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []string
+		want   string
+	}{
+		{"ctx getter", []string{"Price", "PriceCtx"}, "method TickStore.GetPriceCtx is generated twice"},
+		{"record getter", []string{"Ctx"}, "method TickStore.GetCtx is generated twice"},
+	}
+	for _, tt := range tests {
+		var fields []mmapforge.FieldDef
+		for _, n := range tt.fields {
+			fields = append(fields, mmapforge.FieldDef{Name: strings.ToLower(n), GoName: n, Type: mmapforge.FieldUint32})
+		}
+		_, err := NewGraph(&Config{Target: t.TempDir()}, []StructSchema{{Name: "Tick", Package: "feeds", Fields: fields}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	g, err := NewGraph(&Config{Target: t.TempDir(), Features: map[string]bool{"record": false}}, []StructSchema{
+		{Name: "Tick", Package: "feeds", Fields: []mmapforge.FieldDef{{Name: "ctx", GoName: "Ctx", Type: mmapforge.FieldUint32}}},
+	})
+	if err != nil {
+		t.Errorf("field Ctx without the record feature: %v", err)
+	} else if got := g.Nodes[0].MethodNames(); len(got) != 10 {
+		t.Errorf("MethodNames = %v", got)
+	}
+}
+
+func TestGraph_CheckCollisions_Declared(t *testing.T) {
+	g, err := NewGraph(&Config{Target: t.TempDir()}, []StructSchema{
+		{Name: "Tick", Package: "feeds", Fields: []mmapforge.FieldDef{{Name: "X", Type: mmapforge.FieldUint32}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.CheckCollisions(map[string]string{"Tick": "tick.go:3:6"}); err != nil {
+		t.Errorf("schema struct itself should not collide: %v", err)
+	}
+	err = g.CheckCollisions(map[string]string{"OpenTickStore": "open.go:7:6"})
+	if err == nil || !strings.Contains(err.Error(), "OpenTickStore generated for Tick collides with OpenTickStore declared at open.go:7:6") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestNewGraph_InvalidConfig(t *testing.T) {
+	_, err := NewGraph(&Config{Target: t.TempDir(), Features: map[string]bool{"bogus": true}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown feature") {
+		t.Errorf("err = %v, want unknown feature", err)
+	}
+}
+
+func TestGraph_Header(t *testing.T) {
+	if got := (&Graph{Config: &Config{}}).Header(); got != DefaultHeader {
+		t.Errorf("Header() = %q, want default", got)
+	}
+	if got := (&Graph{Config: &Config{Header: "// x"}}).Header(); got != "// x" {
+		t.Errorf("Header() = %q, want custom", got)
+	}
+}
+
+func TestGenerate_ExternalTemplates_Files(t *testing.T) {
+	origTypes := TypeTemplates
+	defer func() { TypeTemplates = origTypes }()
+	TypeTemplates = []TypeTemplate{}
+
+	ext := NewTemplate("ext")
+	if _, err := ext.Parse(`
+{{ define "helper/pkg" }}package {{ (index .Nodes 0).Package }}{{ end }}
+{{ define "names" }}{{ template "helper/pkg" . }}
+const Names = "{{ range .Nodes }}{{ .Name }}{{ end }}"
+{{ end }}
+{{ define "extra/more" }}{{ template "helper/pkg" . }}{{ end }}
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	g, err := NewGraph(&Config{Target: dir, Templates: []*Template{ext}}, []StructSchema{
+		{Name: "Foo", Package: "pkg", Fields: []mmapforge.FieldDef{{Name: "x", GoName: "X", Type: mmapforge.FieldUint32}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := generate(g); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "names.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `const Names = "Foo"`) {
+		t.Errorf("names.go = %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "extra_more.go")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "helper_pkg.go")); err == nil {
+		t.Error("helper templates should not be generated")
+	}
+}
+
+func TestGenerate_ExternalTemplates_FileExecuteError(t *testing.T) {
+	origTypes := TypeTemplates
+	defer func() { TypeTemplates = origTypes }()
+	TypeTemplates = []TypeTemplate{}
+
+	ext := MustParse(NewTemplate("ext").Parse(`{{ define "broken" }}{{ .Missing }}{{ end }}`))
+	g := &Graph{Config: &Config{Target: t.TempDir(), Templates: []*Template{ext}}}
+	if err := generate(g); err == nil || !strings.Contains(err.Error(), `execute "broken"`) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestGenerate_ExternalTemplates_FileWriteError(t *testing.T) {
+	origTypes := TypeTemplates
+	origWrite := writeFormattedFunc
+	defer func() {
+		TypeTemplates = origTypes
+		writeFormattedFunc = origWrite
+	}()
+	TypeTemplates = []TypeTemplate{}
+	writeFormattedFunc = func(_ string, _ []byte) error { return errors.New("disk full") }
+
+	ext := MustParse(NewTemplate("ext").Parse(`{{ define "extra" }}package x{{ end }}`))
+	g := &Graph{Config: &Config{Target: t.TempDir(), Templates: []*Template{ext}}}
+	if err := generate(g); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestGenerate_ConfigOverrides(t *testing.T) {
+	dir := t.TempDir()
+	g, err := NewGraph(&Config{
+		Target:   dir,
+		BuildTag: "linux",
+		Features: map[string]bool{"record": false},
+		Types: map[string]TypeConfig{
+			"Foo": {Store: "FooTable", File: "foo_table.go"},
+			"Bar": {SkipTests: true},
+		},
+	}, []StructSchema{
+		{Name: "Foo", Package: "pkg", Fields: []mmapforge.FieldDef{{Name: "x", GoName: "X", Type: mmapforge.FieldUint32}}},
+		{Name: "Bar", Package: "pkg", Fields: []mmapforge.FieldDef{{Name: "y", GoName: "Y", Type: mmapforge.FieldInt64}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Gen(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got := strings.Join(names, " "); got != "bar_store.go foo_table.go foo_table_test.go mmapforge.lock mmapforge_registry.go mmapforge_registry_test.go" {
+		t.Errorf("files = %s", got)
+	}
+
+	store, err := os.ReadFile(filepath.Join(dir, "foo_table.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"//go:build linux\n", "type FooTable struct", "func NewFooTable(", "func OpenFooTable("} {
+		if !strings.Contains(string(store), want) {
+			t.Errorf("foo_table.go missing %q", want)
+		}
+	}
+	if strings.Contains(string(store), "FooRecord") {
+		t.Error("foo_table.go should not declare FooRecord with the record feature disabled")
+	}
+
+	test, err := os.ReadFile(filepath.Join(dir, "foo_table_test.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(test), "//go:build unix && linux\n") || strings.Contains(string(test), `"sync"`) {
+		t.Errorf("foo_table_test.go = %s", test)
+	}
+}
+
+func TestNewGraph_RegistryCollisions(t *testing.T) {
+	field := []mmapforge.FieldDef{{Name: "X", Type: mmapforge.FieldUint32}}
+	_, err := NewGraph(&Config{Target: t.TempDir()}, []StructSchema{
+		{Name: "Tick", Package: "feeds", Fields: field},
+		{Name: "Stores", Package: "feeds", Fields: field},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Stores generated for the registry collides with schema struct Stores") {
+		t.Errorf("schema named Stores: err = %v", err)
+	}
+
+	_, err = NewGraph(&Config{
+		Target: t.TempDir(),
+		Types:  map[string]TypeConfig{"Tick": {File: "mmapforge_registry.go"}},
+	}, []StructSchema{{Name: "Tick", Package: "feeds", Fields: field}})
+	if err == nil || !strings.Contains(err.Error(), "file mmapforge_registry.go for the registry template collides with Tick") {
+		t.Errorf("file override: err = %v", err)
+	}
+
+	g, err := NewGraph(&Config{Target: t.TempDir()}, []StructSchema{{Name: "Tick", Package: "feeds", Fields: field}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.CheckCollisions(map[string]string{"OpenAll": "app.go:9:6"})
+	if err == nil || !strings.Contains(err.Error(), "OpenAll generated for the registry collides with OpenAll declared at app.go:9:6") {
+		t.Errorf("declared OpenAll: err = %v", err)
+	}
+
+	g.Features = map[string]bool{"registry": false}
+	if err := g.CheckCollisions(map[string]string{"OpenAll": "app.go:9:6"}); err != nil {
+		t.Errorf("registry disabled: unexpected error: %v", err)
+	}
+}
+
+func TestNewGraph_ExtraTemplateCollisions(t *testing.T) {
+	field := []mmapforge.FieldDef{{Name: "X", Type: mmapforge.FieldUint32}}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"tick_store", "file tick_store.go for the external tick_store template collides with Tick"},
+		{"mmapforge/registry.tmpl", "file mmapforge_registry.go for the external mmapforge/registry.tmpl template collides with the registry template"},
+	}
+	for _, tt := range tests {
+		ext := NewTemplate("ext")
+		if _, err := ext.Parse(`{{ define "` + tt.name + `" }}package feeds{{ end }}`); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewGraph(&Config{Target: t.TempDir(), Templates: []*Template{ext}},
+			[]StructSchema{{Name: "Tick", Package: "feeds", Fields: field}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	ext := NewTemplate("ext")
+	if _, err := ext.Parse(`{{ define "helper/tick_store" }}x{{ end }}{{ define "extra" }}package feeds{{ end }}`); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGraph(&Config{Target: t.TempDir(), Templates: []*Template{ext}},
+		[]StructSchema{{Name: "Tick", Package: "feeds", Fields: field}})
+	if err != nil {
+		t.Fatalf("helper and distinct extra template: %v", err)
+	}
+	if got := g.extraTemplates(); len(got) != 1 || got[0] != "extra" {
+		t.Errorf("extraTemplates = %v, want [extra]", got)
+	}
+}
+
+func TestGraph_PackageName(t *testing.T) {
+	nodes := []*Type{{Name: "A", Package: "schema"}}
+	if got := (&Graph{Config: &Config{Package: "cfg"}, Nodes: nodes}).PackageName(); got != "cfg" {
+		t.Errorf("PackageName() = %q, want cfg", got)
+	}
+	if got := (&Graph{Config: &Config{}, Nodes: nodes}).PackageName(); got != "schema" {
+		t.Errorf("PackageName() = %q, want schema", got)
+	}
+	if got := (&Graph{Config: &Config{}}).PackageName(); got != "" {
+		t.Errorf("PackageName() = %q, want empty", got)
+	}
+}
+
+func TestGenerate_Registry(t *testing.T) {
+	dir := t.TempDir()
+	g, err := NewGraph(&Config{Target: dir, BuildTag: "linux"}, []StructSchema{
+		{Name: "Tick", Package: "feeds", SchemaVersion: 2, Fields: []mmapforge.FieldDef{{Name: "x", GoName: "X", Type: mmapforge.FieldUint32}}},
+		{Name: "Quote", Package: "feeds", Fields: []mmapforge.FieldDef{{Name: "y", GoName: "Y", Type: mmapforge.FieldInt64}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Gen(); err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.ReadFile(filepath.Join(dir, "mmapforge_registry.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"//go:build linux\n",
+		"package feeds\n",
+		"\tTick  *TickStore\n\tQuote *QuoteStore\n",
+		`s.Quote, err = NewQuoteStore(filepath.Join(dir, "quote.mmf"), opts...)`,
+		`mmapforge.NewSchemaInfo("Tick", 2, TickLayout()),`,
+		"func (s *Stores) CloseAll() error {",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("mmapforge_registry.go missing %q:\n%s", want, src)
+		}
+	}
+	test, err := os.ReadFile(filepath.Join(dir, "mmapforge_registry_test.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(test), "//go:build unix && linux\n") || !strings.Contains(string(test), "len(Schemas()) = %d, want 2") {
+		t.Errorf("mmapforge_registry_test.go:\n%s", test)
+	}
+}
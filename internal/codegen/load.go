@@ -0,0 +1,164 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Mockable function
+var loadPackagesFunc = packages.Load
+
+// Package is one Go package holding mmapforge:schema structs.
+type Package struct {
+	// Name is the Go package name.
+	Name string
+
+	// Path is the import path.
+	Path string
+
+	// Dir is the package directory; generated files are written here.
+	Dir string
+
+	// Schemas are the annotated structs across all files of the package.
+	Schemas []StructSchema
+
+	// Declared maps each top-level identifier of the hand-written files to
+	// its position, for collision checks against generated code.
+	Declared map[string]string
+}
+
+// LoadPackages loads the packages matching patterns (anything `go build`
+// accepts, such as ./... or a list of .go files) relative to dir and
+// extracts every mmapforge:schema struct from their non-test files.
+// Generated files are skipped. Packages without schemas are dropped.
+func LoadPackages(dir string, patterns ...string) ([]*Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles,
+		Dir:  dir,
+	}
+	pkgs, err := loadPackagesFunc(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("mmapforge: load packages: %w", err)
+	}
+
+	var errs []error
+	var out []*Package
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			errs = append(errs, fmt.Errorf("mmapforge: %w", e))
+		}
+		if len(pkg.Errors) > 0 {
+			continue
+		}
+		p, err := parsePackage(pkg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(p.Schemas) > 0 {
+			out = append(out, p)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return out, nil
+}
+
+// parsePackage parses the Go files of pkg for schemas and declarations.
+func parsePackage(pkg *packages.Package) (*Package, error) {
+	p := &Package{
+		Name:     pkg.Name,
+		Path:     pkg.PkgPath,
+		Declared: map[string]string{},
+	}
+	fset := token.NewFileSet()
+	for _, path := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("mmapforge: parse %s: %w", path, err)
+		}
+		if p.Dir == "" {
+			p.Dir = filepath.Dir(path)
+		}
+		if ast.IsGenerated(f) {
+			continue
+		}
+		schemas, err := extractSchemas(f, fset)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		p.Schemas = append(p.Schemas, schemas...)
+		declarations(f, fset, p.Declared)
+	}
+	return p, nil
+}
+
+// DeclaredInDir returns the top-level identifiers, with their positions,
+// of the hand-written files of package pkg in dir, as Package.Declared
+// does for loaded packages. Test files, generated files and files of other
+// packages are skipped; a missing dir declares nothing.
+func DeclaredInDir(dir, pkg string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mmapforge: read %s: %w", dir, err)
+	}
+	decl := map[string]string{}
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("mmapforge: parse %s: %w", path, err)
+		}
+		if f.Name.Name != pkg || ast.IsGenerated(f) {
+			continue
+		}
+		declarations(f, fset, decl)
+	}
+	return decl, nil
+}
+
+// declarations records the top-level identifiers of f (types, functions,
+// variables and constants; not methods) in decl.
+func declarations(f *ast.File, fset *token.FileSet, decl map[string]string) {
+	add := func(id *ast.Ident) {
+		if id.Name != "_" {
+			decl[id.Name] = fset.Position(id.Pos()).String()
+		}
+	}
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				add(d.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					add(s.Name)
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						add(n)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package codegen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// writeModule writes files (slash-separated paths relative to the module
+// root) into a temporary module and returns its root.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	files["go.mod"] = "module example.com/feeds\n\ngo 1.24\n"
+	for name, src := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestLoadPackages(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"ticks/tick.go": `package ticks
+
+// mmapforge:schema version=2
+type Tick struct {
+	Price float64
+}
+
+func helper() {}
+`,
+		"ticks/quote.go": `package ticks
+
+// mmapforge:schema version=1
+type Quote struct {
+	Bid, Ask float64
+}
+
+var Default = Quote{}
+`,
+		"ticks/tick_store.go": `// Code generated by mmapforge. DO NOT EDIT.
+
+package ticks
+
+// mmapforge:schema version=1
+type Stale struct {
+	X int32
+}
+`,
+		"ticks/tick_test.go": `package ticks
+
+// mmapforge:schema version=1
+type Fixture struct {
+	X int32
+}
+`,
+		"plain/plain.go": "package plain\n\ntype Plain struct{}\n",
+	})
+
+	pkgs, err := LoadPackages(root, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 {
+		t.Fatalf("packages = %d, want 1 (plain has no schemas)", len(pkgs))
+	}
+	p := pkgs[0]
+	if p.Name != "ticks" || p.Path != "example.com/feeds/ticks" {
+		t.Errorf("package = %s %s", p.Name, p.Path)
+	}
+	// go list reports resolved paths; compare without symlinks.
+	if want := filepath.Join(root, "ticks"); mustEvalSymlinks(t, p.Dir) != mustEvalSymlinks(t, want) {
+		t.Errorf("Dir = %s, want %s", p.Dir, want)
+	}
+
+	var names []string
+	for _, s := range p.Schemas {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "Quote,Tick" {
+		t.Errorf("schemas = %s, want Quote,Tick", got)
+	}
+
+	for _, id := range []string{"Tick", "Quote", "helper", "Default"} {
+		if !strings.Contains(p.Declared[id], "ticks") {
+			t.Errorf("Declared[%s] = %q", id, p.Declared[id])
+		}
+	}
+	for _, id := range []string{"Stale", "Fixture"} {
+		if _, ok := p.Declared[id]; ok {
+			t.Errorf("%s from a generated or test file should not be declared", id)
+		}
+	}
+}
+
+func mustEvalSymlinks(t *testing.T, path string) string {
+	t.Helper()
+	p, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadPackages_Files(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"a.go": "package feeds\n\n// mmapforge:schema version=1\ntype A struct {\n\tX int32\n}\n",
+	})
+	pkgs, err := LoadPackages(root, filepath.Join(root, "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 || len(pkgs[0].Schemas) != 1 || pkgs[0].Name != "feeds" {
+		t.Errorf("packages = %+v", pkgs)
+	}
+}
+
+func TestLoadPackages_LoadError(t *testing.T) {
+	orig := loadPackagesFunc
+	defer func() { loadPackagesFunc = orig }()
+
+	loadPackagesFunc = func(_ *packages.Config, _ ...string) ([]*packages.Package, error) {
+		return nil, errors.New("go list failed")
+	}
+	_, err := LoadPackages("", "./...")
+	if err == nil || !strings.Contains(err.Error(), "go list failed") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestLoadPackages_PackageErrors(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"ok/ok.go": "package ok\n\n// mmapforge:schema version=1\ntype OK struct {\n\tX int32\n}\n",
+	})
+	_, err := LoadPackages(root, "./ok", "./missing")
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("err = %v, want error for ./missing", err)
+	}
+}
+
+func TestLoadPackages_SchemaError(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"bad/bad.go": "package bad\n\n// mmapforge:schema version=1\ntype Bad struct {\n\tX complex128\n}\n",
+	})
+	_, err := LoadPackages(root, "./bad")
+	if err == nil || !strings.Contains(err.Error(), "bad.go") {
+		t.Errorf("err = %v, want error naming bad.go", err)
+	}
+}
+
+func TestParsePackage_ParseError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.go")
+	if err := os.WriteFile(path, []byte("package broken\n\nfunc {"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := parsePackage(&packages.Package{Name: "broken", GoFiles: []string{path}})
+	if err == nil || !strings.Contains(err.Error(), "parse") {
+		t.Errorf("err = %v, want parse error", err)
+	}
+}
+
+func TestDeclaredInDir(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"ticks/tick.go":       "package ticks\n\ntype Tick struct{}\n\nvar Stores, _ = 1, 2\n",
+		"ticks/tick_test.go":  "package ticks\n\nfunc OpenAll() {}\n",
+		"ticks/gen.go":        "// Code generated by mmapforge. DO NOT EDIT.\n\npackage ticks\n\nfunc Schemas() {}\n",
+		"ticks/other.go":      "package other\n\nfunc Other() {}\n",
+		"ticks/sub/nested.go": "package ticks\n\nfunc Nested() {}\n",
+	})
+	decl, err := DeclaredInDir(filepath.Join(root, "ticks"), "ticks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decl) != 2 || decl["Tick"] == "" || decl["Stores"] == "" {
+		t.Errorf("DeclaredInDir = %v, want Tick and Stores", decl)
+	}
+
+	if decl, err := DeclaredInDir(filepath.Join(root, "missing"), "ticks"); err != nil || len(decl) != 0 {
+		t.Errorf("missing dir = %v, %v; want empty", decl, err)
+	}
+
+	broken := filepath.Join(root, "broken")
+	if err := os.MkdirAll(broken, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(broken, "b.go"), []byte("package broken\n\nfunc {"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DeclaredInDir(broken, "broken"); err == nil || !strings.Contains(err.Error(), "parse") {
+		t.Errorf("err = %v, want parse error", err)
+	}
+}
@@ -0,0 +1,157 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LockFile is the name of the lock file written next to the generated code.
+const LockFile = "mmapforge.lock"
+
+const lockHeader = "# mmapforge.lock records the schema hash of every version of every\n" +
+	"# mmapforge:schema struct in this package. Commit it; do not edit it.\n"
+
+// Lock maps schema type names to the hex schema hash of each version seen.
+type Lock map[string]map[uint32]string
+
+// ReadLock reads the lock file at path. A missing file is an empty lock.
+func ReadLock(path string) (Lock, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Lock{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mmapforge: read lock: %w", err)
+	}
+
+	l := Lock{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, version, hash, err := parseLockLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("mmapforge: %s:%d: %w", path, n, err)
+		}
+		if _, ok := l[name][version]; ok {
+			return nil, fmt.Errorf("mmapforge: %s:%d: %s v%d listed twice", path, n, name, version)
+		}
+		l.set(name, version, hash)
+	}
+	return l, nil
+}
+
+// parseLockLine parses a "<Type> v<version> sha256:<hex>" line.
+func parseLockLine(line string) (name string, version uint32, hash string, err error) {
+	parts := strings.Fields(line)
+	if len(parts) != 3 || !strings.HasPrefix(parts[1], "v") || !strings.HasPrefix(parts[2], "sha256:") {
+		return "", 0, "", fmt.Errorf("malformed line %q", line)
+	}
+	v, err := strconv.ParseUint(parts[1][1:], 10, 32)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("bad version %q", parts[1])
+	}
+	hash = strings.TrimPrefix(parts[2], "sha256:")
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != 32 {
+		return "", 0, "", fmt.Errorf("bad hash %q", parts[2])
+	}
+	return parts[0], uint32(v), hash, nil
+}
+
+func (l Lock) set(name string, version uint32, hash string) {
+	if l[name] == nil {
+		l[name] = make(map[uint32]string)
+	}
+	l[name][version] = hash
+}
+
+// Bytes returns the lock file contents, sorted by type name and version.
+func (l Lock) Bytes() []byte {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	b.WriteString(lockHeader)
+	for _, name := range names {
+		versions := make([]uint32, 0, len(l[name]))
+		for v := range l[name] {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+		for _, v := range versions {
+			fmt.Fprintf(&b, "%s v%d sha256:%s\n", name, v, l[name][v])
+		}
+	}
+	return b.Bytes()
+}
+
+// Verify reports every type of g whose schema changed without a version
+// bump, or whose version is lower than one already locked. If strict is
+// set it also reports types whose current version is not locked yet.
+func (l Lock) Verify(g *Graph, strict bool) error {
+	var errs []error
+	for _, n := range g.Nodes {
+		hash := lockHash(n)
+		locked, ok := l[n.Name][n.SchemaVersion]
+		switch {
+		case maxVersion(l[n.Name]) > n.SchemaVersion:
+			errs = append(errs, fmt.Errorf("%s v%d: version went backwards (v%d is locked)",
+				n.Name, n.SchemaVersion, maxVersion(l[n.Name])))
+		case ok && locked != hash:
+			errs = append(errs, fmt.Errorf("%s v%d: schema changed without a version bump (locked sha256:%.12s, now sha256:%.12s)",
+				n.Name, n.SchemaVersion, locked, hash))
+		case !ok && strict:
+			errs = append(errs, fmt.Errorf("%s v%d: not recorded in %s; run mmapforge to update it", n.Name, n.SchemaVersion, LockFile))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("mmapforge: %s: %w", filepath.Join(g.Target, LockFile), errors.Join(errs...))
+	}
+	return nil
+}
+
+// Record adds the current version and hash of every type of g.
+func (l Lock) Record(g *Graph) {
+	for _, n := range g.Nodes {
+		l.set(n.Name, n.SchemaVersion, lockHash(n))
+	}
+}
+
+// lockHash returns the hex schema hash of n as recorded in the lock.
+func lockHash(n *Type) string {
+	sum := n.SchemaHash()
+	return hex.EncodeToString(sum[:])
+}
+
+func maxVersion(versions map[uint32]string) uint32 {
+	var m uint32
+	for v := range versions {
+		m = max(m, v)
+	}
+	return m
+}
+
+// CheckLock verifies g against the lock file in its target directory
+// without writing anything. Unlike generation, it also fails when a type's
+// current version has not been recorded.
+func (g *Graph) CheckLock() error {
+	l, err := ReadLock(filepath.Join(g.Target, LockFile))
+	if err != nil {
+		return err
+	}
+	return l.Verify(g, true)
+}
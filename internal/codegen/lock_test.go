@@ -0,0 +1,204 @@
+package codegen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// lockGraph returns a graph for a Tick schema at version whose fields are
+// price and, if withSize is set, size.
+func lockGraph(t *testing.T, dir string, version uint32, withSize bool) *Graph {
+	t.Helper()
+	fields := []mmapforge.FieldDef{{Name: "price", GoName: "Price", Type: mmapforge.FieldFloat64}}
+	if withSize {
+		fields = append(fields, mmapforge.FieldDef{Name: "size", GoName: "Size", Type: mmapforge.FieldUint32})
+	}
+	g, err := NewGraph(&Config{Target: dir, Package: "feeds"}, []StructSchema{{Name: "Tick", SchemaVersion: version, Fields: fields}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestType_SchemaHash(t *testing.T) {
+	g := lockGraph(t, t.TempDir(), 1, true)
+	layout, err := mmapforge.ComputeLayout([]mmapforge.FieldDef{
+		{Name: "price", GoName: "Price", Type: mmapforge.FieldFloat64},
+		{Name: "size", GoName: "Size", Type: mmapforge.FieldUint32},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Nodes[0].SchemaHash() != mmapforge.SchemaHash(layout.Descriptors()) {
+		t.Error("SchemaHash does not match the store's header hash")
+	}
+}
+
+func TestLock_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFile)
+	l := Lock{}
+	l.set("Tick", 2, strings.Repeat("b", 64))
+	l.set("Tick", 1, strings.Repeat("a", 64))
+	l.set("Quote", 1, strings.Repeat("c", 64))
+
+	want := lockHeader +
+		"Quote v1 sha256:" + strings.Repeat("c", 64) + "\n" +
+		"Tick v1 sha256:" + strings.Repeat("a", 64) + "\n" +
+		"Tick v2 sha256:" + strings.Repeat("b", 64) + "\n"
+	if got := string(l.Bytes()); got != want {
+		t.Fatalf("Bytes() =\n%s\nwant\n%s", got, want)
+	}
+
+	if err := os.WriteFile(path, l.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || len(got["Tick"]) != 2 || got["Tick"][2] != strings.Repeat("b", 64) {
+		t.Errorf("ReadLock = %v", got)
+	}
+}
+
+func TestReadLock_Missing(t *testing.T) {
+	l, err := ReadLock(filepath.Join(t.TempDir(), LockFile))
+	if err != nil || len(l) != 0 {
+		t.Errorf("ReadLock = %v, %v", l, err)
+	}
+}
+
+func TestReadLock_Errors(t *testing.T) {
+	hash := strings.Repeat("a", 64)
+	tests := []struct {
+		content, want string
+	}{
+		{"Tick v1\n", "malformed line"},
+		{"Tick 1 sha256:" + hash + "\n", "malformed line"},
+		{"Tick v1 md5:" + hash + "\n", "malformed line"},
+		{"Tick vx sha256:" + hash + "\n", `bad version "vx"`},
+		{"Tick v1 sha256:abc\n", `bad hash "sha256:abc"`},
+		{"Tick v1 sha256:" + strings.Repeat("z", 64) + "\n", "bad hash"},
+		{"# c\n\nTick v1 sha256:" + hash + "\nTick v1 sha256:" + hash + "\n", ":4: Tick v1 listed twice"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), LockFile)
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadLock(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want containing %q", tt.content, err, tt.want)
+		}
+	}
+
+	if _, err := ReadLock(t.TempDir()); err == nil || !strings.Contains(err.Error(), "read lock") {
+		t.Errorf("directory: err = %v", err)
+	}
+}
+
+func TestLock_Verify(t *testing.T) {
+	dir := t.TempDir()
+	l := Lock{}
+	l.Record(lockGraph(t, dir, 1, false))
+	l.Record(lockGraph(t, dir, 2, false))
+
+	tests := []struct {
+		name     string
+		g        *Graph
+		strict   bool
+		wantErr  string
+		wantPass bool
+	}{
+		{"same schema", lockGraph(t, dir, 2, false), true, "", true},
+		{"changed without bump", lockGraph(t, dir, 2, true), false, "Tick v2: schema changed without a version bump", false},
+		{"bumped", lockGraph(t, dir, 3, true), false, "", true},
+		{"bumped, strict", lockGraph(t, dir, 3, true), true, "Tick v3: not recorded in mmapforge.lock", false},
+		{"backwards", lockGraph(t, dir, 0, false), false, "Tick v0: version went backwards (v2 is locked)", false},
+		{"backwards to a locked version", lockGraph(t, dir, 1, false), false, "Tick v1: version went backwards (v2 is locked)", false},
+	}
+	for _, tt := range tests {
+		err := l.Verify(tt.g, tt.strict)
+		if tt.wantPass {
+			if err != nil {
+				t.Errorf("%s: err = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGenerate_Lock(t *testing.T) {
+	dir := t.TempDir()
+	if err := lockGraph(t, dir, 1, false).CheckLock(); err == nil || !strings.Contains(err.Error(), "not recorded") {
+		t.Errorf("CheckLock before generating: err = %v", err)
+	}
+
+	if err := lockGraph(t, dir, 1, false).Gen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lockGraph(t, dir, 1, false).CheckLock(); err != nil {
+		t.Errorf("CheckLock after generating: %v", err)
+	}
+
+	// Changing the schema without a version bump is refused, and nothing is
+	// written.
+	store := filepath.Join(dir, "tick_store.go")
+	before, err := os.ReadFile(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lockGraph(t, dir, 1, true).Gen(); err == nil || !strings.Contains(err.Error(), "without a version bump") {
+		t.Errorf("Gen: err = %v", err)
+	}
+	if after, _ := os.ReadFile(store); string(after) != string(before) {
+		t.Error("tick_store.go was rewritten")
+	}
+
+	// A bump records the new version and keeps the old one.
+	if err := lockGraph(t, dir, 2, true).Gen(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := ReadLock(filepath.Join(dir, LockFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l["Tick"]) != 2 {
+		t.Errorf("lock = %v, want v1 and v2", l)
+	}
+	if err := lockGraph(t, dir, 1, false).CheckLock(); err == nil || !strings.Contains(err.Error(), "version went backwards") {
+		t.Errorf("CheckLock v1: err = %v", err)
+	}
+}
+
+func TestGenerate_LockErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, LockFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := lockGraph(t, dir, 1, false).Gen(); err == nil || !strings.Contains(err.Error(), "read lock") {
+		t.Errorf("read: err = %v", err)
+	}
+	if err := lockGraph(t, dir, 1, false).CheckLock(); err == nil || !strings.Contains(err.Error(), "read lock") {
+		t.Errorf("CheckLock: err = %v", err)
+	}
+
+	origWrite := writeFileFunc
+	defer func() { writeFileFunc = origWrite }()
+	writeFileFunc = func(name string, data []byte, perm os.FileMode) error {
+		if filepath.Base(name) == LockFile {
+			return errors.New("disk full")
+		}
+		return origWrite(name, data, perm)
+	}
+	if err := lockGraph(t, t.TempDir(), 1, false).Gen(); err == nil || !strings.Contains(err.Error(), "write lock: disk full") {
+		t.Errorf("write: err = %v", err)
+	}
+}
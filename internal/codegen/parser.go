@@ -140,12 +140,12 @@ func parseFields(st *ast.StructType) ([]mmapforge.FieldDef, error) {
 		goName := field.Names[0].Name
 		goType := typeString(field.Type)
 
-		ft, err := goTypeToFieldType(goType)
+		ft, err := mmapforge.ParseGoType(goType)
 		if err != nil {
 			return nil, fmt.Errorf("field %s: %w", goName, err)
 		}
 
-		name, maxSize, err := parseMmapTag(tagValue(field.Tag), goName)
+		name, maxSize, err := mmapforge.ParseFieldTag(tagValue(field.Tag), goName)
 		if err != nil {
 			return nil, fmt.Errorf("field %s: %w", goName, err)
 		}
@@ -164,30 +164,6 @@ func parseFields(st *ast.StructType) ([]mmapforge.FieldDef, error) {
 	return fields, nil
 }
 
-// parseMmapTag decodes `mmap:"name,max_size"`. Returns the mmap field
-// name (defaults to lowercase goName) and max_size.
-func parseMmapTag(raw string, goName string) (string, uint32, error) {
-	if raw == "" {
-		return strings.ToLower(goName), 0, nil
-	}
-
-	parts := strings.Split(raw, ",")
-	name := parts[0]
-	if name == "" {
-		name = strings.ToLower(goName)
-	}
-
-	var maxSize uint32
-	if len(parts) >= 2 && parts[1] != "" {
-		v, err := strconv.ParseUint(parts[1], 10, 32)
-		if err != nil {
-			return "", 0, fmt.Errorf("invalid max_size %q: %w", parts[1], err)
-		}
-		maxSize = uint32(v)
-	}
-	return name, maxSize, nil
-}
-
 // tagValue extracts the value for the "mmap" key from a struct tag literal.
 func tagValue(tag *ast.BasicLit) string {
 	if tag == nil {
@@ -210,40 +186,6 @@ func tagValue(tag *ast.BasicLit) string {
 	return rest[:end]
 }
 
-// goTypeToFieldType maps a Go type string to a FieldType.
-func goTypeToFieldType(goType string) (mmapforge.FieldType, error) {
-	switch goType {
-	case "bool":
-		return mmapforge.FieldBool, nil
-	case "int8":
-		return mmapforge.FieldInt8, nil
-	case "uint8":
-		return mmapforge.FieldUint8, nil
-	case "int16":
-		return mmapforge.FieldInt16, nil
-	case "uint16":
-		return mmapforge.FieldUint16, nil
-	case "int32":
-		return mmapforge.FieldInt32, nil
-	case "uint32":
-		return mmapforge.FieldUint32, nil
-	case "int64":
-		return mmapforge.FieldInt64, nil
-	case "uint64":
-		return mmapforge.FieldUint64, nil
-	case "float32":
-		return mmapforge.FieldFloat32, nil
-	case "float64":
-		return mmapforge.FieldFloat64, nil
-	case "string":
-		return mmapforge.FieldString, nil
-	case "[]byte":
-		return mmapforge.FieldBytes, nil
-	default:
-		return 0, fmt.Errorf("unsupported type %q", goType)
-	}
-}
-
 // typeString converts an AST type expression to its string representation.
 func typeString(expr ast.Expr) string {
 	switch t := expr.(type) {
@@ -240,39 +240,6 @@ func TestParseVersionFromDirective(t *testing.T) {
 	}
 }
 
-func TestParseMmapTag(t *testing.T) {
-	cases := []struct {
-		raw     string
-		goName  string
-		wantN   string
-		wantMS  uint32
-		wantErr bool
-	}{
-		{"", "Foo", "foo", 0, false},
-		{"bar", "Foo", "bar", 0, false},
-		{",", "Foo", "foo", 0, false},
-		{",32", "Foo", "foo", 32, false},
-		{"myname,64", "Foo", "myname", 64, false},
-		{"name,128", "Foo", "name", 128, false},
-		{",notanum", "Foo", "", 0, true},
-		{"name,", "Foo", "name", 0, false},
-	}
-	for _, tc := range cases {
-		name, ms, err := parseMmapTag(tc.raw, tc.goName)
-		if (err != nil) != tc.wantErr {
-			t.Errorf("parseMmapTag(%q, %q) err=%v, wantErr=%v", tc.raw, tc.goName, err, tc.wantErr)
-			continue
-		}
-		if err != nil {
-			continue
-		}
-		if name != tc.wantN || ms != tc.wantMS {
-			t.Errorf("parseMmapTag(%q, %q) = (%q, %d), want (%q, %d)",
-				tc.raw, tc.goName, name, ms, tc.wantN, tc.wantMS)
-		}
-	}
-}
-
 func TestTagValue(t *testing.T) {
 	cases := []struct {
 		tag  *ast.BasicLit
@@ -293,41 +260,6 @@ func TestTagValue(t *testing.T) {
 	}
 }
 
-func TestGoTypeToFieldType(t *testing.T) {
-	valid := []struct {
-		goType string
-		want   mmapforge.FieldType
-	}{
-		{"bool", mmapforge.FieldBool},
-		{"int8", mmapforge.FieldInt8},
-		{"uint8", mmapforge.FieldUint8},
-		{"int16", mmapforge.FieldInt16},
-		{"uint16", mmapforge.FieldUint16},
-		{"int32", mmapforge.FieldInt32},
-		{"uint32", mmapforge.FieldUint32},
-		{"int64", mmapforge.FieldInt64},
-		{"uint64", mmapforge.FieldUint64},
-		{"float32", mmapforge.FieldFloat32},
-		{"float64", mmapforge.FieldFloat64},
-		{"string", mmapforge.FieldString},
-		{"[]byte", mmapforge.FieldBytes},
-	}
-	for _, tc := range valid {
-		got, err := goTypeToFieldType(tc.goType)
-		if err != nil {
-			t.Errorf("goTypeToFieldType(%q) error: %v", tc.goType, err)
-		}
-		if got != tc.want {
-			t.Errorf("goTypeToFieldType(%q) = %v, want %v", tc.goType, got, tc.want)
-		}
-	}
-
-	_, err := goTypeToFieldType("complex128")
-	if err == nil {
-		t.Error("expected error for unsupported type")
-	}
-}
-
 func TestTypeString(t *testing.T) {
 	fset := token.NewFileSet()
 	mustParseExpr := func(src string) ast.Expr {
@@ -0,0 +1,129 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/CreditWorthy/mmapforge/plugin"
+)
+
+// Mockable function
+var execCommandFunc = exec.Command
+
+// Plugin returns a Hook that runs the plugin executable at path once the
+// wrapped generator has written the package, and writes the files the
+// plugin returns into the target directory. The protocol is defined by
+// package plugin; the plugin's stderr is passed through.
+func Plugin(path string) Hook {
+	return func(next Generator) Generator {
+		return GenerateFunc(func(g *Graph) error {
+			if err := next.Generate(g); err != nil {
+				return err
+			}
+			return runPlugin(g, path)
+		})
+	}
+}
+
+// PluginRequest returns the plugin request describing g.
+func PluginRequest(g *Graph) *plugin.Request {
+	req := &plugin.Request{
+		Version:  plugin.Version,
+		Target:   g.Target,
+		Package:  g.PackageName(),
+		Header:   g.Header(),
+		BuildTag: g.BuildTag,
+		Features: make(map[string]bool, len(AllFeatures)),
+		Types:    make([]plugin.Type, 0, len(g.Nodes)),
+	}
+	for _, f := range AllFeatures {
+		req.Features[f.Name] = g.FeatureEnabled(f.Name)
+	}
+	for _, n := range g.Nodes {
+		t := plugin.Type{
+			Name:          n.Name,
+			SchemaVersion: n.SchemaVersion,
+			RecordSize:    n.RecordSize,
+			StoreName:     n.StoreName(),
+			RecordName:    n.RecordName(),
+			LayoutFunc:    n.LayoutFuncName(),
+			NewStoreFunc:  n.NewStoreFuncName(),
+			InMemoryFunc:  n.NewStoreInMemoryFuncName(),
+			OpenStoreFunc: n.OpenStoreFuncName(),
+			File:          n.FileName(),
+			Fields:        make([]plugin.Field, len(n.Fields)),
+		}
+		for i, f := range n.Fields {
+			t.Fields[i] = plugin.Field{
+				Name:    f.Name,
+				GoName:  f.GoName,
+				Type:    f.Type.String(),
+				GoType:  f.GoType(),
+				Offset:  f.Offset,
+				Size:    f.Size,
+				MaxSize: f.MaxSize,
+			}
+		}
+		req.Types = append(req.Types, t)
+	}
+	return req
+}
+
+func runPlugin(g *Graph, path string) error {
+	name := filepath.Base(path)
+	// A Request holds only strings, numbers, maps and slices; Marshal cannot fail.
+	in, _ := json.Marshal(PluginRequest(g))
+
+	var out bytes.Buffer
+	cmd := execCommandFunc(path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("mmapforge: plugin %s: %w", name, err)
+	}
+
+	var resp plugin.Response
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return fmt.Errorf("mmapforge: plugin %s: decode response: %w", name, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("mmapforge: plugin %s: %s", name, resp.Error)
+	}
+	generated := make(map[string]string)
+	for _, f := range g.outputFiles() {
+		generated[strings.ToLower(f.name)] = f.owner
+	}
+	for _, f := range resp.Files {
+		if !filepath.IsLocal(f.Name) {
+			return fmt.Errorf("mmapforge: plugin %s: file %q is outside the target directory", name, f.Name)
+		}
+		if owner, ok := generated[strings.ToLower(filepath.ToSlash(filepath.Clean(f.Name)))]; ok {
+			return fmt.Errorf("mmapforge: plugin %s: file %q would overwrite the file generated for %s", name, f.Name, owner)
+		}
+	}
+
+	for _, f := range resp.Files {
+		if err := writePluginFile(g.Target, f); err != nil {
+			return fmt.Errorf("mmapforge: plugin %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// writePluginFile writes f under target, running gofmt on Go files.
+func writePluginFile(target string, f plugin.File) error {
+	path := filepath.Join(target, f.Name)
+	if err := mkdirAllFunc(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	if strings.HasSuffix(f.Name, ".go") {
+		return writeFormattedFunc(path, []byte(f.Content))
+	}
+	return writeFileFunc(path, []byte(f.Content), 0644)
+}
@@ -0,0 +1,190 @@
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+	"github.com/CreditWorthy/mmapforge/plugin"
+)
+
+// TestPluginHelperProcess is not a real test: it is the plugin executed by
+// the tests below, selected with MMAPFORGE_PLUGIN_MODE.
+func TestPluginHelperProcess(t *testing.T) {
+	mode := os.Getenv("MMAPFORGE_PLUGIN_MODE")
+	if mode == "" {
+		return
+	}
+	var req plugin.Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		os.Exit(3)
+	}
+	switch mode {
+	case "ok":
+		fmt.Printf(`{"files":[{"name":"%s_gen.go","content":"package %s\nvar   X=%d"},{"name":"docs/%s.txt","content":"%s"}]}`,
+			strings.ToLower(req.Types[0].Name), req.Package, len(req.Types), req.Types[0].Name, req.Types[0].StoreName)
+	case "fail":
+		os.Exit(2)
+	case "garbage":
+		fmt.Print("not json")
+	case "error":
+		fmt.Print(`{"error":"unsupported field"}`)
+	case "escape":
+		fmt.Print(`{"files":[{"name":"ok.txt"},{"name":"../escape.go","content":"package x"}]}`)
+	case "store":
+		fmt.Print(`{"files":[{"name":"ok.txt"},{"name":"Tick_Store.go","content":"package x"}]}`)
+	case "registry":
+		fmt.Print(`{"files":[{"name":"ok.txt"},{"name":"./mmapforge_registry.go","content":"package x"}]}`)
+	case "lock":
+		fmt.Print(`{"files":[{"name":"ok.txt"},{"name":"mmapforge.lock"}]}`)
+	}
+	os.Exit(0)
+}
+
+// helperPlugin makes execCommandFunc run TestPluginHelperProcess in mode.
+func helperPlugin(t *testing.T, mode string) {
+	t.Helper()
+	orig := execCommandFunc
+	t.Cleanup(func() { execCommandFunc = orig })
+	execCommandFunc = func(string, ...string) *exec.Cmd {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestPluginHelperProcess$")
+		cmd.Env = append(os.Environ(), "MMAPFORGE_PLUGIN_MODE="+mode)
+		return cmd
+	}
+}
+
+func pluginGraph(t *testing.T) *Graph {
+	t.Helper()
+	g, err := NewGraph(&Config{
+		Target:   t.TempDir(),
+		Package:  "feeds",
+		BuildTag: "linux",
+		Types:    map[string]TypeConfig{"Tick": {Store: "TickTable"}},
+	}, []StructSchema{{
+		Name:          "Tick",
+		SchemaVersion: 3,
+		Fields: []mmapforge.FieldDef{
+			{Name: "price", GoName: "Price", Type: mmapforge.FieldFloat64},
+			{Name: "sym", GoName: "Sym", Type: mmapforge.FieldString, MaxSize: 8},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestPluginRequest(t *testing.T) {
+	req := PluginRequest(pluginGraph(t))
+	if req.Version != plugin.Version || req.Package != "feeds" || req.Header != DefaultHeader || req.BuildTag != "linux" {
+		t.Errorf("request = %+v", req)
+	}
+	if !req.Features["record"] {
+		t.Errorf("Features = %v, want record on", req.Features)
+	}
+	if len(req.Types) != 1 {
+		t.Fatalf("types = %+v", req.Types)
+	}
+	ty := req.Types[0]
+	if ty.Name != "Tick" || ty.SchemaVersion != 3 || ty.StoreName != "TickTable" || ty.NewStoreFunc != "NewTickTable" || ty.InMemoryFunc != "NewTickTableInMemory" ||
+		ty.OpenStoreFunc != "OpenTickTable" || ty.RecordName != "TickRecord" || ty.LayoutFunc != "TickLayout" || ty.File != "tick_store.go" {
+		t.Errorf("type = %+v", ty)
+	}
+	want := plugin.Field{Name: "sym", GoName: "Sym", Type: "string", GoType: "string", Offset: ty.Fields[1].Offset, Size: 12, MaxSize: 8}
+	if len(ty.Fields) != 2 || ty.Fields[1] != want || ty.RecordSize != 32 {
+		t.Errorf("fields = %+v, record size %d", ty.Fields, ty.RecordSize)
+	}
+
+	// The package falls back to the schema package when the config has none.
+	g := &Graph{Config: &Config{}, Nodes: []*Type{{Config: &Config{}, Name: "A", Package: "pkg"}}}
+	if got := PluginRequest(g).Package; got != "pkg" {
+		t.Errorf("Package = %q, want pkg", got)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	helperPlugin(t, "ok")
+	g := pluginGraph(t)
+	g.Hooks = []Hook{Plugin("/path/to/metrics")}
+	if err := g.Gen(); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := os.ReadFile(filepath.Join(g.Target, "tick_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != "package feeds\n\nvar X = 1\n" {
+		t.Errorf("tick_gen.go = %q, want gofmt'ed source", src)
+	}
+	txt, err := os.ReadFile(filepath.Join(g.Target, "docs", "Tick.txt"))
+	if err != nil || string(txt) != "TickTable" {
+		t.Errorf("docs/Tick.txt = %q, %v", txt, err)
+	}
+	if _, err := os.Stat(filepath.Join(g.Target, "tick_store.go")); err != nil {
+		t.Errorf("built-in templates should still run: %v", err)
+	}
+}
+
+func TestPlugin_Errors(t *testing.T) {
+	tests := []struct {
+		mode, want string
+	}{
+		{"fail", "plugin metrics: exit status 2"},
+		{"garbage", "plugin metrics: decode response"},
+		{"error", "plugin metrics: unsupported field"},
+		{"escape", `file "../escape.go" is outside the target directory`},
+		{"store", `file "Tick_Store.go" would overwrite the file generated for Tick`},
+		{"registry", `file "./mmapforge_registry.go" would overwrite the file generated for the registry template`},
+		{"lock", `file "mmapforge.lock" would overwrite the file generated for the lock file`},
+	}
+	for _, tt := range tests {
+		helperPlugin(t, tt.mode)
+		g := pluginGraph(t)
+		g.Hooks = []Hook{Plugin("/path/to/metrics")}
+		if err := g.Gen(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want containing %q", tt.mode, err, tt.want)
+		}
+		if _, err := os.Stat(filepath.Join(g.Target, "ok.txt")); err == nil {
+			t.Errorf("%s: no plugin file should be written on error", tt.mode)
+		}
+	}
+}
+
+func TestPlugin_NextError(t *testing.T) {
+	hook := Plugin("/path/to/metrics")
+	err := hook(GenerateFunc(func(*Graph) error { return errors.New("gen failed") })).Generate(pluginGraph(t))
+	if err == nil || err.Error() != "gen failed" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestPlugin_WriteErrors(t *testing.T) {
+	helperPlugin(t, "ok")
+	origMkdir, origFormatted, origWrite := mkdirAllFunc, writeFormattedFunc, writeFileFunc
+	defer func() { mkdirAllFunc, writeFormattedFunc, writeFileFunc = origMkdir, origFormatted, origWrite }()
+
+	g := pluginGraph(t)
+	mkdirAllFunc = func(string, os.FileMode) error { return errors.New("mkdir failed") }
+	if err := runPlugin(g, "metrics"); err == nil || !strings.Contains(err.Error(), "mkdir failed") {
+		t.Errorf("mkdir: err = %v", err)
+	}
+
+	mkdirAllFunc = origMkdir
+	writeFormattedFunc = func(string, []byte) error { return errors.New("format failed") }
+	if err := runPlugin(g, "metrics"); err == nil || !strings.Contains(err.Error(), "format failed") {
+		t.Errorf("go file: err = %v", err)
+	}
+
+	writeFormattedFunc = origFormatted
+	writeFileFunc = func(string, []byte, os.FileMode) error { return errors.New("write failed") }
+	if err := runPlugin(g, "metrics"); err == nil || !strings.Contains(err.Error(), "write failed") {
+		t.Errorf("other file: err = %v", err)
+	}
+}
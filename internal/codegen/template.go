@@ -20,6 +20,10 @@ var parseFilesFunc = func(inner *template.Template, filenames ...string) (*templ
 	return inner.ParseFiles(filenames...)
 }
 
+var parseGlobFunc = func(inner *template.Template, pattern string) (*template.Template, error) {
+	return inner.ParseGlob(pattern)
+}
+
 var addParseTreeFunc = func(inner *template.Template, name string, tree *parse.Tree) (*template.Template, error) {
 	return inner.AddParseTree(name, tree)
 }
@@ -87,6 +91,13 @@ func (t *Template) ParseFiles(filenames ...string) (*Template, error) {
 	return t, nil
 }
 
+func (t *Template) ParseGlob(pattern string) (*Template, error) {
+	if _, err := parseGlobFunc(t.Template, pattern); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
 func (t *Template) AddParseTree(name string, tree *parse.Tree) (*Template, error) {
 	if _, err := addParseTreeFunc(t.Template, name, tree); err != nil {
 		return nil, err
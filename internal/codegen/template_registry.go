@@ -1,7 +1,5 @@
 package codegen
 
-import "strings"
-
 type (
 	// TypeTemplate is executed once per Type node.
 	TypeTemplate struct {
@@ -21,18 +19,34 @@ type (
 // TypeTemplates is the list of per-type templates to execute.
 var TypeTemplates = []TypeTemplate{
 	{
-		Name: "store",
-		Format: func(t *Type) string {
-			return strings.ToLower(t.Name) + "_store.go"
-		},
+		Name:   "store",
+		Format: (*Type).FileName,
 	},
 	{
 		Name: "store_test",
-		Format: func(t *Type) string {
-			return strings.ToLower(t.Name) + "_store_test.go"
+		Cond: func(t *Type) bool {
+			return !t.Options().SkipTests
 		},
+		Format: (*Type).TestFileName,
 	},
 }
 
 // GraphTemplates is the list of graph-wide templates to execute.
-var GraphTemplates = []GraphTemplate{}
+var GraphTemplates = []GraphTemplate{
+	{
+		Name:   "registry",
+		Skip:   skipRegistry,
+		Format: "mmapforge_registry.go",
+	},
+	{
+		Name: "registry_test",
+		Skip: func(g *Graph) bool {
+			return skipRegistry(g) || g.SkipTests()
+		},
+		Format: "mmapforge_registry_test.go",
+	},
+}
+
+func skipRegistry(g *Graph) bool {
+	return len(g.Nodes) == 0 || !g.FeatureEnabled(FeatureRegistry.Name)
+}
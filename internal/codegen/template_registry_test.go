@@ -129,8 +129,36 @@ func TestGraphTemplate_Skip_False(t *testing.T) {
 	}
 }
 
-func TestGraphTemplates_Empty(t *testing.T) {
-	if len(GraphTemplates) != 0 {
-		t.Errorf("GraphTemplates should be empty, got %d", len(GraphTemplates))
+func TestGraphTemplates_Registry(t *testing.T) {
+	if len(GraphTemplates) != 2 {
+		t.Fatalf("GraphTemplates = %d, want registry and registry_test", len(GraphTemplates))
+	}
+	reg, regTest := GraphTemplates[0], GraphTemplates[1]
+	if reg.Name != "registry" || reg.Format != "mmapforge_registry.go" {
+		t.Errorf("registry template = %+v", reg)
+	}
+	if regTest.Name != "registry_test" || regTest.Format != "mmapforge_registry_test.go" {
+		t.Errorf("registry_test template = %+v", regTest)
+	}
+
+	node := &Type{Name: "Foo"}
+	tests := []struct {
+		name           string
+		g              *Graph
+		skip, skipTest bool
+	}{
+		{"no nodes", &Graph{Config: &Config{}}, true, true},
+		{"default", &Graph{Config: &Config{}, Nodes: []*Type{node}}, false, false},
+		{"disabled", &Graph{Config: &Config{Features: map[string]bool{"registry": false}}, Nodes: []*Type{node}}, true, true},
+		{"skip tests", &Graph{Config: &Config{Types: map[string]TypeConfig{"Foo": {SkipTests: true}}}, Nodes: []*Type{node}}, false, true},
+	}
+	for _, tt := range tests {
+		node.Config = tt.g.Config
+		if got := reg.Skip(tt.g); got != tt.skip {
+			t.Errorf("%s: registry Skip = %v, want %v", tt.name, got, tt.skip)
+		}
+		if got := regTest.Skip(tt.g); got != tt.skipTest {
+			t.Errorf("%s: registry_test Skip = %v, want %v", tt.name, got, tt.skipTest)
+		}
 	}
 }
@@ -261,3 +261,20 @@ func TestTemplate_AddParseTree_Error(t *testing.T) {
 		t.Error("AddParseTree should return nil on error")
 	}
 }
+
+func TestTemplate_ParseGlob(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.tmpl"), []byte(`{{ define "a" }}A{{ end }}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := NewTemplate("test").ParseGlob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Lookup("a") == nil {
+		t.Error("template a should be defined")
+	}
+	if _, err := NewTemplate("test").ParseGlob(filepath.Join(dir, "*.missing")); err == nil {
+		t.Error("ParseGlob should fail when nothing matches")
+	}
+}
@@ -42,8 +42,19 @@ func (t *Type) Label() string {
 	return strings.ToLower(t.Name)
 }
 
+// Options returns the per-type overrides from the config.
+func (t *Type) Options() TypeConfig {
+	if t.Config == nil {
+		return TypeConfig{}
+	}
+	return t.Config.Types[t.Name]
+}
+
 // StoreName returns the generated store struct name
 func (t *Type) StoreName() string {
+	if name := t.Options().Store; name != "" {
+		return name
+	}
 	return t.Name + "Store"
 }
 
@@ -52,6 +63,37 @@ func (t *Type) RecordName() string {
 	return t.Name + "Record"
 }
 
+// ViewName returns the generated view struct name
+func (t *Type) ViewName() string {
+	return t.Name + "View"
+}
+
+// UpdaterName returns the generated updater struct name
+func (t *Type) UpdaterName() string {
+	return t.Name + "Updater"
+}
+
+// FieldMaskName returns the generated field mask type name
+func (t *Type) FieldMaskName() string {
+	return t.Name + "FieldMask"
+}
+
+// FieldMaskConst returns the name of f's bit in the field mask
+func (t *Type) FieldMaskConst(f *Field) string {
+	return t.Name + "Field" + f.GoName
+}
+
+// AllFieldsConst returns the name of the field mask with every bit set
+func (t *Type) AllFieldsConst() string {
+	return t.Name + "AllFields"
+}
+
+// HasFieldMask reports if the type's fields fit in the uint64 field mask;
+// types with more than 64 fields get no mask and no SetFields.
+func (t *Type) HasFieldMask() bool {
+	return len(t.Fields) <= 64
+}
+
 // LayoutFuncName returns the name of the Layout() func
 func (t *Type) LayoutFuncName() string {
 	return t.Name + "Layout"
@@ -59,12 +101,96 @@ func (t *Type) LayoutFuncName() string {
 
 // NewStoreFuncName returns the name for CreateStore
 func (t *Type) NewStoreFuncName() string {
-	return "New" + t.Name + "Store"
+	return "New" + t.StoreName()
+}
+
+// NewStoreInMemoryFuncName returns the name for CreateStoreInMemory
+func (t *Type) NewStoreInMemoryFuncName() string {
+	return t.NewStoreFuncName() + "InMemory"
 }
 
 // OpenStoreFuncName returns the name for OpenStore
 func (t *Type) OpenStoreFuncName() string {
-	return "Open" + t.Name + "Store"
+	return "Open" + t.StoreName()
+}
+
+// FileName returns the name of the generated store file.
+func (t *Type) FileName() string {
+	if name := t.Options().File; name != "" {
+		return name
+	}
+	return strings.ToLower(t.Name) + "_store.go"
+}
+
+// TestFileName returns the name of the generated test file.
+func (t *Type) TestFileName() string {
+	if name := t.Options().TestFile; name != "" {
+		return name
+	}
+	return strings.TrimSuffix(t.FileName(), ".go") + "_test.go"
+}
+
+// GeneratedNames returns the package-level identifiers the store template
+// declares for this type.
+func (t *Type) GeneratedNames() []string {
+	names := []string{
+		t.StoreName(),
+		t.LayoutFuncName(),
+		t.NewStoreFuncName(),
+		t.NewStoreInMemoryFuncName(),
+		t.OpenStoreFuncName(),
+		t.UpdaterName(),
+		t.ViewName(),
+	}
+	if t.FeatureEnabled(FeatureRecord.Name) {
+		names = append(names, t.RecordName())
+		if t.HasFieldMask() {
+			names = append(names, t.FieldMaskName(), t.AllFieldsConst())
+			for _, f := range t.Fields {
+				if f.GoName != "" {
+					names = append(names, t.FieldMaskConst(f))
+				}
+			}
+		}
+	}
+	return names
+}
+
+// MethodNames returns the methods the store template declares on the store
+// type, beyond those of the embedded mmapforge.Store. Fields without a Go
+// name, which only hand-built schemas have, are left out.
+func (t *Type) MethodNames() []string {
+	var names []string
+	for _, f := range t.Fields {
+		if f.GoName == "" {
+			continue
+		}
+		names = append(names, f.GetterName(), f.GetterCtxName(), f.SetterName())
+		if f.IsAtomic() {
+			names = append(names, f.AtomicLoadName(), f.AtomicStoreName(), f.AtomicAddName(), f.AtomicCompareAndSwapName())
+		}
+	}
+	names = append(names, "Update", "View", "ViewCtx")
+	if t.FeatureEnabled(FeatureRecord.Name) {
+		names = append(names, "Get", "GetCtx", "GetInto", "GetIntoCtx", "Set", "TryUpdate")
+		if t.HasFieldMask() {
+			names = append(names, "SetFields")
+		}
+	}
+	return names
+}
+
+// SchemaHash returns the hash of the type's field layout, the same hash the
+// generated store writes into its file header.
+func (t *Type) SchemaHash() [32]byte {
+	layout := &mmapforge.RecordLayout{
+		Fields:     make([]mmapforge.FieldLayout, len(t.Fields)),
+		RecordSize: t.RecordSize,
+	}
+	for i, f := range t.Fields {
+		layout.Fields[i] = f.FieldLayout
+	}
+	return mmapforge.SchemaHash(layout.Descriptors())
 }
 
 // Receiver returns a short receiver variable name for store methods.
@@ -92,6 +218,16 @@ func (t *Type) HasBytesField() bool {
 	return false
 }
 
+// HasAtomicField reports if any field gets generated atomic methods.
+func (t *Type) HasAtomicField() bool {
+	for _, f := range t.Fields {
+		if f.IsAtomic() {
+			return true
+		}
+	}
+	return false
+}
+
 // HasVarLenField reports if any field is variable-length (string or bytes).
 func (t *Type) HasVarLenField() bool {
 	return t.HasStringField() || t.HasBytesField()
@@ -136,11 +272,65 @@ func (f *Field) GetterName() string {
 	return "Get" + f.GoName
 }
 
+// GetterCtxName returns the name for the context-aware getter method
+func (f *Field) GetterCtxName() string {
+	return f.GetterName() + "Ctx"
+}
+
 // SetterName returns the name for the setter method
 func (f *Field) SetterName() string {
 	return "Set" + f.GoName
 }
 
+// AtomicLoadName returns the name for the atomic load method
+func (f *Field) AtomicLoadName() string {
+	return "Load" + f.GoName
+}
+
+// AtomicStoreName returns the name for the atomic store method
+func (f *Field) AtomicStoreName() string {
+	return "Store" + f.GoName
+}
+
+// AtomicAddName returns the name for the atomic add method
+func (f *Field) AtomicAddName() string {
+	return "Add" + f.GoName
+}
+
+// AtomicCompareAndSwapName returns the name for the atomic compare-and-swap method
+func (f *Field) AtomicCompareAndSwapName() string {
+	return "CompareAndSwap" + f.GoName
+}
+
+// IsAtomic reports if the field is a 4- or 8-byte integer, which the
+// generated store can update with sync/atomic.
+func (f *Field) IsAtomic() bool {
+	switch f.Type {
+	case mmapforge.FieldInt32, mmapforge.FieldUint32,
+		mmapforge.FieldInt64, mmapforge.FieldUint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// AtomicSuffix returns the type suffix of the field's Store.Atomic*
+// methods, e.g. "Uint64" for AtomicAddUint64.
+func (f *Field) AtomicSuffix() string {
+	switch f.Type {
+	case mmapforge.FieldInt32:
+		return "Int32"
+	case mmapforge.FieldUint32:
+		return "Uint32"
+	case mmapforge.FieldInt64:
+		return "Int64"
+	case mmapforge.FieldUint64:
+		return "Uint64"
+	default:
+		return ""
+	}
+}
+
 // IsString reports if the field is a string.
 func (f *Field) IsString() bool {
 	return f.Type == mmapforge.FieldString
@@ -214,6 +404,40 @@ func (f *Field) ReadCall() string {
 	}
 }
 
+// ViewCall returns the mmapforge.RecordView accessor call for this field.
+func (f *Field) ViewCall() string {
+	switch f.Type {
+	case mmapforge.FieldBool:
+		return fmt.Sprintf("Bool(%d)", f.Offset)
+	case mmapforge.FieldInt8:
+		return fmt.Sprintf("Int8(%d)", f.Offset)
+	case mmapforge.FieldUint8:
+		return fmt.Sprintf("Uint8(%d)", f.Offset)
+	case mmapforge.FieldInt16:
+		return fmt.Sprintf("Int16(%d)", f.Offset)
+	case mmapforge.FieldUint16:
+		return fmt.Sprintf("Uint16(%d)", f.Offset)
+	case mmapforge.FieldInt32:
+		return fmt.Sprintf("Int32(%d)", f.Offset)
+	case mmapforge.FieldUint32:
+		return fmt.Sprintf("Uint32(%d)", f.Offset)
+	case mmapforge.FieldInt64:
+		return fmt.Sprintf("Int64(%d)", f.Offset)
+	case mmapforge.FieldUint64:
+		return fmt.Sprintf("Uint64(%d)", f.Offset)
+	case mmapforge.FieldFloat32:
+		return fmt.Sprintf("Float32(%d)", f.Offset)
+	case mmapforge.FieldFloat64:
+		return fmt.Sprintf("Float64(%d)", f.Offset)
+	case mmapforge.FieldString:
+		return fmt.Sprintf("String(%d, %d)", f.Offset, f.MaxSize)
+	case mmapforge.FieldBytes:
+		return fmt.Sprintf("Bytes(%d, %d)", f.Offset, f.MaxSize)
+	default:
+		return "Raw() // unsupported type"
+	}
+}
+
 // WriteCall returns the Store.Write* method call using "val" as the value arg.
 func (f *Field) WriteCall() string {
 	return f.writeCallWith("val")
@@ -224,6 +448,12 @@ func (f *Field) WriteCallRec() string {
 	return f.writeCallWith("rec." + f.GoName)
 }
 
+// CheckCallRec returns the Store.CheckWrite call validating "rec.<GoName>"
+// for a string or []byte field.
+func (f *Field) CheckCallRec() string {
+	return fmt.Sprintf("s.CheckWrite(idx, %d, len(rec.%s), %d)", f.Offset, f.GoName, f.MaxSize)
+}
+
 // TestValue returns a Go literal for a representative test value.
 func (f *Field) TestValue() string {
 	switch f.Type {
@@ -2,6 +2,8 @@ package codegen
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/CreditWorthy/mmapforge"
@@ -84,6 +86,13 @@ func TestType_NewStoreFuncName(t *testing.T) {
 	}
 }
 
+func TestType_NewStoreInMemoryFuncName(t *testing.T) {
+	ty := newType(&Config{}, "Player", nil)
+	if got := ty.NewStoreInMemoryFuncName(); got != "NewPlayerStoreInMemory" {
+		t.Errorf("NewStoreInMemoryFuncName() = %q, want %q", got, "NewPlayerStoreInMemory")
+	}
+}
+
 func TestType_OpenStoreFuncName(t *testing.T) {
 	ty := newType(&Config{}, "Player", nil)
 	if got := ty.OpenStoreFuncName(); got != "OpenPlayerStore" {
@@ -209,6 +218,46 @@ func TestField_SetterName(t *testing.T) {
 	}
 }
 
+func TestField_AtomicNames(t *testing.T) {
+	f := &Field{mmapforge.FieldLayout{FieldDef: mmapforge.FieldDef{GoName: "Hits"}}}
+	got := []string{f.AtomicLoadName(), f.AtomicStoreName(), f.AtomicAddName(), f.AtomicCompareAndSwapName()}
+	want := []string{"LoadHits", "StoreHits", "AddHits", "CompareAndSwapHits"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("atomic name %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestField_IsAtomic(t *testing.T) {
+	suffixes := map[mmapforge.FieldType]string{
+		mmapforge.FieldInt32:  "Int32",
+		mmapforge.FieldUint32: "Uint32",
+		mmapforge.FieldInt64:  "Int64",
+		mmapforge.FieldUint64: "Uint64",
+	}
+	for _, f := range allFieldTypes() {
+		want, ok := suffixes[f.Type]
+		if f.IsAtomic() != ok {
+			t.Errorf("IsAtomic() for %s = %v, want %v", f.Name, f.IsAtomic(), ok)
+		}
+		if got := f.AtomicSuffix(); got != want {
+			t.Errorf("AtomicSuffix() for %s = %q, want %q", f.Name, got, want)
+		}
+	}
+}
+
+func TestType_HasAtomicField(t *testing.T) {
+	i64 := &Field{mmapforge.FieldLayout{FieldDef: mmapforge.FieldDef{Type: mmapforge.FieldInt64}}}
+	f64 := &Field{mmapforge.FieldLayout{FieldDef: mmapforge.FieldDef{Type: mmapforge.FieldFloat64}}}
+	if newType(&Config{}, "X", []*Field{f64}).HasAtomicField() {
+		t.Error("HasAtomicField() should be false without integer fields")
+	}
+	if !newType(&Config{}, "X", []*Field{f64, i64}).HasAtomicField() {
+		t.Error("HasAtomicField() should be true with an int64 field")
+	}
+}
+
 func TestField_IsString(t *testing.T) {
 	yes := &Field{mmapforge.FieldLayout{FieldDef: mmapforge.FieldDef{Type: mmapforge.FieldString}}}
 	no := &Field{mmapforge.FieldLayout{FieldDef: mmapforge.FieldDef{Type: mmapforge.FieldInt32}}}
@@ -319,6 +368,23 @@ func TestField_ReadCall(t *testing.T) {
 	}
 }
 
+func TestField_ViewCall(t *testing.T) {
+	want := []string{
+		"Bool(8)", "Int8(9)", "Uint8(10)", "Int16(12)", "Uint16(14)",
+		"Int32(16)", "Uint32(20)", "Int64(24)", "Uint64(32)",
+		"Float32(40)", "Float64(48)", "String(56, 32)", "Bytes(92, 64)",
+	}
+	for i, f := range allFieldTypes() {
+		if got := f.ViewCall(); got != want[i] {
+			t.Errorf("ViewCall() for %s = %q, want %q", f.Name, got, want[i])
+		}
+	}
+	unknown := &Field{mmapforge.FieldLayout{FieldDef: mmapforge.FieldDef{Type: mmapforge.FieldType(99)}}}
+	if got := unknown.ViewCall(); got != "Raw() // unsupported type" {
+		t.Errorf("ViewCall() unknown = %q", got)
+	}
+}
+
 func TestField_WriteCall(t *testing.T) {
 	cases := []struct {
 		field *Field
@@ -401,3 +467,84 @@ func TestField_TestValue(t *testing.T) {
 		})
 	}
 }
+
+func TestType_Overrides(t *testing.T) {
+	c := &Config{Types: map[string]TypeConfig{
+		"Player": {Store: "PlayerTable", File: "players.go"},
+		"Item":   {TestFile: "item_gen_test.go", SkipTests: true},
+	}}
+	p := newType(c, "Player", nil)
+	if got := p.StoreName(); got != "PlayerTable" {
+		t.Errorf("StoreName() = %q", got)
+	}
+	if got := p.NewStoreFuncName(); got != "NewPlayerTable" {
+		t.Errorf("NewStoreFuncName() = %q", got)
+	}
+	if got := p.NewStoreInMemoryFuncName(); got != "NewPlayerTableInMemory" {
+		t.Errorf("NewStoreInMemoryFuncName() = %q", got)
+	}
+	if got := p.OpenStoreFuncName(); got != "OpenPlayerTable" {
+		t.Errorf("OpenStoreFuncName() = %q", got)
+	}
+	if got := p.FileName(); got != "players.go" {
+		t.Errorf("FileName() = %q", got)
+	}
+	if got := p.TestFileName(); got != "players_test.go" {
+		t.Errorf("TestFileName() = %q", got)
+	}
+
+	i := newType(c, "Item", nil)
+	if got := i.FileName(); got != "item_store.go" {
+		t.Errorf("FileName() = %q", got)
+	}
+	if got := i.TestFileName(); got != "item_gen_test.go" {
+		t.Errorf("TestFileName() = %q", got)
+	}
+	if !i.Options().SkipTests {
+		t.Error("Options().SkipTests should be set")
+	}
+	if (&Type{Name: "Item"}).Options().SkipTests {
+		t.Error("a type without config should have no overrides")
+	}
+}
+
+func TestType_TestBuildTag(t *testing.T) {
+	if got := newType(&Config{}, "Player", nil).TestBuildTag(); got != "unix" {
+		t.Errorf("TestBuildTag() = %q, want unix", got)
+	}
+	if got := newType(&Config{BuildTag: "linux || darwin"}, "Player", nil).TestBuildTag(); got != "unix && (linux || darwin)" {
+		t.Errorf("TestBuildTag() = %q", got)
+	}
+}
+
+func TestType_GeneratedNames(t *testing.T) {
+	ty := newType(&Config{}, "Player", nil)
+	if got := strings.Join(ty.GeneratedNames(), " "); got != "PlayerStore PlayerLayout NewPlayerStore NewPlayerStoreInMemory OpenPlayerStore PlayerUpdater PlayerView PlayerRecord PlayerFieldMask PlayerAllFields" {
+		t.Errorf("GeneratedNames() = %s", got)
+	}
+	ty = newType(&Config{}, "Player", []*Field{{mmapforge.FieldLayout{FieldDef: mmapforge.FieldDef{GoName: "Health"}}}})
+	if got := strings.Join(ty.GeneratedNames(), " "); !strings.HasSuffix(got, " PlayerFieldHealth") {
+		t.Errorf("GeneratedNames() = %s, want the PlayerFieldHealth mask bit", got)
+	}
+	ty = newType(&Config{Features: map[string]bool{"record": false}}, "Player", nil)
+	if got := strings.Join(ty.GeneratedNames(), " "); strings.Contains(got, "PlayerRecord") || strings.Contains(got, "PlayerFieldMask") {
+		t.Errorf("GeneratedNames() = %s, want no PlayerRecord or mask without the record feature", got)
+	}
+}
+
+func TestType_HasFieldMask(t *testing.T) {
+	fields := make([]*Field, 65)
+	for i := range fields {
+		fields[i] = &Field{mmapforge.FieldLayout{FieldDef: mmapforge.FieldDef{GoName: fmt.Sprintf("F%d", i), Type: mmapforge.FieldUint8}}}
+	}
+	if !newType(&Config{}, "Wide", fields[:64]).HasFieldMask() {
+		t.Error("HasFieldMask() should be true for 64 fields")
+	}
+	ty := newType(&Config{}, "Wide", fields)
+	if ty.HasFieldMask() {
+		t.Error("HasFieldMask() should be false for 65 fields")
+	}
+	if slices.Contains(ty.MethodNames(), "SetFields") || slices.Contains(ty.GeneratedNames(), "WideFieldMask") {
+		t.Error("types without a field mask should not get SetFields")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -169,6 +170,62 @@ func (t FieldType) String() string {
 	}
 }
 
+// ParseGoType maps a Go type, spelled as in source ("int32", "[]byte"), to
+// its FieldType. Named types such as `type Price float64` are rejected so
+// the generator and TypedStore accept exactly the same field types.
+func ParseGoType(goType string) (FieldType, error) {
+	switch goType {
+	case "bool":
+		return FieldBool, nil
+	case "int8":
+		return FieldInt8, nil
+	case "uint8":
+		return FieldUint8, nil
+	case "int16":
+		return FieldInt16, nil
+	case "uint16":
+		return FieldUint16, nil
+	case "int32":
+		return FieldInt32, nil
+	case "uint32":
+		return FieldUint32, nil
+	case "int64":
+		return FieldInt64, nil
+	case "uint64":
+		return FieldUint64, nil
+	case "float32":
+		return FieldFloat32, nil
+	case "float64":
+		return FieldFloat64, nil
+	case "string":
+		return FieldString, nil
+	case "[]byte":
+		return FieldBytes, nil
+	default:
+		return 0, fmt.Errorf("unsupported type %q", goType)
+	}
+}
+
+// ParseFieldTag decodes an `mmap:"name,max_size"` tag value. Returns the
+// mmap field name (defaults to lowercase goName) and max_size.
+func ParseFieldTag(raw string, goName string) (string, uint32, error) {
+	parts := strings.Split(raw, ",")
+	name := parts[0]
+	if name == "" {
+		name = strings.ToLower(goName)
+	}
+
+	var maxSize uint32
+	if len(parts) >= 2 && parts[1] != "" {
+		v, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid max_size %q: %w", parts[1], err)
+		}
+		maxSize = uint32(v)
+	}
+	return name, maxSize, nil
+}
+
 // FieldDescriptor is the canonical representation of a field for schema hashing.
 type FieldDescriptor struct {
 	Name string
@@ -189,6 +246,19 @@ func (r *RecordLayout) Descriptors() []FieldDescriptor {
 	return descs
 }
 
+// fieldAt returns the field starting at the given byte offset.
+func (r *RecordLayout) fieldAt(offset uint32) (FieldLayout, bool) {
+	if r == nil {
+		return FieldLayout{}, false
+	}
+	for _, f := range r.Fields {
+		if f.Offset == offset {
+			return f, true
+		}
+	}
+	return FieldLayout{}, false
+}
+
 // SchemaHash computes the SHA-256 of a canonical field descriptor string.
 // Fields are sorted by name so the hash is layout-order-independent
 func SchemaHash(fields []FieldDescriptor) [32]byte {
@@ -204,3 +274,29 @@ func SchemaHash(fields []FieldDescriptor) [32]byte {
 	}
 	return sha256.Sum256([]byte(strings.Join(parts, ",")))
 }
+
+// SchemaInfo describes one schema type of a generated package, as listed
+// by the generated Schemas function.
+type SchemaInfo struct {
+	// Name is the schema struct name.
+	Name string
+
+	// Version is the schema version from the mmapforge:schema directive.
+	Version uint32
+
+	// RecordSize is the size of one record in bytes.
+	RecordSize uint32
+
+	// Hash is the schema hash stored in the file header.
+	Hash [32]byte
+}
+
+// NewSchemaInfo describes the schema type name with the given layout.
+func NewSchemaInfo(name string, version uint32, layout *RecordLayout) SchemaInfo {
+	return SchemaInfo{
+		Name:       name,
+		Version:    version,
+		RecordSize: layout.RecordSize,
+		Hash:       SchemaHash(layout.Descriptors()),
+	}
+}
@@ -322,3 +322,112 @@ func TestRoundTrip_LayoutToHash(t *testing.T) {
 		t.Error("hash not stable across calls")
 	}
 }
+
+func TestFieldAt(t *testing.T) {
+	layout, err := ComputeLayout([]FieldDef{
+		{Name: "id", GoName: "ID", Type: FieldUint64},
+		{Name: "flag", GoName: "Flag", Type: FieldBool},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := layout.fieldAt(16)
+	if !ok || f.Name != "flag" {
+		t.Errorf("fieldAt(16) = %q, %v; want flag, true", f.Name, ok)
+	}
+	if _, ok := layout.fieldAt(12); ok {
+		t.Error("fieldAt(12) should not match any field")
+	}
+
+	var nilLayout *RecordLayout
+	if _, ok := nilLayout.fieldAt(8); ok {
+		t.Error("fieldAt on nil layout should not match")
+	}
+}
+
+func TestNewSchemaInfo(t *testing.T) {
+	layout, err := ComputeLayout([]FieldDef{
+		{Name: "id", GoName: "ID", Type: FieldUint64},
+		{Name: "name", GoName: "Name", Type: FieldString, MaxSize: 16},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := NewSchemaInfo("Player", 3, layout)
+	if info.Name != "Player" || info.Version != 3 || info.RecordSize != layout.RecordSize {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if info.Hash != SchemaHash(layout.Descriptors()) {
+		t.Error("Hash should match the header schema hash")
+	}
+}
+
+func TestParseFieldTag(t *testing.T) {
+	cases := []struct {
+		raw     string
+		goName  string
+		wantN   string
+		wantMS  uint32
+		wantErr bool
+	}{
+		{"", "Foo", "foo", 0, false},
+		{"bar", "Foo", "bar", 0, false},
+		{",", "Foo", "foo", 0, false},
+		{",32", "Foo", "foo", 32, false},
+		{"myname,64", "Foo", "myname", 64, false},
+		{"name,128", "Foo", "name", 128, false},
+		{",notanum", "Foo", "", 0, true},
+		{"name,", "Foo", "name", 0, false},
+	}
+	for _, tc := range cases {
+		name, ms, err := ParseFieldTag(tc.raw, tc.goName)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseFieldTag(%q, %q) err=%v, wantErr=%v", tc.raw, tc.goName, err, tc.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if name != tc.wantN || ms != tc.wantMS {
+			t.Errorf("ParseFieldTag(%q, %q) = (%q, %d), want (%q, %d)",
+				tc.raw, tc.goName, name, ms, tc.wantN, tc.wantMS)
+		}
+	}
+}
+
+func TestParseGoType(t *testing.T) {
+	valid := []struct {
+		goType string
+		want   FieldType
+	}{
+		{"bool", FieldBool},
+		{"int8", FieldInt8},
+		{"uint8", FieldUint8},
+		{"int16", FieldInt16},
+		{"uint16", FieldUint16},
+		{"int32", FieldInt32},
+		{"uint32", FieldUint32},
+		{"int64", FieldInt64},
+		{"uint64", FieldUint64},
+		{"float32", FieldFloat32},
+		{"float64", FieldFloat64},
+		{"string", FieldString},
+		{"[]byte", FieldBytes},
+	}
+	for _, tc := range valid {
+		got, err := ParseGoType(tc.goType)
+		if err != nil {
+			t.Errorf("ParseGoType(%q) error: %v", tc.goType, err)
+		}
+		if got != tc.want {
+			t.Errorf("ParseGoType(%q) = %v, want %v", tc.goType, got, tc.want)
+		}
+	}
+
+	for _, goType := range []string{"complex128", "int", "byte", "main.Price", "[]uint16"} {
+		if _, err := ParseGoType(goType); err == nil {
+			t.Errorf("ParseGoType(%q): expected error for unsupported type", goType)
+		}
+	}
+}
@@ -29,3 +29,10 @@ func funlock(f *os.File) error {
 	}
 	return nil
 }
+
+// processAlive reports whether a process with the given pid exists.
+// EPERM means it exists but belongs to someone else.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package mmapforge
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// sysMemfdCreate is the memfd_create(2) syscall number; package syscall
+// only defines SYS_MEMFD_CREATE for some architectures.
+var sysMemfdCreate = map[string]uintptr{
+	"386":      356,
+	"amd64":    319,
+	"arm":      385,
+	"arm64":    279,
+	"loong64":  279,
+	"mips":     4354,
+	"mipsle":   4354,
+	"mips64":   5314,
+	"mips64le": 5314,
+	"ppc64":    360,
+	"ppc64le":  360,
+	"riscv64":  279,
+	"s390x":    350,
+}[runtime.GOARCH]
+
+// MFD_CLOEXEC <- close the memfd on exec unless it is passed on explicitly
+const mfdCloexec = 0x1
+
+// memfdCreate <- creates an anonymous file that lives only in memory
+// it has no path; other processes reach it through the fd (SCM_RIGHTS, fork)
+func memfdCreate(name string) (int, error) {
+	if sysMemfdCreate == 0 {
+		return -1, syscall.ENOSYS
+	}
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return -1, err
+	}
+	fd, _, errno := syscall.Syscall(sysMemfdCreate, uintptr(unsafe.Pointer(p)), mfdCloexec, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// CreateStoreMemfd creates a store in an anonymous memory file
+// (memfd_create), for sharing records with forked workers or with other
+// processes over a Unix socket without a path on disk. Pass Store.Fd to
+// the other process and open it there with OpenStoreFromFd. name is only
+// used for debugging (/proc/self/fd) and need not be unique.
+//
+// The file exists until every process has closed it. WithOneWriter is not
+// supported.
+func CreateStoreMemfd(name string, layout *RecordLayout, schemaVersion uint32, opts ...StoreOption) (*Store, error) {
+	cfg := applyOptions(opts)
+	switch {
+	case cfg.readOnly:
+		return nil, fmt.Errorf("mmapforge: cannot create store in read-only mode")
+	case cfg.oneWriter:
+		return nil, fmt.Errorf("mmapforge: create memfd %s: WithOneWriter needs a store file on disk", name)
+	}
+
+	capacity, fileSize, err := createSize("memfd:"+name, layout, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := memfdCreate(name)
+	if err != nil {
+		return nil, fmt.Errorf("mmapforge: memfd_create %s: %w", name, err)
+	}
+	f := os.NewFile(uintptr(fd), "memfd:"+name)
+	return createStoreFile(f, layout, schemaVersion, capacity, fileSize, cfg)
+}
@@ -0,0 +1,130 @@
+//go:build linux
+
+package mmapforge
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCreateStoreMemfd(t *testing.T) {
+	s, err := CreateStoreMemfd("ticks", testLayout(), 2)
+	if err != nil {
+		t.Fatalf("CreateStoreMemfd: %v", err)
+	}
+	defer s.Close()
+
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteUint64(idx, 8, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	link, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(s.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(link, "/memfd:ticks") {
+		t.Errorf("fd links to %q, want a memfd", link)
+	}
+}
+
+// TestCreateStoreMemfd_PassFd hands the store to a "second process" the way
+// a real one would receive it: as an SCM_RIGHTS message on a Unix socket.
+func TestCreateStoreMemfd_PassFd(t *testing.T) {
+	s, err := CreateStoreMemfd("shared", testLayout(), 1)
+	if err != nil {
+		t.Fatalf("CreateStoreMemfd: %v", err)
+	}
+	defer s.Close()
+	idx, _ := s.Append()
+	if err := s.WriteFloat64(idx, 16, 2.5); err != nil {
+		t.Fatal(err)
+	}
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	if err := syscall.Sendmsg(fds[0], []byte{0}, syscall.UnixRights(s.Fd()), nil, 0); err != nil {
+		t.Fatalf("Sendmsg: %v", err)
+	}
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := syscall.Recvmsg(fds[1], make([]byte, 1), oob, 0)
+	if err != nil {
+		t.Fatalf("Recvmsg: %v", err)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	rights, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := OpenStoreFromFd(rights[0], testLayout())
+	if err != nil {
+		t.Fatalf("OpenStoreFromFd: %v", err)
+	}
+	defer s2.Close()
+
+	if v, err := s2.ReadFloat64(idx, 16); err != nil || v != 2.5 {
+		t.Errorf("ReadFloat64 = %v, %v", v, err)
+	}
+	if err := s2.WriteFloat64(idx, 16, 3.5); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.ReadFloat64(idx, 16); v != 3.5 {
+		t.Errorf("write through the passed fd not visible: %v", v)
+	}
+}
+
+func TestCreateStoreMemfd_Errors(t *testing.T) {
+	if _, err := CreateStoreMemfd("x", testLayout(), 1, WithReadOnly()); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("read-only: err = %v", err)
+	}
+	if _, err := CreateStoreMemfd("x", testLayout(), 1, WithOneWriter()); err == nil || !strings.Contains(err.Error(), "WithOneWriter needs a store file on disk") {
+		t.Errorf("one writer: err = %v", err)
+	}
+	if _, err := CreateStoreMemfd("x", testLayout(), 1, WithCapacity(int(^uint(0)>>1))); err == nil || !strings.Contains(err.Error(), "memfd:x: capacity") {
+		t.Errorf("capacity: err = %v", err)
+	}
+	if _, err := CreateStoreMemfd("bad\x00name", testLayout(), 1); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("NUL in name: err = %v, want EINVAL", err)
+	}
+
+	saved := saveFuncs()
+	defer restoreAllFuncs(saved)
+	mmapFixedFunc = func(uintptr, int, *os.File, bool, int, int) error { return syscall.ENOMEM }
+	if _, err := CreateStoreMemfd("x", testLayout(), 1); err == nil || !strings.Contains(err.Error(), "map memfd:x") {
+		t.Errorf("map: err = %v", err)
+	}
+}
+
+func TestMemfdCreate_Unsupported(t *testing.T) {
+	orig := sysMemfdCreate
+	defer func() { sysMemfdCreate = orig }()
+	sysMemfdCreate = 0
+	if _, err := CreateStoreMemfd("x", testLayout(), 1); !errors.Is(err, syscall.ENOSYS) {
+		t.Errorf("err = %v, want ENOSYS", err)
+	}
+}
+
+func TestMemfdCreate_SyscallFails(t *testing.T) {
+	orig := sysMemfdCreate
+	defer func() { sysMemfdCreate = orig }()
+	sysMemfdCreate = ^uintptr(0)
+	if _, err := memfdCreate("x"); err == nil {
+		t.Error("expected error from an invalid syscall number")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix && !linux
+
+package mmapforge
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CreateStoreMemfd creates a store in an anonymous memory file. memfd is
+// Linux only; elsewhere it returns an error wrapping errors.ErrUnsupported.
+func CreateStoreMemfd(name string, layout *RecordLayout, schemaVersion uint32, opts ...StoreOption) (*Store, error) {
+	return nil, fmt.Errorf("mmapforge: create memfd %s: %w", name, errors.ErrUnsupported)
+}
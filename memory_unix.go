@@ -0,0 +1,138 @@
+//go:build unix
+
+package mmapforge
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// functions can be overridden for testing
+var mprotectFunc = mprotectAt
+
+// MemoryRegion is anonymous, process-private memory with a stable base
+// address. Like Region it reserves a virtual address range with PROT_NONE
+// up front; instead of mapping a file over it, Grow makes more of the
+// reservation readable and writable. Pages are zero-filled on first touch
+// and nothing is ever written to disk.
+//
+// Safe for concurrent reads after MapMemory returns.
+type MemoryRegion struct {
+	base  uintptr
+	maxVA int
+	size  atomic.Int64
+}
+
+// MapMemory reserves reserveVA bytes of address space (DefaultMaxVA if 0)
+// and makes the first size bytes usable.
+//
+// Caller must call Close when done.
+func MapMemory(size int, reserveVA ...int) (*MemoryRegion, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("mmapforge: map memory: invalid size %d", size)
+	}
+
+	reserveSize := DefaultMaxVA
+	if len(reserveVA) > 0 && reserveVA[0] > 0 {
+		reserveSize = reserveVA[0]
+	}
+	reserveSize = pageAlign(max(reserveSize, size))
+
+	reserved, err := syscall.Mmap(-1, 0, reserveSize, syscall.PROT_NONE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return nil, fmt.Errorf("mmapforge: reserve %d bytes VA: %w", reserveSize, err)
+	}
+
+	r := &MemoryRegion{
+		base:  uintptr(unsafe.Pointer(&reserved[0])),
+		maxVA: reserveSize,
+	}
+	if err := r.Grow(size); err != nil {
+		_ = munmapAt(r.base, r.maxVA)
+		return nil, err
+	}
+	runtime.SetFinalizer(r, (*MemoryRegion).Close)
+	return r, nil
+}
+
+// mprotectAt <- changes the protection of [addr, addr+length)
+// PROT_NONE pages of the reservation become usable once they are PROT_READ|PROT_WRITE
+func mprotectAt(addr uintptr, length int, prot int) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_MPROTECT,
+		addr,
+		uintptr(length),
+		uintptr(prot),
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Base returns the fixed start address of the memory.
+func (r *MemoryRegion) Base() uintptr {
+	return r.base
+}
+
+// Name returns "memory"; there is no file behind the region.
+func (r *MemoryRegion) Name() string {
+	return "memory"
+}
+
+// Mapped returns the number of usable bytes.
+func (r *MemoryRegion) Mapped() int {
+	return int(r.size.Load())
+}
+
+// Slice returns the byte range [off, off+n) from the stable base.
+func (r *MemoryRegion) Slice(offset, n int) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(r.base+uintptr(offset))), n)
+}
+
+// Grow makes at least minSize bytes (page-aligned) usable. No-op if
+// already large enough. Existing contents and slices are unaffected.
+//
+// Must be externally serialized (Store.appendMu).
+func (r *MemoryRegion) Grow(minSize int) error {
+	cur := int(r.size.Load())
+	if minSize <= cur {
+		return nil
+	}
+
+	aligned := pageAlign(minSize)
+	if aligned > r.maxVA {
+		return fmt.Errorf("mmapforge: grow %d exceeds max VA reservation %d", aligned, r.maxVA)
+	}
+	if err := mprotectFunc(r.base+uintptr(cur), aligned-cur, syscall.PROT_READ|syscall.PROT_WRITE); err != nil {
+		return fmt.Errorf("mmapforge: grow mprotect: %w", err)
+	}
+	r.size.Store(int64(aligned))
+	return nil
+}
+
+// Sync is a no-op: anonymous memory has no stable storage to flush to.
+func (r *MemoryRegion) Sync() error {
+	if r.maxVA == 0 {
+		return fmt.Errorf("mmapforge: sync: %w", ErrClosed)
+	}
+	return nil
+}
+
+// Close releases the reservation. Idempotent.
+func (r *MemoryRegion) Close() error {
+	runtime.SetFinalizer(r, nil)
+	if r.maxVA == 0 {
+		return nil
+	}
+	err := munmapAt(r.base, r.maxVA)
+	r.size.Store(0)
+	r.maxVA = 0
+	if err != nil {
+		return fmt.Errorf("mmapforge: close: %w", err)
+	}
+	return nil
+}
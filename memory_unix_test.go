@@ -0,0 +1,98 @@
+//go:build unix
+
+package mmapforge
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestMapMemory(t *testing.T) {
+	r, err := MapMemory(100, pageSize*4)
+	if err != nil {
+		t.Fatalf("MapMemory: %v", err)
+	}
+	defer r.Close()
+
+	if r.Mapped() != pageSize {
+		t.Errorf("Mapped = %d, want %d", r.Mapped(), pageSize)
+	}
+	if r.Name() != "memory" {
+		t.Errorf("Name = %q", r.Name())
+	}
+
+	base := r.Base()
+	r.Slice(0, 8)[7] = 0xab
+	if err := r.Grow(pageSize * 3); err != nil {
+		t.Fatalf("Grow: %v", err)
+	}
+	if r.Base() != base || r.Mapped() != pageSize*3 {
+		t.Errorf("after Grow: base %#x (was %#x), mapped %d", r.Base(), base, r.Mapped())
+	}
+	if r.Slice(0, 8)[7] != 0xab {
+		t.Error("Grow lost existing contents")
+	}
+	last := r.Slice(pageSize*3-1, 1)
+	if last[0] != 0 {
+		t.Errorf("new page not zeroed: %#x", last[0])
+	}
+	last[0] = 1
+
+	if err := r.Grow(pageSize); err != nil {
+		t.Errorf("shrinking Grow should be a no-op: %v", err)
+	}
+	if err := r.Grow(pageSize * 5); err == nil || !strings.Contains(err.Error(), "exceeds max VA reservation") {
+		t.Errorf("Grow past reservation: err = %v", err)
+	}
+	if err := r.Sync(); err != nil {
+		t.Errorf("Sync: %v", err)
+	}
+}
+
+func TestMapMemory_InvalidSize(t *testing.T) {
+	if _, err := MapMemory(0); err == nil || !strings.Contains(err.Error(), "invalid size 0") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestMapMemory_ReserveClampedToSize(t *testing.T) {
+	r, err := MapMemory(pageSize*2, pageSize)
+	if err != nil {
+		t.Fatalf("MapMemory: %v", err)
+	}
+	defer r.Close()
+	if r.maxVA != pageSize*2 {
+		t.Errorf("maxVA = %d, want %d", r.maxVA, pageSize*2)
+	}
+}
+
+func TestMapMemory_MprotectFails(t *testing.T) {
+	orig := mprotectFunc
+	defer func() { mprotectFunc = orig }()
+	mprotectFunc = func(uintptr, int, int) error { return syscall.ENOMEM }
+
+	if _, err := MapMemory(pageSize); !errors.Is(err, syscall.ENOMEM) {
+		t.Errorf("err = %v, want ENOMEM", err)
+	}
+}
+
+func TestMemoryRegion_Close(t *testing.T) {
+	r, err := MapMemory(pageSize)
+	if err != nil {
+		t.Fatalf("MapMemory: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if err := r.Sync(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Sync after Close: err = %v, want ErrClosed", err)
+	}
+	if r.Mapped() != 0 {
+		t.Errorf("Mapped after Close = %d", r.Mapped())
+	}
+}
@@ -0,0 +1,166 @@
+// Package metrics exports mmapforge.Store.Stats as expvar variables or in
+// the Prometheus text exposition format, without pulling in a client
+// library:
+//
+//	e := metrics.New()
+//	e.Add("ticks", ticks.Store)
+//	expvar.Publish("mmapforge", e.Var())
+//	http.Handle("/metrics", e)
+//
+// Scraping a store while it is closed is safe; once closed it reports
+// zero sizes until it is removed.
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+// ContentType is the Content-Type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter holds a named set of stores.
+type Exporter struct {
+	mu     sync.Mutex
+	stores map[string]*mmapforge.Store
+}
+
+// New returns an empty Exporter.
+func New() *Exporter {
+	return &Exporter{stores: make(map[string]*mmapforge.Store)}
+}
+
+// Add exports s under name, replacing any store already added under it.
+func (e *Exporter) Add(name string, s *mmapforge.Store) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stores[name] = s
+}
+
+// Remove stops exporting the store added under name.
+func (e *Exporter) Remove(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.stores, name)
+}
+
+// Snapshot returns the stats of every store, keyed by name.
+func (e *Exporter) Snapshot() map[string]mmapforge.StoreStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make(map[string]mmapforge.StoreStats, len(e.stores))
+	for name, s := range e.stores {
+		out[name] = s.Stats()
+	}
+	return out
+}
+
+// Var returns an expvar.Var that renders Snapshot as JSON, for
+// expvar.Publish. Durations are in seconds and LastSyncErr is a string.
+func (e *Exporter) Var() expvar.Var {
+	return expvar.Func(func() any {
+		snap := e.Snapshot()
+		out := make(map[string]map[string]any, len(snap))
+		for name, st := range snap {
+			m := make(map[string]any, len(metricDefs)+1)
+			for _, d := range metricDefs {
+				m[d.expvar] = d.value(st)
+			}
+			if st.LastSyncErr != nil {
+				m["last_sync_error"] = st.LastSyncErr.Error()
+			}
+			out[name] = m
+		}
+		return out
+	})
+}
+
+// ServeHTTP writes the Prometheus text format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_ = e.WritePrometheus(w)
+}
+
+// WritePrometheus writes every store's stats to w in the Prometheus text
+// format, one series per store labelled store="<name>".
+func (e *Exporter) WritePrometheus(w io.Writer) error {
+	snap := e.Snapshot()
+	names := make([]string, 0, len(snap))
+	for name := range snap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, d := range metricDefs {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, d.kind)
+		for _, name := range names {
+			fmt.Fprintf(bw, "%s{store=\"%s\"} %v\n", d.name, escapeLabel(name), d.value(snap[name]))
+		}
+	}
+	return bw.Flush()
+}
+
+type metricDef struct {
+	name, expvar, kind, help string
+	value                    func(mmapforge.StoreStats) float64
+}
+
+var metricDefs = []metricDef{
+	{"mmapforge_records", "records", "gauge", "Records in the store.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.Records) }},
+	{"mmapforge_capacity_records", "capacity", "gauge", "Records that fit in the current mapping.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.Capacity) }},
+	{"mmapforge_mapped_bytes", "mapped_bytes", "gauge", "Bytes currently mapped.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.MappedBytes) }},
+	{"mmapforge_reserved_bytes", "reserved_bytes", "gauge", "Size of the virtual address reservation.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.ReservedBytes) }},
+	{"mmapforge_reserve_remaining_bytes", "reserve_remaining_bytes", "gauge", "Bytes the store can still grow before the reservation is exhausted.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.ReserveRemaining) }},
+	{"mmapforge_grows_total", "grows", "counter", "Capacity doublings since open.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.Grows) }},
+	{"mmapforge_last_grow_seconds", "last_grow_seconds", "gauge", "Duration of the most recent grow.",
+		func(s mmapforge.StoreStats) float64 { return s.LastGrowDuration.Seconds() }},
+	{"mmapforge_seqlock_retries_total", "seqlock_retries", "counter", "Reads retried because a writer got in the way.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.SeqlockRetries) }},
+	{"mmapforge_seqlocks_recovered", "seqlocks_recovered", "gauge", "Stuck seqlocks reset when the store was opened.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.RecoveredSeqlocks) }},
+	{"mmapforge_dirty_pages", "dirty_pages", "gauge", "Pages waiting for SyncDirty.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.DirtyPages) }},
+	{"mmapforge_syncs_total", "syncs", "counter", "Syncs since open.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.Syncs) }},
+	{"mmapforge_sync_errors_total", "sync_errors", "counter", "Syncs that failed.",
+		func(s mmapforge.StoreStats) float64 { return float64(s.SyncErrors) }},
+	{"mmapforge_last_sync_timestamp_seconds", "last_sync_unix", "gauge", "Unix time the most recent sync started, 0 if none has.",
+		func(s mmapforge.StoreStats) float64 {
+			if s.LastSync.IsZero() {
+				return 0
+			}
+			return float64(s.LastSync.UnixNano()) / 1e9
+		}},
+	{"mmapforge_last_sync_duration_seconds", "last_sync_seconds", "gauge", "Duration of the most recent sync.",
+		func(s mmapforge.StoreStats) float64 { return s.LastSyncDuration.Seconds() }},
+	{"mmapforge_last_sync_failed", "last_sync_failed", "gauge", "1 if the most recent sync returned an error.",
+		func(s mmapforge.StoreStats) float64 {
+			if s.LastSyncErr != nil {
+				return 1
+			}
+			return 0
+		}},
+	{"mmapforge_auto_sync_interval_seconds", "auto_sync_interval_seconds", "gauge", "WithAutoSync interval, 0 if off.",
+		func(s mmapforge.StoreStats) float64 { return s.AutoSyncInterval.Seconds() }},
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
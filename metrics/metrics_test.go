@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CreditWorthy/mmapforge"
+)
+
+var errWriteFailed = errors.New("write failed")
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errWriteFailed }
+
+func newStore(t *testing.T) *mmapforge.Store {
+	t.Helper()
+	layout, err := mmapforge.ComputeLayout([]mmapforge.FieldDef{{Name: "id", Type: mmapforge.FieldUint64}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := mmapforge.CreateStore(filepath.Join(t.TempDir(), "s.mmf"), layout, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	for range 3 {
+		if _, err := s.Append(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestWritePrometheus(t *testing.T) {
+	e := New()
+	e.Add(`ti"cks`, newStore(t))
+	e.Add("other", newStore(t))
+
+	var b strings.Builder
+	if err := e.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE mmapforge_records gauge\n",
+		`mmapforge_records{store="ti\"cks"} 3` + "\n",
+		`mmapforge_records{store="other"} 3` + "\n",
+		"# TYPE mmapforge_syncs_total counter\n",
+		`mmapforge_syncs_total{store="other"} 1` + "\n",
+		`mmapforge_last_sync_failed{store="other"} 0` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, `store="other"`) > strings.Index(out, `store="ti\"cks"`) {
+		t.Error("stores not sorted by name")
+	}
+
+	e.Remove("other")
+	b.Reset()
+	_ = e.WritePrometheus(&b)
+	if strings.Contains(b.String(), `store="other"`) {
+		t.Error("removed store still exported")
+	}
+}
+
+func TestWritePrometheus_WriteError(t *testing.T) {
+	e := New()
+	e.Add("s", newStore(t))
+	if err := e.WritePrometheus(failWriter{}); !errors.Is(err, errWriteFailed) {
+		t.Errorf("err = %v, want errWriteFailed", err)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	e := New()
+	e.Add("s", newStore(t))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `mmapforge_reserve_remaining_bytes{store="s"}`) {
+		t.Errorf("body:\n%s", rec.Body.String())
+	}
+}
+
+func TestVar(t *testing.T) {
+	e := New()
+	e.Add("s", newStore(t))
+
+	var got map[string]map[string]any
+	if err := json.Unmarshal([]byte(e.Var().String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["s"]["records"] != 3.0 || got["s"]["syncs"] != 1.0 {
+		t.Errorf("expvar = %v", got)
+	}
+	if _, ok := got["s"]["last_sync_error"]; ok {
+		t.Error("last_sync_error set without a failed sync")
+	}
+}
+
+func TestLastSyncValues(t *testing.T) {
+	var zero mmapforge.StoreStats
+	failed := mmapforge.StoreStats{LastSyncErr: errWriteFailed}
+	for _, d := range metricDefs {
+		switch d.name {
+		case "mmapforge_last_sync_timestamp_seconds":
+			if v := d.value(zero); v != 0 {
+				t.Errorf("timestamp with no sync = %v", v)
+			}
+		case "mmapforge_last_sync_failed":
+			if v := d.value(failed); v != 1 {
+				t.Errorf("last_sync_failed = %v", v)
+			}
+		}
+	}
+}
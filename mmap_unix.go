@@ -26,6 +26,9 @@ const DefaultMaxVA = 1 << 30
 // functions can be overridden for testing
 var mmapFixedFunc = mmapFixed
 var madviseFunc = madviseAt
+var madviseHintFunc = madvise
+var mlockFunc = mlockAt
+var residentFunc = resident
 var regionFinalizerFunc = regionFinalizer
 var mmapSyscall = func(addr, length, prot, flags, fd, offset uintptr) (uintptr, error) {
 	r, _, errno := syscall.Syscall6(syscall.SYS_MMAP, addr, length, prot, flags, fd, offset)
@@ -68,14 +71,69 @@ const (
 
 	// Random <- we jump around; kernel skips prefetch, keeps more pages cached instead
 	Random
+
+	// Normal <- no hint; kernel default readahead
+	Normal
+
+	// WillNeed <- we are about to touch this; kernel starts reading it in now
+	WillNeed
+
+	// DontNeed <- cold range; the pages are dropped from our mapping and the kernel
+	// is free to evict them from the page cache. Data is not lost: the next touch
+	// reads it back from the file. Only valid for ranges (Region.Advise), never as
+	// the access pattern of a whole mapping.
+	DontNeed
 )
 
+// MapHints are optional kernel hints for a Region, for latency-critical
+// mappings that should not take a page fault on first touch. Each hint is
+// best effort: if the kernel refuses one, Map still succeeds and
+// Region.Hints reports which ones took effect, as far as they can be
+// checked.
+type MapHints struct {
+	// Prefault populates the page tables up front: MAP_POPULATE on Linux,
+	// an madvise(MADV_WILLNEED) sweep elsewhere. It is reported as honoured
+	// only if mincore(2) then finds every page resident.
+	Prefault bool
+
+	// HugePages asks for transparent huge pages (MADV_HUGEPAGE, Linux
+	// only), advising the whole VA reservation and every file range mapped
+	// into it. It is reported as honoured when the kernel accepts the
+	// advice, which does not mean it uses huge pages: most kernels back
+	// only shared memory (memfd, tmpfs) with them, not files on disk.
+	HugePages bool
+
+	// Mlock pins the mapping in RAM with mlock(2). Subject to
+	// RLIMIT_MEMLOCK.
+	Mlock bool
+}
+
+// bits <- packs the hints into one word so Region can publish them atomically
+func (h MapHints) bits() uint32 {
+	var b uint32
+	if h.Prefault {
+		b |= 1
+	}
+	if h.HugePages {
+		b |= 2
+	}
+	if h.Mlock {
+		b |= 4
+	}
+	return b
+}
+
+func hintsFromBits(b uint32) MapHints {
+	return MapHints{Prefault: b&1 != 0, HugePages: b&2 != 0, Mlock: b&4 != 0}
+}
+
 // Region is a page-aligned, memory-mapped view of a file with a stable
 // base address. A large virtual address range is reserved up front with
 // PROT_NONE. The file is mapped over the start of that range using
-// MAP_FIXED. On Grow the file is extended and remapped at the same
-// base address, so pointers and slices obtained from Slice remain valid
-// as long as they fall within the previously mapped size.
+// MAP_FIXED. On Grow the file is extended and its new tail is mapped
+// right after the current mapping, so pointers and slices obtained from
+// Slice remain valid as long as they fall within the previously mapped
+// size.
 //
 // Owns the underlying *os.File. Safe for concurrent reads after Map returns.
 type Region struct {
@@ -85,6 +143,8 @@ type Region struct {
 	size      atomic.Int64
 	access    AccessPattern
 	writeable bool
+	hints     MapHints
+	honoured  atomic.Uint32
 }
 
 // Map opens a memory-mapped view of f starting at offset 0.
@@ -98,9 +158,18 @@ type Region struct {
 //
 // Caller must call Close when done.
 func Map(f *os.File, size int, writable bool, access AccessPattern, reserveVA ...int) (*Region, error) {
+	return MapWithHints(f, size, writable, access, MapHints{}, reserveVA...)
+}
+
+// MapWithHints is Map with kernel hints applied to the mapping, and to
+// the pages each Grow adds.
+func MapWithHints(f *os.File, size int, writable bool, access AccessPattern, hints MapHints, reserveVA ...int) (*Region, error) {
 	if size <= 0 {
 		return nil, fmt.Errorf("mmapforge: map: invalid size %d", size)
 	}
+	if access == DontNeed {
+		return nil, fmt.Errorf("mmapforge: map: %s is not an access pattern for a whole mapping", access)
+	}
 
 	reserveSize := DefaultMaxVA
 	if len(reserveVA) > 0 && reserveVA[0] > 0 {
@@ -123,6 +192,12 @@ func Map(f *os.File, size int, writable bool, access AccessPattern, reserveVA ..
 	// unsafe.Pointer() <- required to obtain the base address from the anonymous mapping reservation
 	base := uintptr(unsafe.Pointer(&reserved[0]))
 
+	// huge pages are advised on the reservation as a whole; each file range mapped over it
+	// replaces that part of the reservation and is advised again in applyHints
+	if hints.HugePages && madvHugepage >= 0 {
+		_ = madviseHintFunc(base, reserveSize, madvHugepage)
+	}
+
 	info, err := f.Stat()
 	if err != nil {
 		munerr := syscall.Munmap(reserved)
@@ -143,7 +218,7 @@ func Map(f *os.File, size int, writable bool, access AccessPattern, reserveVA ..
 		}
 	}
 
-	if fixmaperr := mmapFixedFunc(base, size, f, writable); fixmaperr != nil {
+	if fixmaperr := mmapFixedFunc(base, size, f, writable, hints.mmapFlags(), 0); fixmaperr != nil {
 		munerror := syscall.Munmap(reserved)
 		return nil, errors.Join(
 			fmt.Errorf("mmapforge: mmap: %w", fixmaperr),
@@ -165,9 +240,11 @@ func Map(f *os.File, size int, writable bool, access AccessPattern, reserveVA ..
 		file:      f,
 		writeable: writable,
 		access:    access,
+		hints:     hints,
 	}
 
 	r.size.Store(int64(size))
+	r.applyHints(0, size)
 	rp := &r
 	runtime.SetFinalizer(rp, regionFinalizerFunc)
 	return rp, nil
@@ -188,7 +265,10 @@ func Map(f *os.File, size int, writable bool, access AccessPattern, reserveVA ..
 //
 // we verify the returned address matches what we asked for;
 // if it doesnt something went seriously wrong
-func mmapFixed(addr uintptr, length int, f *os.File, writable bool) error {
+//
+// flags  <- extra mmap flags, e.g. MAP_POPULATE for MapHints.Prefault
+// offset <- file offset mapped at addr; page-aligned, Grow maps only the new tail
+func mmapFixed(addr uintptr, length int, f *os.File, writable bool, flags int, offset int) error {
 	prot := syscall.PROT_READ
 	if writable {
 		prot |= syscall.PROT_WRITE
@@ -198,9 +278,9 @@ func mmapFixed(addr uintptr, length int, f *os.File, writable bool) error {
 		addr,
 		uintptr(length),
 		uintptr(prot),
-		uintptr(syscall.MAP_SHARED|syscall.MAP_FIXED),
+		uintptr(syscall.MAP_SHARED|syscall.MAP_FIXED|flags),
 		f.Fd(),
-		0,
+		uintptr(offset),
 	)
 	if err != nil {
 		return err
@@ -232,29 +312,155 @@ func munmapAt(addr uintptr, length int) error {
 // this is just a hint; kernel can ignore it <- if it returns ENOSYS (not implemented)
 // we swallow the error instead of failing the whole Map call
 func madviseAt(addr uintptr, length int, advise int) error {
+	if err := madvise(addr, length, advise); err != nil && err != syscall.ENOSYS {
+		return err
+	}
+	return nil
+}
+
+// madvise <- the raw madvise(2) call; unlike madviseAt every failure is reported
+// so MapHints can tell whether the kernel took the hint
+func madvise(addr uintptr, length int, advise int) error {
 	_, _, errno := syscall.Syscall(
 		syscall.SYS_MADVISE,
 		addr,
 		uintptr(length),
 		uintptr(advise),
 	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
 
-	if errno != 0 && errno != syscall.ENOSYS {
+// mlockAt <- pins [addr, addr+length) in RAM so it is never paged out
+// munmap drops the lock, so no munlock is needed
+func mlockAt(addr uintptr, length int) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_MLOCK, addr, uintptr(length), 0)
+	if errno != 0 {
 		return errno
 	}
 	return nil
 }
 
+// mmapFlags <- extra mmap flags the hints need at map time
+func (h MapHints) mmapFlags() int {
+	if h.Prefault {
+		return mapPopulate
+	}
+	return 0
+}
+
+// resident <- reports whether every page of [addr, addr+length) is in RAM, via mincore(2)
+// any failure counts as not resident
+func resident(addr uintptr, length int) bool {
+	vec := make([]byte, (length+pageSize-1)/pageSize)
+	if len(vec) == 0 {
+		return true
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_MINCORE, addr, uintptr(length), uintptr(unsafe.Pointer(&vec[0])))
+	if errno != 0 {
+		return false
+	}
+	for _, v := range vec {
+		if v&1 == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// applyHints <- applies the madvise/mlock hints to the mapped bytes [from, to) and records
+// which ones took effect; failures are not errors, the hints are best effort
+//
+// Map applies them to the whole mapping and Grow only to the pages it added, so a hint
+// is reported only while it held for every range so far. Only what was checked is
+// reported: Prefault when mincore finds every page resident afterwards (MAP_POPULATE
+// itself never fails), HugePages and Mlock when the kernel accepted them
+func (r *Region) applyHints(from, to int) {
+	honoured := r.hints
+	if from > 0 {
+		honoured = r.Hints()
+	}
+	addr, length := r.base+uintptr(from), to-from
+	if r.hints.Prefault {
+		if mapPopulate == 0 {
+			_ = madviseHintFunc(addr, length, syscall.MADV_WILLNEED)
+		}
+		honoured.Prefault = honoured.Prefault && residentFunc(addr, length)
+	}
+	if r.hints.HugePages {
+		honoured.HugePages = honoured.HugePages && madvHugepage >= 0 &&
+			madviseHintFunc(addr, length, madvHugepage) == nil
+	}
+	if r.hints.Mlock {
+		honoured.Mlock = honoured.Mlock && mlockFunc(addr, length) == nil
+	}
+	r.honoured.Store(honoured.bits())
+}
+
+// Hints reports which of the requested MapHints the kernel honoured for the
+// current mapping.
+func (r *Region) Hints() MapHints {
+	return hintsFromBits(r.honoured.Load())
+}
+
 // sysAdvice <- converts our AccessPattern enum to the int the kernel expects for madvise(2)
 func (a AccessPattern) sysAdvice() int {
 	switch a {
 	case Random:
 		return syscall.MADV_RANDOM
+	case Normal:
+		return syscall.MADV_NORMAL
+	case WillNeed:
+		return syscall.MADV_WILLNEED
+	case DontNeed:
+		return syscall.MADV_DONTNEED
 	default:
 		return syscall.MADV_SEQUENTIAL
 	}
 }
 
+// String returns the name of the pattern.
+func (a AccessPattern) String() string {
+	switch a {
+	case Sequential:
+		return "sequential"
+	case Random:
+		return "random"
+	case Normal:
+		return "normal"
+	case WillNeed:
+		return "willneed"
+	case DontNeed:
+		return "dontneed"
+	default:
+		return fmt.Sprintf("AccessPattern(%d)", int(a))
+	}
+}
+
+// Advise applies pattern to the mapped bytes [offset, offset+n), widened to
+// whole pages. It does not change the pattern of the Region: Grow applies
+// the mapping-wide pattern from Map to the pages it adds.
+func (r *Region) Advise(offset, n int, pattern AccessPattern) error {
+	size := int(r.size.Load())
+	if size == 0 {
+		return fmt.Errorf("mmapforge: advise: %w", ErrClosed)
+	}
+	if offset < 0 || n < 0 || offset+n > size {
+		return fmt.Errorf("mmapforge: advise [%d, %d) outside mapping of %d bytes", offset, offset+n, size)
+	}
+	if n == 0 {
+		return nil
+	}
+	start := offset / pageSize * pageSize
+	end := pageAlign(offset + n)
+	if err := madviseFunc(r.base+uintptr(start), end-start, pattern.sysAdvice()); err != nil {
+		return fmt.Errorf("mmapforge: advise %s: %w", pattern, err)
+	}
+	return nil
+}
+
 // Sync flushes dirty pages to disk via msync. Block until the kernal confirms the write hit stable storage.
 func (r *Region) Sync() error {
 	sz := r.size.Load()
@@ -270,6 +476,40 @@ func (r *Region) Sync() error {
 	return nil
 }
 
+// SyncRange flushes the mapped bytes [offset, offset+n), widened to whole
+// pages, and blocks like Sync until they hit stable storage.
+func (r *Region) SyncRange(offset, n int) error {
+	size := int(r.size.Load())
+	if size == 0 {
+		return fmt.Errorf("mmapforge: sync: %w", ErrClosed)
+	}
+	if offset < 0 || n < 0 || offset+n > size {
+		return fmt.Errorf("mmapforge: sync [%d, %d) outside mapping of %d bytes", offset, offset+n, size)
+	}
+	if n == 0 {
+		return nil
+	}
+	start := offset / pageSize * pageSize
+	end := pageAlign(offset + n)
+	if err := msyncSyscall(r.base+uintptr(start), uintptr(end-start), uintptr(syscall.MS_SYNC)); err != nil {
+		return fmt.Errorf("mmapforge: sync: %w", err)
+	}
+	return nil
+}
+
+// SyncAsync schedules write-back of every dirty page (MS_ASYNC) and returns
+// without waiting for it.
+func (r *Region) SyncAsync() error {
+	sz := r.size.Load()
+	if sz == 0 {
+		return fmt.Errorf("mmapforge: sync: %w", ErrClosed)
+	}
+	if err := msyncSyscall(r.base, uintptr(sz), uintptr(syscall.MS_ASYNC)); err != nil {
+		return fmt.Errorf("mmapforge: sync async: %w", err)
+	}
+	return nil
+}
+
 // Unmap releases the entire VA reservation. Idempotent.
 func (r *Region) Unmap() error {
 	if r.size.Load() == 0 && r.maxVA == 0 {
@@ -305,12 +545,14 @@ func (r *Region) Close() error {
 	return nil
 }
 
-// Grow remaps the file to at least minSize bytes (page-aligned) at the
-// same base address using MAP_FIXED. No-op if already large enough.
+// Grow extends the mapping to at least minSize bytes (page-aligned) by
+// mapping only the new tail of the file after the current mapping, using
+// MAP_FIXED. No-op if already large enough.
 //
-// Because the base address never changes, slices from previous Slice
-// calls remain valid (they point into the same VA range). New pages
-// beyond the old size become accessible after Grow returns.
+// Because the base address never changes and the existing pages are not
+// remapped, slices from previous Slice calls remain valid and keep their
+// advice and locks. New pages beyond the old size become accessible after
+// Grow returns, with the access pattern and hints from Map applied.
 //
 // Must be externally serialized (Store.appendMu).
 func (r *Region) Grow(minSize int) error {
@@ -328,18 +570,31 @@ func (r *Region) Grow(minSize int) error {
 		return fmt.Errorf("mmapforge: grow truncate: %w", err)
 	}
 
-	if err := mmapFixedFunc(r.base, aligned, r.file, r.writeable); err != nil {
-		return fmt.Errorf("mmapforge: grow mmap: %w", err)
-	}
-
-	if err := madviseFunc(r.base, aligned, r.access.sysAdvice()); err != nil {
-		return fmt.Errorf("mmapforge: grow madvise: %w", err)
+	// the kernel already maps the partial last page of the current mapping in full
+	if start := pageAlign(cur); aligned > start {
+		tail := r.base + uintptr(start)
+		if err := mmapFixedFunc(tail, aligned-start, r.file, r.writeable, r.hints.mmapFlags(), start); err != nil {
+			return fmt.Errorf("mmapforge: grow mmap: %w", err)
+		}
+		if err := madviseFunc(tail, aligned-start, r.access.sysAdvice()); err != nil {
+			return fmt.Errorf("mmapforge: grow madvise: %w", err)
+		}
+		r.applyHints(start, aligned)
 	}
-
 	r.size.Store(int64(aligned))
 	return nil
 }
 
+// Base returns the fixed start address of the mapping.
+func (r *Region) Base() uintptr {
+	return r.base
+}
+
+// Name returns the name of the mapped file.
+func (r *Region) Name() string {
+	return r.file.Name()
+}
+
 // Mapped returns the size of the mapped region in bytes.
 func (r *Region) Mapped() int {
 	return int(r.size.Load())
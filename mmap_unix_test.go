@@ -4,9 +4,11 @@ package mmapforge
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"os"
 	"runtime"
+	"slices"
 	"strings"
 	"syscall"
 	"testing"
@@ -311,11 +313,11 @@ func TestMmapFixedSuccess(t *testing.T) {
 
 	addr := uintptr(unsafe.Pointer(&reserved[0]))
 
-	if fixErr := mmapFixed(addr, pageSize, f, false); fixErr != nil {
+	if fixErr := mmapFixed(addr, pageSize, f, false, 0, 0); fixErr != nil {
 		t.Fatalf("mmapFixed: %v", fixErr)
 	}
 
-	if fixErr := mmapFixed(addr, pageSize, f, true); fixErr != nil {
+	if fixErr := mmapFixed(addr, pageSize, f, true, 0, 0); fixErr != nil {
 		t.Fatalf("mmapFixed writable: %v", fixErr)
 	}
 }
@@ -335,7 +337,7 @@ func TestMmapFixedBadFd(t *testing.T) {
 	defer func() { deferSysMunmap(t, reserved) }()
 
 	addr := uintptr(unsafe.Pointer(&reserved[0]))
-	err = mmapFixed(addr, pageSize, f, false)
+	err = mmapFixed(addr, pageSize, f, false, 0, 0)
 	if err == nil {
 		t.Fatal("expected error for mmapFixed with closed fd")
 	}
@@ -451,7 +453,7 @@ func TestMapReserveVAError(t *testing.T) {
 	}
 }
 
-func restoreFuncs(oldMmap func(uintptr, int, *os.File, bool) error, oldMadvise func(uintptr, int, int) error) {
+func restoreFuncs(oldMmap func(uintptr, int, *os.File, bool, int, int) error, oldMadvise func(uintptr, int, int) error) {
 	mmapFixedFunc = oldMmap
 	madviseFunc = oldMadvise
 }
@@ -460,7 +462,7 @@ func TestMapMmapFixedErrorCleanup(t *testing.T) {
 	oldMmap, oldMadvise := mmapFixedFunc, madviseFunc
 	defer restoreFuncs(oldMmap, oldMadvise)
 
-	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool) error {
+	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool, _, _ int) error {
 		return syscall.ENOMEM
 	}
 
@@ -480,7 +482,7 @@ func TestMapMmapFixedAddressMismatch(t *testing.T) {
 	oldMmap, oldMadvise := mmapFixedFunc, madviseFunc
 	defer restoreFuncs(oldMmap, oldMadvise)
 
-	mmapFixedFunc = func(addr uintptr, _ int, _ *os.File, _ bool) error {
+	mmapFixedFunc = func(addr uintptr, _ int, _ *os.File, _ bool, _, _ int) error {
 		return fmt.Errorf("mmapforge: mmap: expected address %#x, got %#x", addr, uintptr(0xBADF00D))
 	}
 
@@ -543,7 +545,7 @@ func TestMmapFixedAddressMismatchReal(t *testing.T) {
 	defer func() { deferSysMunmap(t, reserved) }()
 
 	addr := uintptr(unsafe.Pointer(&reserved[0]))
-	err = mmapFixed(addr, pageSize, f, false)
+	err = mmapFixed(addr, pageSize, f, false, 0, 0)
 	if err == nil {
 		t.Fatal("expected error for address mismatch")
 	}
@@ -1019,7 +1021,7 @@ func TestGrowMmapError(t *testing.T) {
 		}
 	}()
 
-	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool) error {
+	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool, _, _ int) error {
 		return fmt.Errorf("injected mmap error")
 	}
 
@@ -1155,6 +1157,57 @@ func TestGrowFileExtended(t *testing.T) {
 	}
 }
 
+func TestGrowMapsOnlyTail(t *testing.T) {
+	oldMmap, oldMadvise := mmapFixedFunc, madviseFunc
+	defer restoreFuncs(oldMmap, oldMadvise)
+
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Map(f, pageSize/2, true, Sequential, pageSize*8)
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+	defer r.Close()
+
+	type mapCall struct{ addr, length, offset int }
+	var calls []mapCall
+	mmapFixedFunc = func(addr uintptr, length int, f *os.File, writable bool, flags, offset int) error {
+		calls = append(calls, mapCall{int(addr - r.base), length, offset})
+		return mmapFixed(addr, length, f, writable, flags, offset)
+	}
+
+	// Growing within the partial first page maps nothing new.
+	if err := r.Grow(pageSize); err != nil {
+		t.Fatalf("Grow: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("Grow within the mapped page called mmap %v", calls)
+	}
+
+	if err := r.Grow(pageSize * 3); err != nil {
+		t.Fatalf("Grow: %v", err)
+	}
+	want := []mapCall{{pageSize, pageSize * 2, pageSize}}
+	if !slices.Equal(calls, want) {
+		t.Errorf("mmap calls = %v, want %v", calls, want)
+	}
+
+	// The tail maps the right part of the file.
+	copy(r.Slice(pageSize*2, 4), "tail")
+	if err := r.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 4)
+	if _, err := f.ReadAt(got, int64(pageSize*2)); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "tail" {
+		t.Errorf("file at %d = %q, want %q", pageSize*2, got, "tail")
+	}
+}
+
 func TestUnmapReturnsError(t *testing.T) {
 	r := &Region{
 		base:  1,
@@ -1292,3 +1345,295 @@ func TestMsyncSyscallError(t *testing.T) {
 		t.Fatal("expected error from msyncSyscall on unmapped address")
 	}
 }
+
+// hintCall is one madvise hint or mlock call recorded by stubHints.
+type hintCall struct {
+	advice int // -1 for mlock
+	length int
+}
+
+// stubHints makes madvise hints and mlock succeed or fail as told, and
+// records the calls. The mapping counts as resident.
+func stubHints(t *testing.T, adviseErr, mlockErr error) *[]hintCall {
+	t.Helper()
+	oldAdvise, oldMlock, oldResident := madviseHintFunc, mlockFunc, residentFunc
+	t.Cleanup(func() {
+		madviseHintFunc, mlockFunc, residentFunc = oldAdvise, oldMlock, oldResident
+	})
+	residentFunc = func(uintptr, int) bool { return true }
+	var calls []hintCall
+	madviseHintFunc = func(_ uintptr, length int, advice int) error {
+		calls = append(calls, hintCall{advice, length})
+		return adviseErr
+	}
+	mlockFunc = func(_ uintptr, length int) error {
+		calls = append(calls, hintCall{-1, length})
+		return mlockErr
+	}
+	return &calls
+}
+
+func TestMapWithHints(t *testing.T) {
+	calls := stubHints(t, nil, nil)
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := MapHints{Prefault: true, HugePages: true, Mlock: true}
+	r, err := MapWithHints(f, pageSize, true, Random, all, pageSize*4)
+	if err != nil {
+		t.Fatalf("MapWithHints: %v", err)
+	}
+	defer r.Close()
+
+	want := all
+	want.HugePages = madvHugepage >= 0
+	if got := r.Hints(); got != want {
+		t.Errorf("Hints = %+v, want %+v", got, want)
+	}
+
+	// Grow maps only the new tail, so the hints go to the new pages alone.
+	*calls = nil
+	if err := r.Grow(pageSize * 3); err != nil {
+		t.Fatalf("Grow: %v", err)
+	}
+	for _, c := range *calls {
+		if c.length != pageSize*2 {
+			t.Errorf("hint %d applied to %d bytes on Grow, want the %d-byte tail", c.advice, c.length, pageSize*2)
+		}
+	}
+	if got := r.Hints(); got != want {
+		t.Errorf("Hints after Grow = %+v, want %+v", got, want)
+	}
+}
+
+func TestMapWithHints_HugePagesReservation(t *testing.T) {
+	if madvHugepage < 0 {
+		t.Skip("no transparent huge pages on this platform")
+	}
+	calls := stubHints(t, nil, nil)
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := MapWithHints(f, pageSize, true, Random, MapHints{HugePages: true}, pageSize*4)
+	if err != nil {
+		t.Fatalf("MapWithHints: %v", err)
+	}
+	defer r.Close()
+
+	// A file on disk is advised too: first the whole reservation, then the
+	// file mapping that replaced its start.
+	wantCalls := []hintCall{{madvHugepage, pageSize * 4}, {madvHugepage, pageSize}}
+	if !slices.Equal(*calls, wantCalls) {
+		t.Errorf("madvise calls = %v, want %v", *calls, wantCalls)
+	}
+	if !r.Hints().HugePages {
+		t.Error("HugePages not reported after the kernel accepted it")
+	}
+}
+
+func TestMapWithHints_GrowRefused(t *testing.T) {
+	stubHints(t, nil, nil)
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := MapWithHints(f, pageSize, true, Random, MapHints{Prefault: true, Mlock: true}, pageSize*4)
+	if err != nil {
+		t.Fatalf("MapWithHints: %v", err)
+	}
+	defer r.Close()
+
+	// Once a grown tail misses a hint it is no longer reported, even
+	// though the original pages kept it.
+	mlockFunc = func(uintptr, int) error { return syscall.EPERM }
+	residentFunc = func(uintptr, int) bool { return false }
+	if err := r.Grow(pageSize * 2); err != nil {
+		t.Fatalf("Grow: %v", err)
+	}
+	mlockFunc = func(uintptr, int) error { return nil }
+	residentFunc = func(uintptr, int) bool { return true }
+	if err := r.Grow(pageSize * 3); err != nil {
+		t.Fatalf("Grow: %v", err)
+	}
+	if got := r.Hints(); got != (MapHints{}) {
+		t.Errorf("Hints = %+v, want none after a refused grow", got)
+	}
+}
+
+func TestMapWithHints_Unverified(t *testing.T) {
+	stubHints(t, nil, nil)
+	residentFunc = func(uintptr, int) bool { return false }
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := MapWithHints(f, pageSize, true, Random, MapHints{Prefault: true}, pageSize*4)
+	if err != nil {
+		t.Fatalf("MapWithHints: %v", err)
+	}
+	defer r.Close()
+
+	// Pages that are not resident afterwards are not reported prefaulted.
+	if got := r.Hints(); got != (MapHints{}) {
+		t.Errorf("Hints = %+v, want none", got)
+	}
+}
+
+func TestResident(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Map(f, pageSize*2, true, Random)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	b := r.Slice(0, pageSize*2)
+	b[0], b[pageSize] = 1, 1
+	if !resident(r.base, pageSize*2) {
+		t.Error("touched pages not resident")
+	}
+	if !resident(r.base, 0) {
+		t.Error("empty range not resident")
+	}
+	// The PROT_NONE tail of the reservation is not backed by anything.
+	if resident(r.base+uintptr(pageSize*2), pageSize) {
+		t.Error("reservation tail reported resident")
+	}
+}
+
+func TestMapWithHints_Refused(t *testing.T) {
+	stubHints(t, syscall.EINVAL, syscall.EPERM)
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := MapWithHints(f, pageSize, true, Random, MapHints{HugePages: true, Mlock: true}, pageSize*4)
+	if err != nil {
+		t.Fatalf("refused hints should not fail the mapping: %v", err)
+	}
+	defer r.Close()
+	if got := r.Hints(); got != (MapHints{}) {
+		t.Errorf("Hints = %+v, want none honoured", got)
+	}
+}
+
+func TestMapWithHints_Real(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := MapWithHints(f, pageSize*2, true, Random, MapHints{Prefault: true, HugePages: true, Mlock: true}, pageSize*4)
+	if err != nil {
+		t.Fatalf("MapWithHints: %v", err)
+	}
+	defer r.Close()
+
+	// Whether huge pages and mlock are granted depends on the kernel and
+	// RLIMIT_MEMLOCK; prefaulting never fails.
+	if !r.Hints().Prefault {
+		t.Errorf("Hints = %+v, want Prefault", r.Hints())
+	}
+	r.Slice(0, 1)[0] = 1
+}
+
+func TestMlockAt_Error(t *testing.T) {
+	if err := mlockAt(0, pageSize); err == nil {
+		t.Error("expected error locking unmapped memory")
+	}
+}
+
+func TestMapHints_Bits(t *testing.T) {
+	for _, h := range []MapHints{{}, {Prefault: true}, {HugePages: true}, {Mlock: true}, {Prefault: true, HugePages: true, Mlock: true}} {
+		if got := hintsFromBits(h.bits()); got != h {
+			t.Errorf("round trip of %+v = %+v", h, got)
+		}
+	}
+}
+
+func TestAccessPattern_String(t *testing.T) {
+	for p, want := range map[AccessPattern]string{
+		Sequential: "sequential", Random: "random", Normal: "normal",
+		WillNeed: "willneed", DontNeed: "dontneed", AccessPattern(42): "AccessPattern(42)",
+	} {
+		if got := p.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", int(p), got, want)
+		}
+	}
+}
+
+func TestAccessPattern_SysAdvice(t *testing.T) {
+	for p, want := range map[AccessPattern]int{
+		Sequential: syscall.MADV_SEQUENTIAL, Random: syscall.MADV_RANDOM, Normal: syscall.MADV_NORMAL,
+		WillNeed: syscall.MADV_WILLNEED, DontNeed: syscall.MADV_DONTNEED,
+	} {
+		if got := p.sysAdvice(); got != want {
+			t.Errorf("%s.sysAdvice() = %d, want %d", p, got, want)
+		}
+	}
+}
+
+func TestMapDontNeedRejected(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := Map(f, pageSize, true, DontNeed); err == nil || !strings.Contains(err.Error(), "not an access pattern for a whole mapping") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRegionAdvise(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "mmapforge-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Map(f, pageSize*4, true, Normal, pageSize*8)
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+
+	r.Slice(pageSize+10, 1)[0] = 0x5a
+	for _, p := range []AccessPattern{Sequential, Random, Normal, WillNeed, DontNeed} {
+		if err := r.Advise(pageSize+5, 100, p); err != nil {
+			t.Errorf("Advise(%s): %v", p, err)
+		}
+	}
+	// DontNeed on a shared file mapping drops the pages, not the data.
+	if got := r.Slice(pageSize+10, 1)[0]; got != 0x5a {
+		t.Errorf("byte after DontNeed = %#x, want 0x5a", got)
+	}
+	if err := r.Advise(0, 0, WillNeed); err != nil {
+		t.Errorf("empty range: %v", err)
+	}
+	if err := r.Advise(pageSize*3, pageSize+1, WillNeed); err == nil || !strings.Contains(err.Error(), "outside mapping") {
+		t.Errorf("past the end: err = %v", err)
+	}
+	if err := r.Advise(-1, 1, WillNeed); err == nil {
+		t.Error("expected error for negative offset")
+	}
+
+	oldMmap, oldMadvise := mmapFixedFunc, madviseFunc
+	madviseFunc = func(uintptr, int, int) error { return syscall.EINVAL }
+	err = r.Advise(0, 1, WillNeed)
+	restoreFuncs(oldMmap, oldMadvise)
+	if err == nil || !strings.Contains(err.Error(), "advise willneed") {
+		t.Errorf("madvise error: err = %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Advise(0, 1, WillNeed); !errors.Is(err, ErrClosed) {
+		t.Errorf("after Close: err = %v, want ErrClosed", err)
+	}
+}
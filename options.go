@@ -1,11 +1,22 @@
 package mmapforge
 
+import "time"
+
 // StoreOption configures how a Store is opened or created.
 type StoreOption func(*storeConfig)
 
 type storeConfig struct {
-	readOnly  bool
-	oneWriter bool
+	schemaVersion uint32
+	capacity      int
+	readOnly      bool
+	oneWriter     bool
+	hints         MapHints
+	access        AccessPattern
+	dirtyTracking bool
+	autoSync      time.Duration
+	backoff       ReadBackoff
+	heartbeat     time.Duration
+	multiWriter   bool
 }
 
 // WithReadOnly opens the store in read-only mode.
@@ -26,8 +37,124 @@ func WithOneWriter() StoreOption {
 	}
 }
 
+// WithSchemaVersion sets the schema version written when Open creates a
+// new file. Defaults to 1. CreateStore takes the version as an argument
+// and ignores this option.
+func WithSchemaVersion(v uint32) StoreOption {
+	return func(c *storeConfig) {
+		c.schemaVersion = v
+	}
+}
+
+// WithCapacity sets the number of records a newly created file is sized
+// for, so bulk loads don't grow the mapping repeatedly. Values below the
+// default initial capacity are ignored. Has no effect on OpenStore.
+func WithCapacity(n int) StoreOption {
+	return func(c *storeConfig) {
+		c.capacity = n
+	}
+}
+
+// WithPrefault populates the page tables of the mapping up front
+// (MAP_POPULATE, or an madvise(MADV_WILLNEED) sweep where that is not
+// available), so first touches don't page-fault. Store.Hints reports it
+// only if every page was found resident afterwards.
+func WithPrefault() StoreOption {
+	return func(c *storeConfig) {
+		c.hints.Prefault = true
+	}
+}
+
+// WithTransparentHugePages asks the kernel to back the mapping with
+// transparent huge pages (MADV_HUGEPAGE, Linux only), cutting TLB misses
+// on large stores. Store.Hints reports whether the kernel accepted the
+// advice; most kernels only use huge pages for stores in shared memory
+// (CreateStoreMemfd, or a file on tmpfs), not for files on disk.
+func WithTransparentHugePages() StoreOption {
+	return func(c *storeConfig) {
+		c.hints.HugePages = true
+	}
+}
+
+// WithMlock pins the mapping in RAM with mlock(2) so it is never paged
+// out. It needs a large enough RLIMIT_MEMLOCK (or CAP_IPC_LOCK); if the
+// kernel refuses, the store still opens and Store.Hints reports it.
+func WithMlock() StoreOption {
+	return func(c *storeConfig) {
+		c.hints.Mlock = true
+	}
+}
+
+// WithAccessPattern sets the madvise pattern for the whole mapping.
+// Defaults to Random, which suits point lookups; full scans want
+// Sequential. DontNeed is only valid for Store.Advise.
+func WithAccessPattern(p AccessPattern) StoreOption {
+	return func(c *storeConfig) {
+		c.access = p
+	}
+}
+
+// WithDirtyTracking keeps a bitmap of the pages written through the
+// seqlock (and Store.MarkDirty), so Store.SyncDirty can msync just those
+// pages. It costs one atomic load per write, plus one atomic OR the first
+// time a page is dirtied after a sync. Ignored for read-only stores.
+func WithDirtyTracking() StoreOption {
+	return func(c *storeConfig) {
+		c.dirtyTracking = true
+	}
+}
+
+// WithAutoSync syncs the store in the background every interval: the
+// header, then the dirty pages if the store also has WithDirtyTracking, or
+// else the pages of every record. Close stops the goroutine before its own
+// final sync. Store.Stats reports how the last sync went. Ignored for
+// read-only stores and for intervals <= 0.
+func WithAutoSync(interval time.Duration) StoreOption {
+	return func(c *storeConfig) {
+		c.autoSync = interval
+	}
+}
+
+// WithReadBackoff sets how readers of this store wait out writers,
+// replacing DefaultReadBackoff. Negative values count as 0.
+func WithReadBackoff(b ReadBackoff) StoreOption {
+	return func(c *storeConfig) {
+		c.backoff = ReadBackoff{Spins: max(b.Spins, 0), MaxRetries: max(b.MaxRetries, 0)}
+	}
+}
+
+// WithWriterHeartbeat makes the writer refresh the heartbeat in the
+// store's writer record every interval, so readers can tell a writer that
+// is hung, not just one that has exited (Store.Writer). Ignored by stores
+// that keep no writer record: read-only, WithMultiWriter and format
+// version 1 stores.
+func WithWriterHeartbeat(interval time.Duration) StoreOption {
+	return func(c *storeConfig) {
+		c.heartbeat = interval
+	}
+}
+
+// WithMultiWriter lets several goroutines, or several processes that all
+// open the store with it, write the same records concurrently:
+// SeqBeginWrite takes a record's seqlock with a compare-and-swap and waits
+// while another writer holds it, instead of assuming it is the only
+// writer. Appends must still come from one process. Without WithOneWriter,
+// OpenStore no longer resets stuck seqlocks, since another writer may be
+// mid-write; see Store.RecoverSeqlocks.
+//
+// The mode is recorded in the file header (FlagMultiWriter), and every
+// later opener follows it, option or not. Switch an existing file over
+// while no other writer has it open: one already attached keeps the
+// single-writer protocol. Format version 1 files have no flags, so writers
+// refuse to open them with this option.
+func WithMultiWriter() StoreOption {
+	return func(c *storeConfig) {
+		c.multiWriter = true
+	}
+}
+
 func applyOptions(opts []StoreOption) storeConfig {
-	var cfg storeConfig
+	cfg := storeConfig{schemaVersion: 1, access: Random, backoff: DefaultReadBackoff}
 	for _, o := range opts {
 		o(&cfg)
 	}
@@ -10,6 +10,9 @@ func TestApplyOptions_NoOpts(t *testing.T) {
 	if cfg.oneWriter {
 		t.Error("oneWriter should be false by default")
 	}
+	if cfg.schemaVersion != 1 {
+		t.Errorf("schemaVersion = %d, want 1 by default", cfg.schemaVersion)
+	}
 }
 
 func TestApplyOptions_WithReadOnly(t *testing.T) {
@@ -41,3 +44,37 @@ func TestApplyOptions_BothOptions(t *testing.T) {
 		t.Error("oneWriter should be true")
 	}
 }
+
+func TestApplyOptions_WithSchemaVersion(t *testing.T) {
+	cfg := applyOptions([]StoreOption{WithSchemaVersion(7)})
+	if cfg.schemaVersion != 7 {
+		t.Errorf("schemaVersion = %d, want 7", cfg.schemaVersion)
+	}
+}
+
+func TestApplyOptions_WithCapacity(t *testing.T) {
+	cfg := applyOptions([]StoreOption{WithCapacity(500)})
+	if cfg.capacity != 500 {
+		t.Errorf("capacity = %d, want 500", cfg.capacity)
+	}
+}
+
+func TestApplyOptions_Hints(t *testing.T) {
+	cfg := applyOptions(nil)
+	if cfg.hints != (MapHints{}) {
+		t.Errorf("hints = %+v, want none by default", cfg.hints)
+	}
+	cfg = applyOptions([]StoreOption{WithPrefault(), WithTransparentHugePages(), WithMlock()})
+	if cfg.hints != (MapHints{Prefault: true, HugePages: true, Mlock: true}) {
+		t.Errorf("hints = %+v, want all", cfg.hints)
+	}
+}
+
+func TestApplyOptions_WithAccessPattern(t *testing.T) {
+	if cfg := applyOptions(nil); cfg.access != Random {
+		t.Errorf("access = %v, want random by default", cfg.access)
+	}
+	if cfg := applyOptions([]StoreOption{WithAccessPattern(Sequential)}); cfg.access != Sequential {
+		t.Errorf("access = %v, want sequential", cfg.access)
+	}
+}
@@ -0,0 +1,139 @@
+// Package plugin defines the protocol between the mmapforge generator and
+// plugins run with `mmapforge -plugin`. A plugin is a program that reads a
+// Request as JSON on stdin and writes a Response as JSON on stdout:
+//
+//	func main() {
+//		plugin.Run(func(req *plugin.Request) ([]plugin.File, error) {
+//			var b strings.Builder
+//			fmt.Fprintf(&b, "%s\n\npackage %s\n", req.Header, req.Package)
+//			// ...
+//			return []plugin.File{{Name: "metrics_gen.go", Content: b.String()}}, nil
+//		})
+//	}
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Version is the protocol version sent in Request.Version.
+const Version = 1
+
+// Request describes one generated package.
+type Request struct {
+	// Version is the protocol version, currently Version.
+	Version int `json:"version"`
+
+	// Target is the output directory of the package.
+	Target string `json:"target"`
+
+	// Package is the Go package name of the generated code.
+	Package string `json:"package"`
+
+	// Header is the file header of generated files.
+	Header string `json:"header"`
+
+	// BuildTag is the configured build constraint, if any.
+	BuildTag string `json:"build_tag,omitempty"`
+
+	// Features reports every known generator feature and whether it is on.
+	Features map[string]bool `json:"features"`
+
+	// Types are the schema types of the package.
+	Types []Type `json:"types"`
+}
+
+// Type is one mmapforge:schema struct and the names generated for it.
+type Type struct {
+	Name          string  `json:"name"`
+	SchemaVersion uint32  `json:"schema_version"`
+	RecordSize    uint32  `json:"record_size"`
+	StoreName     string  `json:"store_name"`
+	RecordName    string  `json:"record_name"`
+	LayoutFunc    string  `json:"layout_func"`
+	NewStoreFunc  string  `json:"new_store_func"`
+	InMemoryFunc  string  `json:"in_memory_func"`
+	OpenStoreFunc string  `json:"open_store_func"`
+	File          string  `json:"file"`
+	Fields        []Field `json:"fields"`
+}
+
+// Field is one field of a Type with its computed layout.
+type Field struct {
+	// Name is the mmap tag name.
+	Name string `json:"name"`
+
+	// GoName is the Go struct field name.
+	GoName string `json:"go_name"`
+
+	// Type is the mmapforge field type, such as "uint64" or "bytes".
+	Type string `json:"type"`
+
+	// GoType is the Go type of the generated accessors, such as "[]byte".
+	GoType string `json:"go_type"`
+
+	Offset  uint32 `json:"offset"`
+	Size    uint32 `json:"size"`
+	MaxSize uint32 `json:"max_size,omitempty"`
+}
+
+// Response is the answer of a plugin.
+type Response struct {
+	// Files are written into the target directory. Go files are gofmt'ed.
+	// Names must be local paths and must not name a file the generator
+	// writes, such as a store, the registry or mmapforge.lock.
+	Files []File `json:"files,omitempty"`
+
+	// Error reports a generation failure; mmapforge writes no files of the
+	// plugin and exits non-zero.
+	Error string `json:"error,omitempty"`
+}
+
+// File is one generated file.
+type File struct {
+	// Name is the path relative to the target directory.
+	Name string `json:"name"`
+
+	// Content is the file content.
+	Content string `json:"content"`
+}
+
+// Mockable functions
+var exitFunc = os.Exit
+
+// Run serves one request on stdin and stdout with gen. It exits the
+// process with status 1 if the request cannot be read or the response
+// cannot be written.
+func Run(gen func(*Request) ([]File, error)) {
+	if err := Serve(os.Stdin, os.Stdout, gen); err != nil {
+		fmt.Fprintf(os.Stderr, "mmapforge plugin: %v\n", err)
+		exitFunc(1)
+	}
+}
+
+// Serve reads a Request from r, calls gen and writes the Response to w.
+// An error from gen is reported in Response.Error.
+func Serve(r io.Reader, w io.Writer, gen func(*Request) ([]File, error)) error {
+	var req Request
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("read request: %w", err)
+	}
+	if req.Version != Version {
+		return fmt.Errorf("unsupported protocol version %d (want %d)", req.Version, Version)
+	}
+
+	var resp Response
+	files, err := gen(&req)
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Files = files
+	}
+	if err := json.NewEncoder(w).Encode(&resp); err != nil {
+		return fmt.Errorf("write response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var errWriteFailed = errors.New("write failed")
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errWriteFailed }
+
+func request(t *testing.T, req Request) *bytes.Reader {
+	t.Helper()
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(b)
+}
+
+func TestServe(t *testing.T) {
+	in := request(t, Request{
+		Version: Version,
+		Package: "feeds",
+		Types:   []Type{{Name: "Tick", Fields: []Field{{Name: "price", Type: "float64"}}}},
+	})
+	var out bytes.Buffer
+	err := Serve(in, &out, func(req *Request) ([]File, error) {
+		if req.Package != "feeds" || len(req.Types) != 1 || req.Types[0].Fields[0].Type != "float64" {
+			t.Errorf("request = %+v", req)
+		}
+		return []File{{Name: "x.go", Content: "package feeds\n"}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != "" || len(resp.Files) != 1 || resp.Files[0].Name != "x.go" {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestServe_GenError(t *testing.T) {
+	var out bytes.Buffer
+	err := Serve(request(t, Request{Version: Version}), &out, func(*Request) ([]File, error) {
+		return []File{{Name: "ignored.go"}}, errors.New("no metrics for you")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(out.String()); got != `{"error":"no metrics for you"}` {
+		t.Errorf("response = %s", got)
+	}
+}
+
+func TestServe_Errors(t *testing.T) {
+	gen := func(*Request) ([]File, error) { return nil, nil }
+
+	if err := Serve(strings.NewReader("{"), &bytes.Buffer{}, gen); err == nil || !strings.Contains(err.Error(), "read request") {
+		t.Errorf("bad JSON: err = %v", err)
+	}
+	if err := Serve(request(t, Request{Version: 99}), &bytes.Buffer{}, gen); err == nil || !strings.Contains(err.Error(), "unsupported protocol version 99") {
+		t.Errorf("bad version: err = %v", err)
+	}
+	if err := Serve(request(t, Request{Version: Version}), failWriter{}, gen); !errors.Is(err, errWriteFailed) {
+		t.Errorf("write: err = %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "req.json")
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	origStdin, origStderr, origExit := os.Stdin, os.Stderr, exitFunc
+	defer func() { os.Stdin, os.Stderr, exitFunc = origStdin, origStderr, origExit }()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdin, os.Stderr = f, devNull
+	code := 0
+	exitFunc = func(c int) { code = c }
+
+	Run(func(*Request) ([]File, error) { return nil, nil })
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+}
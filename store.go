@@ -7,6 +7,7 @@ import (
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -14,16 +15,19 @@ const initialCapacity = 64
 
 // Header field offsets — must match EncodeHeader/DecodeHeader in header.go.
 const (
-	offsetRecordCount = 48
-	offsetCapacity    = 56
+	offsetRecordCount  = 48
+	offsetCapacity     = 56
+	offsetWriterRecord = 72 // format version 2; see store_writer.go
 )
 
 var statFileFunc = func(f *os.File) (os.FileInfo, error) { return f.Stat() }
 var encodeHeaderFunc = EncodeHeader
+var mapMemoryFunc = MapMemory
 
 // Store is the base mmap-backed record store.
 type Store struct {
-	region         *Region
+	region         Backing
+	base           uintptr
 	layout         *RecordLayout
 	header         *Header
 	recordCountPtr *atomic.Uint64
@@ -31,8 +35,19 @@ type Store struct {
 	lockFile       *os.File
 	path           string
 	recordSize     int
+	dataOff        int
 	appendMu       sync.Mutex
 	writable       bool
+	dirty          *dirtyTracker
+	autoSync       *bgLoop
+	heartbeat      *bgLoop
+	writer         *writerRecord
+	ownsWriter     bool
+	backoff        ReadBackoff
+	multi          bool
+	statsMu        sync.Mutex
+	stats          StoreStats
+	seqRetries     retryCounters
 }
 
 // CreateStore creates a new mmapforge file at path with the given layout and schema version.
@@ -42,11 +57,85 @@ func CreateStore(path string, layout *RecordLayout, schemaVersion uint32, opts .
 		return nil, fmt.Errorf("mmapforge: cannot create store in read-only mode")
 	}
 
+	capacity, fileSize, err := createSize(path, layout, cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("mmapforge: create %s: %w", path, err)
 	}
 
+	return createStoreFile(f, layout, schemaVersion, capacity, fileSize, cfg)
+}
+
+// createStoreFile sizes and maps the new store file f and writes its
+// header. f is closed if anything fails.
+func createStoreFile(f *os.File, layout *RecordLayout, schemaVersion uint32, capacity, fileSize int, cfg storeConfig) (*Store, error) {
+	path := f.Name()
+	region, err := MapWithHints(f, fileSize, true, cfg.access, cfg.hints, StoreReserveVA)
+	if err != nil {
+		closeErr := f.Close()
+		return nil, errors.Join(
+			fmt.Errorf("mmapforge: map %s: %w", path, err),
+			fmt.Errorf("mmapforge: close %s: %w", path, closeErr),
+		)
+	}
+
+	return initStore(region, layout, schemaVersion, capacity, cfg)
+}
+
+// CreateStoreWithBacking creates a new store in b, growing it to fit the
+// header and the initial capacity. The store takes ownership of b and
+// closes it on Close, or right away if creation fails.
+func CreateStoreWithBacking(b Backing, layout *RecordLayout, schemaVersion uint32, opts ...StoreOption) (*Store, error) {
+	cfg := applyOptions(opts)
+	var capacity, size int
+	var err error
+	if cfg.readOnly {
+		err = fmt.Errorf("mmapforge: cannot create store in read-only mode")
+	} else {
+		capacity, size, err = createSize(b.Name(), layout, cfg)
+	}
+	if err == nil {
+		err = b.Grow(size)
+	}
+	if err != nil {
+		closeErr := b.Close()
+		return nil, errors.Join(
+			err,
+			fmt.Errorf("mmapforge: close %s: %w", b.Name(), closeErr),
+		)
+	}
+
+	return initStore(b, layout, schemaVersion, capacity, cfg)
+}
+
+// CreateStoreInMemory creates a store in anonymous memory. It behaves like
+// a file-backed store, but its records are gone once it is closed.
+// WithOneWriter is not supported.
+func CreateStoreInMemory(layout *RecordLayout, schemaVersion uint32, opts ...StoreOption) (*Store, error) {
+	region, err := mapMemoryFunc(HeaderSize, StoreReserveVA)
+	if err != nil {
+		return nil, fmt.Errorf("mmapforge: map memory: %w", err)
+	}
+	return CreateStoreWithBacking(region, layout, schemaVersion, opts...)
+}
+
+// createSize returns the initial capacity and byte size of a new store.
+func createSize(name string, layout *RecordLayout, cfg storeConfig) (capacity, size int, err error) {
+	capacity = max(cfg.capacity, initialCapacity)
+	if uint64(capacity) > (uint64(math.MaxInt)-HeaderSize)/uint64(layout.RecordSize) {
+		return 0, 0, fmt.Errorf("mmapforge: create %s: capacity %d overflows address space", name, capacity)
+	}
+	return capacity, HeaderSize + int(layout.RecordSize)*capacity, nil
+}
+
+// initStore writes a fresh header into b and returns the store over it.
+// b is closed if anything fails.
+func initStore(b Backing, layout *RecordLayout, schemaVersion uint32, capacity int, cfg storeConfig) (*Store, error) {
+	name := b.Name()
 	hash := SchemaHash(layout.Descriptors())
 	h := &Header{
 		Magic:         Magic,
@@ -55,49 +144,34 @@ func CreateStore(path string, layout *RecordLayout, schemaVersion uint32, opts .
 		SchemaVersion: schemaVersion,
 		RecordSize:    layout.RecordSize,
 		RecordCount:   0,
-		Capacity:      uint64(initialCapacity),
+		Capacity:      uint64(capacity),
 	}
-
-	fileSize := HeaderSize + int(layout.RecordSize)*initialCapacity
-	region, err := Map(f, fileSize, true, Random, StoreReserveVA)
-	if err != nil {
-		closeErr := f.Close()
-		return nil, errors.Join(
-			fmt.Errorf("mmapforge: map %s: %w", path, err),
-			fmt.Errorf("mmapforge: close %s: %w", path, closeErr),
-		)
+	if cfg.multiWriter {
+		h.Flags |= FlagMultiWriter
 	}
 
-	if encodeErr := encodeHeaderFunc(region.Slice(0, HeaderSize), h); encodeErr != nil {
-		closeErr := region.Close()
+	if encodeErr := encodeHeaderFunc(b.Slice(0, HeaderSize), h); encodeErr != nil {
+		closeErr := b.Close()
 		return nil, errors.Join(
 			fmt.Errorf("mmapforge: encode header: %w", encodeErr),
-			fmt.Errorf("mmapforge: close %s: %w", path, closeErr),
+			fmt.Errorf("mmapforge: close %s: %w", name, closeErr),
 		)
 	}
 
-	s := &Store{
-		region:     region,
-		layout:     layout,
-		header:     h,
-		path:       path,
-		writable:   true,
-		recordSize: int(layout.RecordSize),
-	}
-
-	s.recordCountPtr = (*atomic.Uint64)(unsafe.Pointer(s.region.base + offsetRecordCount))
-	s.capacityPtr = (*atomic.Uint64)(unsafe.Pointer(s.region.base + offsetCapacity))
+	s := newStore(b, layout, h, cfg)
 
 	if cfg.oneWriter {
 		if lockErr := s.acquireLock(); lockErr != nil {
-			regionErr := region.Close()
+			regionErr := b.Close()
 			return nil, errors.Join(
 				fmt.Errorf("mmapforge: acquire lock: %w", lockErr),
-				fmt.Errorf("mmapforge: close %s: %w", path, regionErr),
+				fmt.Errorf("mmapforge: close %s: %w", name, regionErr),
 			)
 		}
 	}
 
+	s.claimWriter(cfg.heartbeat)
+	s.startAutoSync(cfg.autoSync)
 	return s, nil
 }
 
@@ -115,6 +189,13 @@ func OpenStore(path string, layout *RecordLayout, opts ...StoreOption) (*Store,
 		return nil, fmt.Errorf("mmapforge: open %s: %w", path, err)
 	}
 
+	return openStoreFile(f, layout, cfg)
+}
+
+// openStoreFile maps the store file f and validates its header. f is
+// closed if anything fails.
+func openStoreFile(f *os.File, layout *RecordLayout, cfg storeConfig) (*Store, error) {
+	path := f.Name()
 	info, err := statFileFunc(f)
 	if err != nil {
 		closeErr := f.Close()
@@ -125,7 +206,7 @@ func OpenStore(path string, layout *RecordLayout, opts ...StoreOption) (*Store,
 	}
 
 	fileSize := int(info.Size())
-	if fileSize < HeaderSize {
+	if fileSize < HeaderSizeV1 {
 		closeErr := f.Close()
 		return nil, errors.Join(
 			fmt.Errorf("mmapforge: file %s is too small (%d bytes)", path, fileSize),
@@ -133,8 +214,7 @@ func OpenStore(path string, layout *RecordLayout, opts ...StoreOption) (*Store,
 		)
 	}
 
-	writable := !cfg.readOnly
-	region, err := Map(f, fileSize, writable, Random, StoreReserveVA)
+	region, err := MapWithHints(f, fileSize, !cfg.readOnly, cfg.access, cfg.hints, StoreReserveVA)
 	if err != nil {
 		closeErr := f.Close()
 		return nil, errors.Join(
@@ -143,62 +223,132 @@ func OpenStore(path string, layout *RecordLayout, opts ...StoreOption) (*Store,
 		)
 	}
 
-	h, err := DecodeHeader(region.Slice(0, HeaderSize))
+	return attachStore(region, layout, cfg)
+}
+
+// OpenStoreWithBacking opens the store already held in b, as written by
+// CreateStoreWithBacking, and validates the schema hash. The store takes
+// ownership of b and closes it on Close, or right away if opening fails.
+func OpenStoreWithBacking(b Backing, layout *RecordLayout, opts ...StoreOption) (*Store, error) {
+	if n := b.Mapped(); n < HeaderSizeV1 {
+		closeErr := b.Close()
+		return nil, errors.Join(
+			fmt.Errorf("mmapforge: %s is too small (%d bytes)", b.Name(), n),
+			fmt.Errorf("mmapforge: close %s: %w", b.Name(), closeErr),
+		)
+	}
+	return attachStore(b, layout, applyOptions(opts))
+}
+
+// attachStore decodes and validates the header in b and returns the store
+// over it. b is closed if anything fails.
+func attachStore(b Backing, layout *RecordLayout, cfg storeConfig) (*Store, error) {
+	name := b.Name()
+	if cfg.oneWriter && cfg.readOnly {
+		closeErr := b.Close()
+		return nil, errors.Join(
+			fmt.Errorf("mmapforge: WithOneWriter and WithReadOnly are mutually exclusive"),
+			fmt.Errorf("mmapforge: close %s: %w", name, closeErr),
+		)
+	}
+
+	h, err := DecodeHeader(b.Slice(0, min(b.Mapped(), HeaderSize)))
 	if err != nil {
-		closeErr := region.Close()
+		closeErr := b.Close()
 		return nil, errors.Join(
 			fmt.Errorf("mmapforge: decode header: %w", err),
-			fmt.Errorf("mmapforge: close %s: %w", path, closeErr),
+			fmt.Errorf("mmapforge: close %s: %w", name, closeErr),
 		)
 	}
 
 	expectedHash := SchemaHash(layout.Descriptors())
 	if h.SchemaHash != expectedHash {
-		closeErr := region.Close()
+		closeErr := b.Close()
 		return nil, errors.Join(
 			fmt.Errorf("mmapforge: schema hash mismatch: expected %x, got %x", expectedHash, h.SchemaHash),
-			fmt.Errorf("mmapforge: close %s: %w", path, closeErr),
+			fmt.Errorf("mmapforge: close %s: %w", name, closeErr),
 		)
 	}
 
-	s := &Store{
-		region:     region,
-		layout:     layout,
-		header:     h,
-		path:       path,
-		writable:   writable,
-		recordSize: int(layout.RecordSize),
+	if cfg.multiWriter && !cfg.readOnly && h.FormatVersion < 2 {
+		closeErr := b.Close()
+		return nil, errors.Join(
+			fmt.Errorf("mmapforge: WithMultiWriter needs format version 2, %s is version %d", name, h.FormatVersion),
+			fmt.Errorf("mmapforge: close %s: %w", name, closeErr),
+		)
 	}
 
-	s.recordCountPtr = (*atomic.Uint64)(unsafe.Pointer(s.region.base + offsetRecordCount))
-	s.capacityPtr = (*atomic.Uint64)(unsafe.Pointer(s.region.base + offsetCapacity))
-
-	if writable {
-		s.recoverSeqlocks()
-	}
+	s := newStore(b, layout, h, cfg)
 
 	if cfg.oneWriter {
-		if cfg.readOnly {
-			return nil, fmt.Errorf("mmapforge: WithOneWriter and WithReadOnly are mutually exclusive")
-		}
 		if err := s.acquireLock(); err != nil {
-			_ = region.Close()
+			_ = b.Close()
 			return nil, err
 		}
 	}
 
+	if s.writable && s.multi && h.Flags&FlagMultiWriter == 0 {
+		h.Flags |= FlagMultiWriter
+		if err := s.flushHeader(); err != nil {
+			closeErr := b.Close()
+			lockErr := s.releaseLock()
+			return nil, errors.Join(
+				fmt.Errorf("mmapforge: flush header: %w", err),
+				fmt.Errorf("mmapforge: close %s: %w", name, closeErr),
+				fmt.Errorf("mmapforge: release lock: %w", lockErr),
+			)
+		}
+	}
+
+	// Another process may be mid-write on a shared multi-writer store, so
+	// only reset stuck seqlocks once this writer is known to be the only one.
+	if s.writable && (!s.multi || cfg.oneWriter) {
+		s.stats.RecoveredSeqlocks = s.recoverSeqlocks()
+	}
+
+	s.claimWriter(cfg.heartbeat)
+	s.startAutoSync(cfg.autoSync)
 	return s, nil
 }
 
+// newStore returns a store over b, whose header is h.
+func newStore(b Backing, layout *RecordLayout, h *Header, cfg storeConfig) *Store {
+	s := &Store{
+		region:     b,
+		base:       b.Base(),
+		layout:     layout,
+		header:     h,
+		path:       b.Name(),
+		writable:   !cfg.readOnly,
+		recordSize: int(layout.RecordSize),
+		dataOff:    h.Size(),
+		backoff:    cfg.backoff,
+		multi:      cfg.multiWriter || h.Flags&FlagMultiWriter != 0,
+	}
+	if cfg.dirtyTracking && s.writable {
+		s.dirty = newDirtyTracker(reservedSize(b))
+	}
+
+	s.recordCountPtr = (*atomic.Uint64)(unsafe.Pointer(s.base + offsetRecordCount))
+	s.capacityPtr = (*atomic.Uint64)(unsafe.Pointer(s.base + offsetCapacity))
+	s.writer = &noWriter
+	if h.FormatVersion >= 2 {
+		s.writer = (*writerRecord)(unsafe.Pointer(s.base + offsetWriterRecord))
+	}
+	return s
+}
+
 // Close syncs and closes the store. All references into store memory become invalid.
 func (s *Store) Close() error {
 	if s.region == nil {
 		return fmt.Errorf("mmapforge: close %s: %w", s.path, ErrClosed)
 	}
+	s.stopAutoSync()
+	s.releaseWriter()
 
 	if s.writable {
 		if err := s.flushHeader(); err != nil {
-			closeErr := s.region.Close()
+			closeErr := s.unmap()
 			lockErr := s.releaseLock()
 			return errors.Join(
 				fmt.Errorf("mmapforge: flush header: %w", err),
@@ -208,7 +358,7 @@ func (s *Store) Close() error {
 		}
 
 		if syncErr := s.region.Sync(); syncErr != nil {
-			closeErr := s.region.Close()
+			closeErr := s.unmap()
 			lockErr := s.releaseLock()
 			return errors.Join(
 				fmt.Errorf("mmapforge: sync: %w", syncErr),
@@ -218,24 +368,80 @@ func (s *Store) Close() error {
 		}
 	}
 
-	err := s.region.Close()
-	s.region = nil
+	err := s.unmap()
 	lockErr := s.releaseLock()
 	return errors.Join(err, lockErr)
 }
 
+// closedCount backs the record count and capacity of closed stores, so
+// Len and Cap read 0 instead of unmapped memory.
+var closedCount atomic.Uint64
+
+// unmap closes the backing and forgets every pointer into it. It holds
+// statsMu so that Stats never sees a half-closed store.
+func (s *Store) unmap() error {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	err := s.region.Close()
+	s.region = nil
+	s.base = 0
+	s.recordCountPtr = &closedCount
+	s.capacityPtr = &closedCount
+	s.writer = &noWriter
+	return err
+}
+
 // Sync flushes the header and dirty pages to disk.
 func (s *Store) Sync() error {
 	if s.region == nil {
 		return fmt.Errorf("mmapforge: sync %s: %w", s.path, ErrClosed)
 	}
+	start := time.Now()
+	err := s.syncAll()
+	s.recordSync(start, err)
+	return err
+}
+
+// syncAll flushes the header and msyncs the whole backing.
+func (s *Store) syncAll() error {
 	if err := s.flushHeader(); err != nil {
 		return err
 	}
 	return s.region.Sync()
 }
 
-// Len returns the number of records in the store.
+// Hints reports which of the WithPrefault, WithTransparentHugePages and
+// WithMlock hints the kernel honoured for the current mapping. Stores that
+// are not backed by a file mapping honour none.
+func (s *Store) Hints() MapHints {
+	r, ok := s.region.(*Region)
+	if !ok {
+		return MapHints{}
+	}
+	return r.Hints()
+}
+
+// Advise applies pattern to the records [from, to), e.g. WillNeed before a
+// batch job touches them or DontNeed to let the kernel drop a cold range
+// from the page cache. Neighbouring records that share a page are
+// affected too. The store's WithAccessPattern is re-applied to the whole
+// mapping when the store grows. Stores without a file mapping return an
+// error wrapping errors.ErrUnsupported.
+func (s *Store) Advise(from, to int, pattern AccessPattern) error {
+	if s.region == nil {
+		return fmt.Errorf("mmapforge: advise %s: %w", s.path, ErrClosed)
+	}
+	if n := s.Len(); from < 0 || from > to || to > n {
+		return fmt.Errorf("mmapforge: advise %s: records [%d, %d): %w (count=%d)", s.path, from, to, ErrOutOfBounds, n)
+	}
+	r, ok := s.region.(*Region)
+	if !ok {
+		return fmt.Errorf("mmapforge: advise %s: %w", s.path, errors.ErrUnsupported)
+	}
+	return r.Advise(s.dataOff+from*s.recordSize, (to-from)*s.recordSize, pattern)
+}
+
+// Len returns the number of records in the store, or 0 once it is closed.
 func (s *Store) Len() int {
 	v := s.recordCountPtr.Load()
 	if v > uint64(math.MaxInt) {
@@ -279,10 +485,15 @@ func (s *Store) Append() (int, error) {
 	return int(idx), nil
 }
 
-// flushHeader writes the live record count back to the header and encodes it.
+// flushHeader writes the live record count and capacity back to the header
+// and encodes it. It holds appendMu so a concurrent grow can't be undone by
+// a stale capacity.
 func (s *Store) flushHeader() error {
+	s.appendMu.Lock()
+	defer s.appendMu.Unlock()
 	s.header.RecordCount = s.recordCountPtr.Load()
-	return encodeHeaderFunc(s.region.Slice(0, HeaderSize), s.header)
+	s.header.Capacity = s.capacityPtr.Load()
+	return encodeHeaderFunc(s.region.Slice(0, s.dataOff), s.header)
 }
 
 // grow doubles the capacity of the store.
@@ -296,14 +507,16 @@ func (s *Store) grow() error {
 	if recSize < 0 {
 		return fmt.Errorf("mmapforge: grow %s: negative record size", s.path)
 	}
-	newSize := uint64(HeaderSize) + newCap*uint64(recSize)
+	newSize := uint64(s.dataOff) + newCap*uint64(recSize)
 	if newSize > uint64(math.MaxInt) {
 		return fmt.Errorf("mmapforge: grow %s: size %d overflows address space", s.path, newSize)
 	}
+	start := time.Now()
 	if err := s.region.Grow(int(newSize)); err != nil {
 		return fmt.Errorf("mmapforge: grow %s: %w", s.path, err)
 	}
 	s.capacityPtr.Store(newCap)
+	s.recordGrow(start)
 	return nil
 }
 
@@ -313,12 +526,44 @@ func (s *Store) fieldSlice(idx int, fieldOffset, fieldSize uint32) ([]byte, erro
 	}
 	count := s.recordCountPtr.Load()
 	if idx < 0 || uint64(idx) >= count {
-		return nil, fmt.Errorf("mmapforge: record %d: %w (count=%d)", idx, ErrOutOfBounds, count)
+		return nil, s.fieldError(idx, fieldOffset, 0, 0, fmt.Errorf("%w (count=%d)", ErrOutOfBounds, count))
 	}
-	off := HeaderSize + idx*s.recordSize + int(fieldOffset)
+	off := s.dataOff + idx*s.recordSize + int(fieldOffset)
 	return s.region.Slice(off, int(fieldSize)), nil
 }
 
+// fieldError builds a *FieldError for the field at offset, resolving its
+// names from the store layout. Only called on error paths.
+func (s *Store) fieldError(idx int, offset uint32, n int, maxSize uint32, err error) *FieldError {
+	fe := &FieldError{
+		Index:  idx,
+		Offset: offset,
+		Len:    n,
+		Max:    maxSize,
+		Err:    err,
+	}
+	if f, ok := s.layout.fieldAt(offset); ok {
+		fe.Field = f.Name
+		fe.GoName = f.GoName
+	}
+	return fe
+}
+
+// RecoverSeqlocks resets every stuck (odd) seqlock counter to the next
+// even value and returns how many it reset. OpenStore does this itself
+// unless the store is opened WithMultiWriter without WithOneWriter; call
+// it only when no other writer can be mid-write, e.g. once Writer reports
+// the writer dead.
+func (s *Store) RecoverSeqlocks() (int, error) {
+	if s.region == nil {
+		return 0, fmt.Errorf("mmapforge: recover seqlocks %s: %w", s.path, ErrClosed)
+	}
+	if !s.writable {
+		return 0, fmt.Errorf("mmapforge: recover seqlocks %s: %w", s.path, ErrReadOnly)
+	}
+	return s.recoverSeqlocks(), nil
+}
+
 // recoverSeqlocks scans all records and resets any stuck (odd) seqlock
 // counters to the next even value. This recovers from a process crash
 // that happened mid-write, preventing readers from spinning forever.
@@ -327,8 +572,8 @@ func (s *Store) recoverSeqlocks() int {
 	recovered := 0
 
 	for i := uint64(0); i < count; i++ {
-		off := uintptr(HeaderSize) + uintptr(i)*uintptr(s.recordSize)
-		ptr := (*atomic.Uint64)(unsafe.Pointer(s.region.base + off))
+		off := uintptr(s.dataOff) + uintptr(i)*uintptr(s.recordSize)
+		ptr := (*atomic.Uint64)(unsafe.Pointer(s.base + off))
 		seq := ptr.Load()
 		if seq&1 != 0 {
 			ptr.Store(seq + 1)
@@ -339,8 +584,12 @@ func (s *Store) recoverSeqlocks() int {
 	return recovered
 }
 
-// acquireLock creates/opens the sidecar .lock file and acquires an exclusive flock.
+// acquireLock creates/opens the sidecar .lock file and acquires an
+// exclusive flock on it.
 func (s *Store) acquireLock() error {
+	if _, ok := s.region.(*Region); !ok {
+		return fmt.Errorf("mmapforge: WithOneWriter needs a file-backed store, not %s", s.path)
+	}
 	lockPath := s.path + ".lock"
 	lf, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
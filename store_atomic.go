@@ -0,0 +1,261 @@
+//go:build unix
+
+package mmapforge
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// The Atomic* methods update a single 4- or 8-byte integer field with
+// sync/atomic instead of a full record write, for counters and state
+// machines that many goroutines or processes touch at once. Loads never
+// wait. A mutation takes the record's seqlock with a compare-and-swap,
+// waiting like a WithMultiWriter SeqBeginWrite while another writer holds
+// it, and releases it after changing the field, so the counter advances by
+// two and readers that overlapped the change retry. A compare-and-swap
+// that fails leaves the counter as it was.
+//
+// Because mutations take the seqlock, they never interleave with a
+// seqlock write of the same record (a generated Set, SetFields or
+// TryUpdate) on a WithMultiWriter store. On a single-writer store they
+// count as writers: they are safe against each other, but must not run
+// concurrently with that store's seqlock writes.
+//
+// sync/atomic works in the host's byte order, so the Atomic* methods
+// return an error wrapping errors.ErrUnsupported on big-endian hosts.
+
+// AtomicLoadInt32 atomically loads an int32 from record idx at the given byte offset.
+func (s *Store) AtomicLoadInt32(idx int, offset uint32) (int32, error) {
+	p, err := s.atomicField(idx, offset, 4, false)
+	if err != nil {
+		return 0, err
+	}
+	return atomic.LoadInt32((*int32)(p)), nil
+}
+
+// AtomicStoreInt32 atomically stores an int32 in record idx at the given byte offset.
+func (s *Store) AtomicStoreInt32(idx int, offset uint32, val int32) error {
+	p, err := s.atomicField(idx, offset, 4, true)
+	if err != nil {
+		return err
+	}
+	seq := s.atomicBegin(idx)
+	atomic.StoreInt32((*int32)(p), val)
+	s.atomicEnd(idx, seq, true)
+	return nil
+}
+
+// AtomicAddInt32 atomically adds delta to the int32 in record idx at the
+// given byte offset and returns the new value.
+func (s *Store) AtomicAddInt32(idx int, offset uint32, delta int32) (int32, error) {
+	p, err := s.atomicField(idx, offset, 4, true)
+	if err != nil {
+		return 0, err
+	}
+	seq := s.atomicBegin(idx)
+	v := atomic.AddInt32((*int32)(p), delta)
+	s.atomicEnd(idx, seq, true)
+	return v, nil
+}
+
+// AtomicCompareAndSwapInt32 atomically replaces the int32 in record idx at
+// the given byte offset with new if it equals old, and reports whether it did.
+func (s *Store) AtomicCompareAndSwapInt32(idx int, offset uint32, old, new int32) (bool, error) {
+	p, err := s.atomicField(idx, offset, 4, true)
+	if err != nil {
+		return false, err
+	}
+	seq := s.atomicBegin(idx)
+	swapped := atomic.CompareAndSwapInt32((*int32)(p), old, new)
+	s.atomicEnd(idx, seq, swapped)
+	return swapped, nil
+}
+
+// AtomicLoadUint32 atomically loads a uint32 from record idx at the given byte offset.
+func (s *Store) AtomicLoadUint32(idx int, offset uint32) (uint32, error) {
+	p, err := s.atomicField(idx, offset, 4, false)
+	if err != nil {
+		return 0, err
+	}
+	return atomic.LoadUint32((*uint32)(p)), nil
+}
+
+// AtomicStoreUint32 atomically stores a uint32 in record idx at the given byte offset.
+func (s *Store) AtomicStoreUint32(idx int, offset uint32, val uint32) error {
+	p, err := s.atomicField(idx, offset, 4, true)
+	if err != nil {
+		return err
+	}
+	seq := s.atomicBegin(idx)
+	atomic.StoreUint32((*uint32)(p), val)
+	s.atomicEnd(idx, seq, true)
+	return nil
+}
+
+// AtomicAddUint32 atomically adds delta to the uint32 in record idx at the
+// given byte offset and returns the new value.
+func (s *Store) AtomicAddUint32(idx int, offset uint32, delta uint32) (uint32, error) {
+	p, err := s.atomicField(idx, offset, 4, true)
+	if err != nil {
+		return 0, err
+	}
+	seq := s.atomicBegin(idx)
+	v := atomic.AddUint32((*uint32)(p), delta)
+	s.atomicEnd(idx, seq, true)
+	return v, nil
+}
+
+// AtomicCompareAndSwapUint32 atomically replaces the uint32 in record idx at
+// the given byte offset with new if it equals old, and reports whether it did.
+func (s *Store) AtomicCompareAndSwapUint32(idx int, offset uint32, old, new uint32) (bool, error) {
+	p, err := s.atomicField(idx, offset, 4, true)
+	if err != nil {
+		return false, err
+	}
+	seq := s.atomicBegin(idx)
+	swapped := atomic.CompareAndSwapUint32((*uint32)(p), old, new)
+	s.atomicEnd(idx, seq, swapped)
+	return swapped, nil
+}
+
+// AtomicLoadInt64 atomically loads an int64 from record idx at the given byte offset.
+func (s *Store) AtomicLoadInt64(idx int, offset uint32) (int64, error) {
+	p, err := s.atomicField(idx, offset, 8, false)
+	if err != nil {
+		return 0, err
+	}
+	return atomic.LoadInt64((*int64)(p)), nil
+}
+
+// AtomicStoreInt64 atomically stores an int64 in record idx at the given byte offset.
+func (s *Store) AtomicStoreInt64(idx int, offset uint32, val int64) error {
+	p, err := s.atomicField(idx, offset, 8, true)
+	if err != nil {
+		return err
+	}
+	seq := s.atomicBegin(idx)
+	atomic.StoreInt64((*int64)(p), val)
+	s.atomicEnd(idx, seq, true)
+	return nil
+}
+
+// AtomicAddInt64 atomically adds delta to the int64 in record idx at the
+// given byte offset and returns the new value.
+func (s *Store) AtomicAddInt64(idx int, offset uint32, delta int64) (int64, error) {
+	p, err := s.atomicField(idx, offset, 8, true)
+	if err != nil {
+		return 0, err
+	}
+	seq := s.atomicBegin(idx)
+	v := atomic.AddInt64((*int64)(p), delta)
+	s.atomicEnd(idx, seq, true)
+	return v, nil
+}
+
+// AtomicCompareAndSwapInt64 atomically replaces the int64 in record idx at
+// the given byte offset with new if it equals old, and reports whether it did.
+func (s *Store) AtomicCompareAndSwapInt64(idx int, offset uint32, old, new int64) (bool, error) {
+	p, err := s.atomicField(idx, offset, 8, true)
+	if err != nil {
+		return false, err
+	}
+	seq := s.atomicBegin(idx)
+	swapped := atomic.CompareAndSwapInt64((*int64)(p), old, new)
+	s.atomicEnd(idx, seq, swapped)
+	return swapped, nil
+}
+
+// AtomicLoadUint64 atomically loads a uint64 from record idx at the given byte offset.
+func (s *Store) AtomicLoadUint64(idx int, offset uint32) (uint64, error) {
+	p, err := s.atomicField(idx, offset, 8, false)
+	if err != nil {
+		return 0, err
+	}
+	return atomic.LoadUint64((*uint64)(p)), nil
+}
+
+// AtomicStoreUint64 atomically stores a uint64 in record idx at the given byte offset.
+func (s *Store) AtomicStoreUint64(idx int, offset uint32, val uint64) error {
+	p, err := s.atomicField(idx, offset, 8, true)
+	if err != nil {
+		return err
+	}
+	seq := s.atomicBegin(idx)
+	atomic.StoreUint64((*uint64)(p), val)
+	s.atomicEnd(idx, seq, true)
+	return nil
+}
+
+// AtomicAddUint64 atomically adds delta to the uint64 in record idx at the
+// given byte offset and returns the new value.
+func (s *Store) AtomicAddUint64(idx int, offset uint32, delta uint64) (uint64, error) {
+	p, err := s.atomicField(idx, offset, 8, true)
+	if err != nil {
+		return 0, err
+	}
+	seq := s.atomicBegin(idx)
+	v := atomic.AddUint64((*uint64)(p), delta)
+	s.atomicEnd(idx, seq, true)
+	return v, nil
+}
+
+// AtomicCompareAndSwapUint64 atomically replaces the uint64 in record idx at
+// the given byte offset with new if it equals old, and reports whether it did.
+func (s *Store) AtomicCompareAndSwapUint64(idx int, offset uint32, old, new uint64) (bool, error) {
+	p, err := s.atomicField(idx, offset, 8, true)
+	if err != nil {
+		return false, err
+	}
+	seq := s.atomicBegin(idx)
+	swapped := atomic.CompareAndSwapUint64((*uint64)(p), old, new)
+	s.atomicEnd(idx, seq, swapped)
+	return swapped, nil
+}
+
+// atomicField returns a pointer to the size-byte field at offset in record
+// idx, checking bounds, alignment and, for mutations, that the store is
+// writable. Layouts from ComputeLayout are always naturally aligned.
+func (s *Store) atomicField(idx int, offset, size uint32, write bool) (unsafe.Pointer, error) {
+	if !littleEndian {
+		return nil, s.fieldError(idx, offset, 0, 0, fmt.Errorf("big-endian host: %w", errors.ErrUnsupported))
+	}
+	if write && !s.writable {
+		return nil, s.fieldError(idx, offset, 0, 0, ErrReadOnly)
+	}
+	b, err := s.fieldSlice(idx, offset, size)
+	if err != nil {
+		return nil, err
+	}
+	p := unsafe.Pointer(&b[0])
+	if uintptr(p)%uintptr(size) != 0 {
+		return nil, s.fieldError(idx, offset, 0, 0, fmt.Errorf("%w (want %d-byte alignment)", ErrMisaligned, size))
+	}
+	return p, nil
+}
+
+// atomicBegin takes record idx's seqlock for an atomic field mutation and
+// returns the even counter value it replaced.
+func (s *Store) atomicBegin(idx int) uint64 {
+	ptr := s.seqPtr(idx)
+	if ptr == nil {
+		return 0
+	}
+	return s.seqAcquire(ptr)
+}
+
+// atomicEnd releases the seqlock taken by atomicBegin. If the mutation
+// changed the field the counter moves on to the next even value and the
+// record is marked dirty; otherwise it is restored to seq, so readers don't
+// retry for nothing.
+func (s *Store) atomicEnd(idx int, seq uint64, changed bool) {
+	if changed {
+		s.SeqEndWrite(idx)
+		return
+	}
+	if ptr := s.seqPtr(idx); ptr != nil {
+		ptr.Store(seq)
+	}
+}
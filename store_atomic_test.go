@@ -0,0 +1,280 @@
+package mmapforge
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testAtomicLayout() *RecordLayout {
+	layout, err := ComputeLayout([]FieldDef{
+		{Name: "i32", Type: FieldInt32},
+		{Name: "u32", Type: FieldUint32},
+		{Name: "i64", Type: FieldInt64},
+		{Name: "u64", Type: FieldUint64},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return layout
+}
+
+func TestAtomic_AllTypes(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testAtomicLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := func(name string) uint32 {
+		f, _ := s.Field(name)
+		return f.Offset
+	}
+
+	if err := s.AtomicStoreInt32(idx, off("i32"), -5); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.AtomicAddInt32(idx, off("i32"), 2); err != nil || v != -3 {
+		t.Errorf("AtomicAddInt32 = %d, %v", v, err)
+	}
+	if ok, err := s.AtomicCompareAndSwapInt32(idx, off("i32"), -3, 7); err != nil || !ok {
+		t.Errorf("AtomicCompareAndSwapInt32 = %v, %v", ok, err)
+	}
+	if v, err := s.AtomicLoadInt32(idx, off("i32")); err != nil || v != 7 {
+		t.Errorf("AtomicLoadInt32 = %d, %v", v, err)
+	}
+
+	if err := s.AtomicStoreUint32(idx, off("u32"), 5); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.AtomicAddUint32(idx, off("u32"), 2); err != nil || v != 7 {
+		t.Errorf("AtomicAddUint32 = %d, %v", v, err)
+	}
+	if ok, err := s.AtomicCompareAndSwapUint32(idx, off("u32"), 1, 9); err != nil || ok {
+		t.Errorf("AtomicCompareAndSwapUint32 with a stale old value = %v, %v", ok, err)
+	}
+	if v, err := s.AtomicLoadUint32(idx, off("u32")); err != nil || v != 7 {
+		t.Errorf("AtomicLoadUint32 = %d, %v", v, err)
+	}
+
+	if err := s.AtomicStoreInt64(idx, off("i64"), -1<<40); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.AtomicAddInt64(idx, off("i64"), 1); err != nil || v != -1<<40+1 {
+		t.Errorf("AtomicAddInt64 = %d, %v", v, err)
+	}
+	if ok, err := s.AtomicCompareAndSwapInt64(idx, off("i64"), -1<<40+1, 3); err != nil || !ok {
+		t.Errorf("AtomicCompareAndSwapInt64 = %v, %v", ok, err)
+	}
+	if v, err := s.AtomicLoadInt64(idx, off("i64")); err != nil || v != 3 {
+		t.Errorf("AtomicLoadInt64 = %d, %v", v, err)
+	}
+
+	if err := s.AtomicStoreUint64(idx, off("u64"), 1<<40); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.AtomicAddUint64(idx, off("u64"), 1); err != nil || v != 1<<40+1 {
+		t.Errorf("AtomicAddUint64 = %d, %v", v, err)
+	}
+	if ok, err := s.AtomicCompareAndSwapUint64(idx, off("u64"), 1<<40+1, 4); err != nil || !ok {
+		t.Errorf("AtomicCompareAndSwapUint64 = %v, %v", ok, err)
+	}
+	if v, err := s.ReadUint64(idx, off("u64")); err != nil || v != 4 {
+		t.Errorf("ReadUint64 = %d, %v", v, err)
+	}
+
+	// 12 mutations, one failed CAS: each successful one bumps by two.
+	if seq := s.SeqReadBegin(idx); seq != 2*11 {
+		t.Errorf("seq = %d, want %d", seq, 2*11)
+	}
+}
+
+func TestAtomic_WaitsForWriter(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := s.layout.Fields[0].Offset
+
+	s.SeqBeginWrite(idx)
+	done := make(chan error)
+	go func() {
+		_, err := s.AtomicAddUint64(idx, off, 1)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("AtomicAddUint64 returned (%v) while a write was in progress", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	s.SeqEndWrite(idx)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if seq := s.SeqReadBegin(idx); seq != 4 {
+		t.Errorf("seq = %d, want 4", seq)
+	}
+	if err := s.SeqTryBeginWrite(idx); err != nil {
+		t.Errorf("SeqTryBeginWrite after an atomic update: %v", err)
+	}
+	s.SeqEndWrite(idx)
+}
+
+func TestAtomic_FailedCompareAndSwapKeepsSeq(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := s.layout.Fields[0].Offset
+
+	if ok, err := s.AtomicCompareAndSwapUint64(idx, off, 1, 2); err != nil || ok {
+		t.Fatalf("CompareAndSwap = %v, %v, want false", ok, err)
+	}
+	if seq := s.SeqReadBegin(idx); seq != 0 {
+		t.Errorf("seq = %d, want 0", seq)
+	}
+}
+
+func TestAtomic_ConcurrentWithSeqlockWrites(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithMultiWriter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := s.layout.Fields[0].Offset
+
+	// Half the workers increment with a plain read-modify-write under the
+	// seqlock, as TryUpdate does; no increment may be lost to the others.
+	const workers, perWorker = 8, 500
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				if w%2 == 0 {
+					if _, err := s.AtomicAddUint64(idx, off, 1); err != nil {
+						t.Error(err)
+						return
+					}
+					continue
+				}
+				s.SeqBeginWrite(idx)
+				v, err := s.ReadUint64(idx, off)
+				if err == nil {
+					err = s.WriteUint64(idx, off, v+1)
+				}
+				s.SeqEndWrite(idx)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := s.AtomicLoadUint64(idx, off); v != workers*perWorker {
+		t.Errorf("counter = %d, want %d", v, workers*perWorker)
+	}
+}
+
+func TestAtomic_Errors(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AtomicAddUint64(0, 8, 1); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("empty store: err = %v, want ErrOutOfBounds", err)
+	}
+	if _, err := s.Append(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.AtomicLoadUint64(0, 12)
+	var fe *FieldError
+	if !errors.Is(err, ErrMisaligned) || !errors.As(err, &fe) {
+		t.Errorf("misaligned: err = %v, want a *FieldError wrapping ErrMisaligned", err)
+	}
+
+	r, err := OpenStore(path, testLayout(), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := r.AtomicLoadUint64(0, 8); err != nil || v != 0 {
+		t.Errorf("read-only load = %d, %v", v, err)
+	}
+	if err := r.AtomicStoreUint64(0, 8, 1); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("read-only store: err = %v, want ErrReadOnly", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AtomicLoadInt32(0, 8); !errors.Is(err, ErrClosed) {
+		t.Errorf("closed: err = %v, want ErrClosed", err)
+	}
+}
+
+func TestAtomic_MarksDirty(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithDirtyTracking())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.Append(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SyncDirty(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AtomicAddUint64(0, 8, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.DirtyPages(); n != 1 {
+		t.Errorf("DirtyPages = %d, want 1", n)
+	}
+}
+
+func TestAtomic_ConcurrentAdds(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := s.layout.Fields[0].Offset
+
+	const workers, perWorker = 8, 1000
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				if _, err := s.AtomicAddUint64(idx, off, 1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := s.AtomicLoadUint64(idx, off); v != workers*perWorker {
+		t.Errorf("counter = %d, want %d", v, workers*perWorker)
+	}
+}
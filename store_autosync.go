@@ -0,0 +1,64 @@
+package mmapforge
+
+import "time"
+
+// bgLoop is a goroutine that calls fn every interval until halted. It
+// backs WithAutoSync and WithWriterHeartbeat.
+type bgLoop struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func startLoop(interval time.Duration, fn func()) *bgLoop {
+	l := &bgLoop{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(l.done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-t.C:
+				fn()
+			}
+		}
+	}()
+	return l
+}
+
+// halt stops the loop and waits for an in-flight fn to return.
+func (l *bgLoop) halt() {
+	close(l.stop)
+	<-l.done
+}
+
+// startAutoSync starts syncing s every interval. No-op for read-only
+// stores and intervals <= 0.
+func (s *Store) startAutoSync(interval time.Duration) {
+	if interval <= 0 || !s.writable {
+		return
+	}
+	s.stats.AutoSyncInterval = interval
+	s.autoSync = startLoop(interval, func() {
+		// The error lands in Stats; the next tick tries again.
+		if s.dirty != nil {
+			_ = s.SyncDirty()
+		} else {
+			_ = s.SyncRange(0, s.Len())
+		}
+	})
+}
+
+// stopAutoSync stops the goroutine and waits for an in-flight sync to
+// finish, so Close never unmaps under it.
+func (s *Store) stopAutoSync() {
+	if s.autoSync == nil {
+		return
+	}
+	s.autoSync.halt()
+	s.autoSync = nil
+}
@@ -0,0 +1,137 @@
+package mmapforge
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitForSyncs polls s.Stats until at least n syncs have run.
+func waitForSyncs(t *testing.T, s *Store, n uint64) StoreStats {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st := s.Stats()
+		if st.Syncs >= n {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d syncs after 5s, want %d", st.Syncs, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWithAutoSync(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithAutoSync(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, _ := s.Append()
+	if err := s.WriteUint64(idx, 8, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	st := waitForSyncs(t, s, 2)
+	if st.AutoSyncInterval != time.Millisecond {
+		t.Errorf("AutoSyncInterval = %v", st.AutoSyncInterval)
+	}
+	if st.LastSync.IsZero() || st.LastSyncErr != nil || st.SyncErrors != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if s.autoSync != nil {
+		t.Error("Close left the auto-sync goroutine running")
+	}
+	after := s.Stats().Syncs
+	time.Sleep(10 * time.Millisecond)
+	if got := s.Stats().Syncs; got != after {
+		t.Errorf("syncs kept running after Close: %d -> %d", after, got)
+	}
+}
+
+func TestWithAutoSync_Dirty(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithDirtyTracking(), WithAutoSync(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	idx, _ := s.Append()
+	s.SeqBeginWrite(idx)
+	s.SeqEndWrite(idx)
+
+	before := s.Stats().Syncs
+	waitForSyncs(t, s, before+2)
+	if n := s.DirtyPages(); n != 0 {
+		t.Errorf("DirtyPages = %d after auto-sync", n)
+	}
+}
+
+func TestWithAutoSync_Error(t *testing.T) {
+	orig := msyncSyscall
+	msyncSyscall = func(_, _, _ uintptr) error { return syscall.EIO }
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithAutoSync(time.Millisecond))
+	if err != nil {
+		msyncSyscall = orig
+		t.Fatal(err)
+	}
+
+	st := waitForSyncs(t, s, 1)
+	s.stopAutoSync()
+	msyncSyscall = orig
+	if !errors.Is(st.LastSyncErr, syscall.EIO) || st.SyncErrors == 0 {
+		t.Errorf("stats = %+v, want EIO recorded", st)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestWithAutoSync_Ignored(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1, WithAutoSync(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.autoSync != nil {
+		t.Error("zero interval started a goroutine")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := OpenStore(path, testLayout(), WithReadOnly(), WithAutoSync(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	if ro.autoSync != nil || ro.Stats().AutoSyncInterval != 0 {
+		t.Error("read-only store started auto-sync")
+	}
+}
+
+func TestStore_StatsCountsSyncs(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	appendN(t, s, 3)
+
+	_ = s.Sync()
+	_ = s.SyncRange(0, 3)
+	_ = s.SyncAsync()
+	_ = s.SyncDirty()
+	if st := s.Stats(); st.Syncs != 4 || st.SyncErrors != 0 {
+		t.Errorf("stats = %+v, want 4 syncs", st)
+	}
+
+	orig := msyncSyscall
+	msyncSyscall = func(_, _, _ uintptr) error { return syscall.EIO }
+	_ = s.Sync()
+	msyncSyscall = orig
+	if st := s.Stats(); st.Syncs != 5 || st.SyncErrors != 1 || !errors.Is(st.LastSyncErr, syscall.EIO) {
+		t.Errorf("stats = %+v, want the failed sync recorded", st)
+	}
+}
@@ -0,0 +1,148 @@
+//go:build unix
+
+package mmapforge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"iter"
+	"math"
+)
+
+// DiffKind classifies a FieldDiff.
+type DiffKind uint8
+
+const (
+	// DiffChanged is a field whose value differs between the two stores.
+	DiffChanged DiffKind = iota
+	// DiffAppended covers records [Index, Index+Count) present only in b.
+	DiffAppended
+	// DiffTruncated covers records [Index, Index+Count) present only in a.
+	DiffTruncated
+)
+
+var diffKindNames = [...]string{
+	DiffChanged:   "changed",
+	DiffAppended:  "appended",
+	DiffTruncated: "truncated",
+}
+
+func (k DiffKind) String() string {
+	if int(k) < len(diffKindNames) {
+		return diffKindNames[k]
+	}
+	return fmt.Sprintf("DiffKind(%d)", k)
+}
+
+// FieldDiff is one difference reported by DiffStores. For DiffChanged, Field
+// names the mmap field and Old and New hold its values in a and b. For tail
+// kinds, Index and Count give the range of extra records. A non-nil Err ends
+// the sequence.
+type FieldDiff struct {
+	Kind  DiffKind
+	Index int
+	Count int
+	Field string
+	Old   any
+	New   any
+	Err   error
+}
+
+// DiffStores walks the records a and b have in common using their shared
+// layout and yields every field whose value differs, followed by one
+// summary for any appended or truncated tail. The stores must have the same
+// schema hash. String and []byte values are zero-copy and valid only until
+// the stores are closed.
+func DiffStores(a, b *Store) iter.Seq[FieldDiff] {
+	return func(yield func(FieldDiff) bool) {
+		if a.region == nil || b.region == nil {
+			yield(FieldDiff{Err: fmt.Errorf("mmapforge: diff: %w", ErrClosed)})
+			return
+		}
+		if a.header.SchemaHash != b.header.SchemaHash || a.recordSize != b.recordSize {
+			yield(FieldDiff{Err: fmt.Errorf("mmapforge: diff %s and %s: %w", a.path, b.path, ErrSchemaMismatch)})
+			return
+		}
+
+		na, nb := a.Len(), b.Len()
+		var bufA, bufB []byte
+		for i := range min(na, nb) {
+			var err error
+			if bufA, err = a.recordBytes(i, bufA); err != nil {
+				yield(FieldDiff{Index: i, Err: err})
+				return
+			}
+			if bufB, err = b.recordBytes(i, bufB); err != nil {
+				yield(FieldDiff{Index: i, Err: err})
+				return
+			}
+			if bytes.Equal(bufA, bufB) {
+				continue
+			}
+
+			ma, err := a.GetRecordMap(i)
+			if err != nil {
+				yield(FieldDiff{Index: i, Err: err})
+				return
+			}
+			mb, err := b.GetRecordMap(i)
+			if err != nil {
+				yield(FieldDiff{Index: i, Err: err})
+				return
+			}
+			for _, f := range a.layout.Fields {
+				if valuesEqual(ma[f.Name], mb[f.Name]) {
+					continue
+				}
+				if !yield(FieldDiff{Kind: DiffChanged, Index: i, Field: f.Name, Old: ma[f.Name], New: mb[f.Name]}) {
+					return
+				}
+			}
+		}
+
+		switch {
+		case nb > na:
+			yield(FieldDiff{Kind: DiffAppended, Index: na, Count: nb - na})
+		case na > nb:
+			yield(FieldDiff{Kind: DiffTruncated, Index: nb, Count: na - nb})
+		}
+	}
+}
+
+// recordBytes copies the user fields of record idx (everything after the
+// seqlock) into dst under the seqlock and returns it.
+func (s *Store) recordBytes(idx int, dst []byte) ([]byte, error) {
+	off := s.dataOff + idx*s.recordSize + SeqFieldSize
+	n := s.recordSize - SeqFieldSize
+	for attempt := 0; ; attempt++ {
+		seq := s.SeqReadBegin(idx)
+		if seq&1 == 0 {
+			dst = append(dst[:0], s.region.Slice(off, n)...)
+			if s.SeqReadValid(idx, seq) {
+				return dst, nil
+			}
+		}
+		if err := s.SeqReadRetry(context.Background(), idx, attempt); err != nil {
+			return dst, err
+		}
+	}
+}
+
+// valuesEqual compares two readAny results. Floats compare by bit pattern
+// so an unchanged NaN is not reported as a difference.
+func valuesEqual(x, y any) bool {
+	switch xv := x.(type) {
+	case float32:
+		yv, ok := y.(float32)
+		return ok && math.Float32bits(xv) == math.Float32bits(yv)
+	case float64:
+		yv, ok := y.(float64)
+		return ok && math.Float64bits(xv) == math.Float64bits(yv)
+	case []byte:
+		yv, ok := y.([]byte)
+		return ok && bytes.Equal(xv, yv)
+	default:
+		return x == y
+	}
+}
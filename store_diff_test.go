@@ -0,0 +1,204 @@
+//go:build unix
+
+package mmapforge
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+// diffPair creates two stores with the dynamic layout and n records each.
+func diffPair(t *testing.T, n int) (*Store, *Store) {
+	t.Helper()
+	layout := testDynamicLayout()
+	a, err := CreateStore(tempPath(t), layout, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CreateStore(tempPath(t), layout, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	for _, s := range []*Store{a, b} {
+		for i := 0; i < n; i++ {
+			if _, err := s.Append(); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SetAny(i, "name", "rec"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return a, b
+}
+
+func TestDiffStores_Identical(t *testing.T) {
+	a, b := diffPair(t, 5)
+	if got := slices.Collect(DiffStores(a, b)); len(got) != 0 {
+		t.Errorf("diffs = %+v, want none", got)
+	}
+}
+
+func TestDiffStores_Changed(t *testing.T) {
+	a, b := diffPair(t, 5)
+	for _, set := range []struct {
+		idx   int
+		field string
+		v     any
+	}{
+		{1, "u32", 7},
+		{1, "name", "other"},
+		{3, "data", []byte{1, 2}},
+		{4, "f64", math.NaN()},
+	} {
+		if err := b.SetAny(set.idx, set.field, set.v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Same NaN on both sides is not a difference.
+	if err := a.SetAny(4, "f64", math.NaN()); err != nil {
+		t.Fatal(err)
+	}
+	// A stale byte past the string length is not a difference either.
+	if err := a.SetAny(2, "name", "recX"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetAny(2, "name", "rec"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := slices.Collect(DiffStores(a, b))
+	if len(got) != 3 {
+		t.Fatalf("diffs = %+v, want 3", got)
+	}
+	if d := got[0]; d.Kind != DiffChanged || d.Index != 1 || d.Field != "u32" || d.Old != uint32(0) || d.New != uint32(7) {
+		t.Errorf("diff 0 = %+v", d)
+	}
+	if d := got[1]; d.Index != 1 || d.Field != "name" || d.Old != "rec" || d.New != "other" {
+		t.Errorf("diff 1 = %+v", d)
+	}
+	if d := got[2]; d.Index != 3 || d.Field != "data" || len(d.Old.([]byte)) != 0 || string(d.New.([]byte)) != "\x01\x02" {
+		t.Errorf("diff 2 = %+v", d)
+	}
+}
+
+func TestDiffStores_Tails(t *testing.T) {
+	a, b := diffPair(t, 3)
+	for i := 0; i < 4; i++ {
+		if _, err := b.Append(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := slices.Collect(DiffStores(a, b))
+	want := []FieldDiff{{Kind: DiffAppended, Index: 3, Count: 4}}
+	if !slices.Equal(got, want) {
+		t.Errorf("a→b = %+v, want %+v", got, want)
+	}
+
+	got = slices.Collect(DiffStores(b, a))
+	want = []FieldDiff{{Kind: DiffTruncated, Index: 3, Count: 4}}
+	if !slices.Equal(got, want) {
+		t.Errorf("b→a = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffStores_EarlyBreak(t *testing.T) {
+	a, b := diffPair(t, 3)
+	for i := 0; i < 3; i++ {
+		if err := b.SetAny(i, "i8", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := 0
+	for range DiffStores(a, b) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("iterations = %d, want 1", n)
+	}
+}
+
+func TestDiffStores_SchemaMismatch(t *testing.T) {
+	a, _ := diffPair(t, 1)
+	other, err := CreateStore(tempPath(t), testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	got := slices.Collect(DiffStores(a, other))
+	if len(got) != 1 || !errors.Is(got[0].Err, ErrSchemaMismatch) {
+		t.Errorf("diffs = %+v, want one ErrSchemaMismatch", got)
+	}
+}
+
+func TestDiffStores_Closed(t *testing.T) {
+	a, b := diffPair(t, 1)
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got := slices.Collect(DiffStores(a, b))
+	if len(got) != 1 || !errors.Is(got[0].Err, ErrClosed) {
+		t.Errorf("diffs = %+v, want one ErrClosed", got)
+	}
+}
+
+func TestDiffStores_ReadError(t *testing.T) {
+	a, b := diffPair(t, 2)
+	// Corrupt the string length prefix of record 1 in b.
+	f, _ := b.Field("name")
+	off := HeaderSize + b.recordSize + int(f.Offset)
+	b.region.Slice(off, 4)[0] = 0xff
+
+	got := slices.Collect(DiffStores(a, b))
+	if len(got) != 1 || got[0].Index != 1 || !errors.Is(got[0].Err, ErrCorrupted) {
+		t.Errorf("diffs = %+v, want one ErrCorrupted at record 1", got)
+	}
+
+	got = slices.Collect(DiffStores(b, a))
+	if len(got) != 1 || !errors.Is(got[0].Err, ErrCorrupted) {
+		t.Errorf("reversed diffs = %+v, want one ErrCorrupted", got)
+	}
+}
+
+func TestDiffKind_String(t *testing.T) {
+	for k, want := range map[DiffKind]string{
+		DiffChanged:   "changed",
+		DiffAppended:  "appended",
+		DiffTruncated: "truncated",
+		DiffKind(9):   "DiffKind(9)",
+	} {
+		if got := k.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestValuesEqual(t *testing.T) {
+	tests := []struct {
+		x, y any
+		want bool
+	}{
+		{float32(1), float32(1), true},
+		{float32(1), 1.0, false},
+		{math.NaN(), math.NaN(), true},
+		{0.0, math.Copysign(0, -1), false},
+		{[]byte("a"), []byte("a"), true},
+		{[]byte("a"), "a", false},
+		{"a", "a", true},
+		{int8(1), int16(1), false},
+	}
+	for _, tt := range tests {
+		if got := valuesEqual(tt.x, tt.y); got != tt.want {
+			t.Errorf("valuesEqual(%v, %v) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+//go:build unix
+
+package mmapforge
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Field returns the layout of the field with the given mmap name.
+func (s *Store) Field(name string) (FieldLayout, bool) {
+	for _, f := range s.layout.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return FieldLayout{}, false
+}
+
+// GetAny reads the named field of record idx under the seqlock and returns it
+// as its natural Go type (bool, int8 ... float64, string or []byte).
+// String and []byte values are zero-copy and valid only until Close.
+func (s *Store) GetAny(idx int, name string) (any, error) {
+	f, ok := s.Field(name)
+	if !ok {
+		return nil, fmt.Errorf("mmapforge: field %q: %w", name, ErrUnknownField)
+	}
+	if _, err := s.fieldSlice(idx, f.Offset, f.Size); err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		seq := s.SeqReadBegin(idx)
+		if seq&1 == 0 {
+			v, err := s.readAny(idx, f)
+			if err != nil {
+				return nil, err
+			}
+			if s.SeqReadValid(idx, seq) {
+				return v, nil
+			}
+		}
+		if err := s.SeqReadRetry(context.Background(), idx, attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SetAny writes value into the named field of record idx under the seqlock.
+// The value is coerced to the field type: any integer or float converts to a
+// numeric field if it fits without loss, strings are parsed for numeric and
+// bool fields, and string and []byte convert to each other.
+func (s *Store) SetAny(idx int, name string, value any) error {
+	f, ok := s.Field(name)
+	if !ok {
+		return fmt.Errorf("mmapforge: field %q: %w", name, ErrUnknownField)
+	}
+	if !s.writable {
+		return fmt.Errorf("mmapforge: set %s: %w", name, ErrReadOnly)
+	}
+	v, err := coerce(f.Type, value)
+	if err != nil {
+		return s.fieldError(idx, f.Offset, 0, 0, err)
+	}
+	if _, err := s.fieldSlice(idx, f.Offset, f.Size); err != nil {
+		return err
+	}
+
+	s.SeqBeginWrite(idx)
+	err = s.writeAny(idx, f, v)
+	s.SeqEndWrite(idx)
+	return err
+}
+
+// GetRecordMap reads every field of record idx in one seqlock cycle and
+// returns them keyed by mmap field name.
+func (s *Store) GetRecordMap(idx int) (map[string]any, error) {
+	if _, err := s.fieldSlice(idx, 0, uint32(s.recordSize)); err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		seq := s.SeqReadBegin(idx)
+		if seq&1 == 0 {
+			m := make(map[string]any, len(s.layout.Fields))
+			for _, f := range s.layout.Fields {
+				v, err := s.readAny(idx, f)
+				if err != nil {
+					return nil, err
+				}
+				m[f.Name] = v
+			}
+			if s.SeqReadValid(idx, seq) {
+				return m, nil
+			}
+		}
+		if err := s.SeqReadRetry(context.Background(), idx, attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readAny dispatches to the Read* primitive for f.
+func (s *Store) readAny(idx int, f FieldLayout) (any, error) {
+	switch f.Type {
+	case FieldBool:
+		return s.ReadBool(idx, f.Offset)
+	case FieldInt8:
+		return s.ReadInt8(idx, f.Offset)
+	case FieldUint8:
+		return s.ReadUint8(idx, f.Offset)
+	case FieldInt16:
+		return s.ReadInt16(idx, f.Offset)
+	case FieldUint16:
+		return s.ReadUint16(idx, f.Offset)
+	case FieldInt32:
+		return s.ReadInt32(idx, f.Offset)
+	case FieldUint32:
+		return s.ReadUint32(idx, f.Offset)
+	case FieldInt64:
+		return s.ReadInt64(idx, f.Offset)
+	case FieldUint64:
+		return s.ReadUint64(idx, f.Offset)
+	case FieldFloat32:
+		return s.ReadFloat32(idx, f.Offset)
+	case FieldFloat64:
+		return s.ReadFloat64(idx, f.Offset)
+	case FieldString:
+		return s.ReadString(idx, f.Offset, f.Size, f.MaxSize)
+	case FieldBytes:
+		return s.ReadBytes(idx, f.Offset, f.Size, f.MaxSize)
+	default:
+		return nil, fmt.Errorf("mmapforge: field %q: unknown field type %d", f.Name, f.Type)
+	}
+}
+
+// writeAny dispatches to the Write* primitive for f. v must already be
+// coerced to the field's Go type.
+func (s *Store) writeAny(idx int, f FieldLayout, v any) error {
+	switch f.Type {
+	case FieldBool:
+		return s.WriteBool(idx, f.Offset, v.(bool))
+	case FieldInt8:
+		return s.WriteInt8(idx, f.Offset, v.(int8))
+	case FieldUint8:
+		return s.WriteUint8(idx, f.Offset, v.(uint8))
+	case FieldInt16:
+		return s.WriteInt16(idx, f.Offset, v.(int16))
+	case FieldUint16:
+		return s.WriteUint16(idx, f.Offset, v.(uint16))
+	case FieldInt32:
+		return s.WriteInt32(idx, f.Offset, v.(int32))
+	case FieldUint32:
+		return s.WriteUint32(idx, f.Offset, v.(uint32))
+	case FieldInt64:
+		return s.WriteInt64(idx, f.Offset, v.(int64))
+	case FieldUint64:
+		return s.WriteUint64(idx, f.Offset, v.(uint64))
+	case FieldFloat32:
+		return s.WriteFloat32(idx, f.Offset, v.(float32))
+	case FieldFloat64:
+		return s.WriteFloat64(idx, f.Offset, v.(float64))
+	case FieldString:
+		return s.WriteString(idx, f.Offset, f.Size, f.MaxSize, v.(string))
+	default:
+		return s.WriteBytes(idx, f.Offset, f.Size, f.MaxSize, v.([]byte))
+	}
+}
+
+// coerce converts value to the Go type that backs ft.
+func coerce(ft FieldType, value any) (any, error) {
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return nil, fmt.Errorf("%w: nil for %v", ErrInvalidValue, ft)
+	}
+
+	switch ft {
+	case FieldBool:
+		switch rv.Kind() {
+		case reflect.Bool:
+			return rv.Bool(), nil
+		case reflect.String:
+			b, err := strconv.ParseBool(rv.String())
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q for bool", ErrInvalidValue, rv.String())
+			}
+			return b, nil
+		}
+	case FieldInt8, FieldInt16, FieldInt32, FieldInt64:
+		n, err := coerceInt(rv, ft)
+		if err != nil {
+			return nil, err
+		}
+		switch ft {
+		case FieldInt8:
+			return int8(n), nil
+		case FieldInt16:
+			return int16(n), nil
+		case FieldInt32:
+			return int32(n), nil
+		default:
+			return n, nil
+		}
+	case FieldUint8, FieldUint16, FieldUint32, FieldUint64:
+		n, err := coerceUint(rv, ft)
+		if err != nil {
+			return nil, err
+		}
+		switch ft {
+		case FieldUint8:
+			return uint8(n), nil
+		case FieldUint16:
+			return uint16(n), nil
+		case FieldUint32:
+			return uint32(n), nil
+		default:
+			return n, nil
+		}
+	case FieldFloat32, FieldFloat64:
+		x, err := coerceFloat(rv, ft)
+		if err != nil {
+			return nil, err
+		}
+		if ft == FieldFloat32 {
+			if math.Abs(x) > math.MaxFloat32 && !math.IsInf(x, 0) {
+				return nil, fmt.Errorf("%w: %v overflows %v", ErrInvalidValue, x, ft)
+			}
+			return float32(x), nil
+		}
+		return x, nil
+	case FieldString:
+		if rv.Kind() == reflect.String {
+			return rv.String(), nil
+		}
+		if isByteSlice(rv) {
+			return string(rv.Bytes()), nil
+		}
+	case FieldBytes:
+		if isByteSlice(rv) {
+			return rv.Bytes(), nil
+		}
+		if rv.Kind() == reflect.String {
+			return []byte(rv.String()), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %T for %v", ErrInvalidValue, value, ft)
+}
+
+// intRange returns the inclusive bounds of a signed field type.
+func intRange(ft FieldType) (lo, hi int64) {
+	switch ft {
+	case FieldInt8:
+		return math.MinInt8, math.MaxInt8
+	case FieldInt16:
+		return math.MinInt16, math.MaxInt16
+	case FieldInt32:
+		return math.MinInt32, math.MaxInt32
+	default:
+		return math.MinInt64, math.MaxInt64
+	}
+}
+
+// uintMax returns the upper bound of an unsigned field type.
+func uintMax(ft FieldType) uint64 {
+	switch ft {
+	case FieldUint8:
+		return math.MaxUint8
+	case FieldUint16:
+		return math.MaxUint16
+	case FieldUint32:
+		return math.MaxUint32
+	default:
+		return math.MaxUint64
+	}
+}
+
+func coerceInt(rv reflect.Value, ft FieldType) (int64, error) {
+	lo, hi := intRange(ft)
+	var n int64
+	switch {
+	case rv.CanInt():
+		n = rv.Int()
+	case rv.CanUint():
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: %d overflows %v", ErrInvalidValue, u, ft)
+		}
+		n = int64(u)
+	case rv.CanFloat():
+		x := rv.Float()
+		if x != math.Trunc(x) || x < float64(lo) || x >= -float64(lo) {
+			return 0, fmt.Errorf("%w: %v does not fit %v", ErrInvalidValue, x, ft)
+		}
+		n = int64(x)
+	case rv.Kind() == reflect.String:
+		v, err := strconv.ParseInt(rv.String(), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q for %v", ErrInvalidValue, rv.String(), ft)
+		}
+		n = v
+	default:
+		return 0, fmt.Errorf("%w: %v for %v", ErrInvalidValue, rv.Type(), ft)
+	}
+	if n < lo || n > hi {
+		return 0, fmt.Errorf("%w: %d overflows %v", ErrInvalidValue, n, ft)
+	}
+	return n, nil
+}
+
+func coerceUint(rv reflect.Value, ft FieldType) (uint64, error) {
+	limit := uintMax(ft)
+	var n uint64
+	switch {
+	case rv.CanUint():
+		n = rv.Uint()
+	case rv.CanInt():
+		i := rv.Int()
+		if i < 0 {
+			return 0, fmt.Errorf("%w: %d overflows %v", ErrInvalidValue, i, ft)
+		}
+		n = uint64(i)
+	case rv.CanFloat():
+		x := rv.Float()
+		if x != math.Trunc(x) || x < 0 || x >= float64(limit)+1 {
+			return 0, fmt.Errorf("%w: %v does not fit %v", ErrInvalidValue, x, ft)
+		}
+		n = uint64(x)
+	case rv.Kind() == reflect.String:
+		v, err := strconv.ParseUint(rv.String(), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q for %v", ErrInvalidValue, rv.String(), ft)
+		}
+		n = v
+	default:
+		return 0, fmt.Errorf("%w: %v for %v", ErrInvalidValue, rv.Type(), ft)
+	}
+	if n > limit {
+		return 0, fmt.Errorf("%w: %d overflows %v", ErrInvalidValue, n, ft)
+	}
+	return n, nil
+}
+
+func coerceFloat(rv reflect.Value, ft FieldType) (float64, error) {
+	switch {
+	case rv.CanFloat():
+		return rv.Float(), nil
+	case rv.CanInt():
+		return float64(rv.Int()), nil
+	case rv.CanUint():
+		return float64(rv.Uint()), nil
+	case rv.Kind() == reflect.String:
+		x, err := strconv.ParseFloat(rv.String(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q for %v", ErrInvalidValue, rv.String(), ft)
+		}
+		return x, nil
+	default:
+		return 0, fmt.Errorf("%w: %v for %v", ErrInvalidValue, rv.Type(), ft)
+	}
+}
+
+func isByteSlice(rv reflect.Value) bool {
+	return rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8
+}
@@ -0,0 +1,233 @@
+//go:build unix
+
+package mmapforge
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func testDynamicLayout() *RecordLayout {
+	layout, err := ComputeLayout([]FieldDef{
+		{Name: "flag", GoName: "Flag", Type: FieldBool},
+		{Name: "i8", GoName: "I8", Type: FieldInt8},
+		{Name: "u8", GoName: "U8", Type: FieldUint8},
+		{Name: "i16", GoName: "I16", Type: FieldInt16},
+		{Name: "u16", GoName: "U16", Type: FieldUint16},
+		{Name: "i32", GoName: "I32", Type: FieldInt32},
+		{Name: "u32", GoName: "U32", Type: FieldUint32},
+		{Name: "i64", GoName: "I64", Type: FieldInt64},
+		{Name: "u64", GoName: "U64", Type: FieldUint64},
+		{Name: "f32", GoName: "F32", Type: FieldFloat32},
+		{Name: "f64", GoName: "F64", Type: FieldFloat64},
+		{Name: "name", GoName: "Name", Type: FieldString, MaxSize: 8},
+		{Name: "data", GoName: "Data", Type: FieldBytes, MaxSize: 8},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return layout
+}
+
+func mustCreateDynamicStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := CreateStore(tempPath(t), testDynamicLayout(), 1)
+	if err != nil {
+		t.Fatalf("CreateStore: %v", err)
+	}
+	if _, err := s.Append(); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	return s
+}
+
+func TestStore_Field(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	defer s.Close()
+
+	f, ok := s.Field("u32")
+	if !ok || f.Type != FieldUint32 || f.GoName != "U32" {
+		t.Errorf("Field(u32) = %+v, %v", f, ok)
+	}
+	if _, ok := s.Field("missing"); ok {
+		t.Error("Field(missing) should not be found")
+	}
+}
+
+func TestStore_SetAnyGetAny(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	defer s.Close()
+
+	cases := []struct {
+		name  string
+		value any
+		want  any
+	}{
+		{"flag", true, true},
+		{"flag", "false", false},
+		{"i8", -5, int8(-5)},
+		{"i8", uint(7), int8(7)},
+		{"i8", 12.0, int8(12)},
+		{"i8", "-100", int8(-100)},
+		{"u8", 200, uint8(200)},
+		{"u8", 3.0, uint8(3)},
+		{"u8", "9", uint8(9)},
+		{"i16", int64(-1234), int16(-1234)},
+		{"u16", uint64(54321), uint16(54321)},
+		{"i32", int32(-100000), int32(-100000)},
+		{"u32", 3000000000, uint32(3000000000)},
+		{"i64", int64(math.MinInt64), int64(math.MinInt64)},
+		{"u64", uint64(math.MaxUint64), uint64(math.MaxUint64)},
+		{"f32", 1.5, float32(1.5)},
+		{"f32", 2, float32(2)},
+		{"f64", "2.25", 2.25},
+		{"f64", uint8(4), 4.0},
+		{"name", "hello", "hello"},
+		{"name", []byte("bytes"), "bytes"},
+		{"data", []byte{1, 2}, []byte{1, 2}},
+		{"data", "ab", []byte("ab")},
+	}
+	for _, tc := range cases {
+		if err := s.SetAny(0, tc.name, tc.value); err != nil {
+			t.Errorf("SetAny(%s, %#v): %v", tc.name, tc.value, err)
+			continue
+		}
+		got, err := s.GetAny(0, tc.name)
+		if err != nil {
+			t.Errorf("GetAny(%s): %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("GetAny(%s) after SetAny(%#v) = %#v, want %#v", tc.name, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestStore_SetAny_Invalid(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	defer s.Close()
+
+	cases := []struct {
+		name  string
+		value any
+	}{
+		{"flag", nil},
+		{"flag", 1},
+		{"flag", "maybe"},
+		{"i8", 128},
+		{"i8", uint64(math.MaxUint64)},
+		{"i8", 1.5},
+		{"i8", "x"},
+		{"i8", true},
+		{"u8", -1},
+		{"u8", 256},
+		{"u8", -1.0},
+		{"u8", "-1"},
+		{"u8", struct{}{}},
+		{"u16", 70000.0},
+		{"f32", math.MaxFloat64},
+		{"f64", "abc"},
+		{"f64", false},
+		{"name", 5},
+		{"data", 5},
+	}
+	for _, tc := range cases {
+		err := s.SetAny(0, tc.name, tc.value)
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("SetAny(%s, %#v) err = %v, want ErrInvalidValue", tc.name, tc.value, err)
+			continue
+		}
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Field != tc.name {
+			t.Errorf("SetAny(%s, %#v) err = %v, want FieldError for %s", tc.name, tc.value, err, tc.name)
+		}
+	}
+}
+
+func TestStore_SetAny_Errors(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	defer s.Close()
+
+	if err := s.SetAny(0, "missing", 1); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("unknown field err = %v, want ErrUnknownField", err)
+	}
+	if err := s.SetAny(5, "u8", 1); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("out of bounds err = %v, want ErrOutOfBounds", err)
+	}
+	if err := s.SetAny(0, "name", "too long for field"); !errors.Is(err, ErrStringTooLong) {
+		t.Errorf("too long err = %v, want ErrStringTooLong", err)
+	}
+
+	s.writable = false
+	if err := s.SetAny(0, "u8", 1); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("read-only err = %v, want ErrReadOnly", err)
+	}
+	s.writable = true
+}
+
+func TestStore_GetAny_Errors(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	defer s.Close()
+
+	if _, err := s.GetAny(0, "missing"); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("unknown field err = %v, want ErrUnknownField", err)
+	}
+	if _, err := s.GetAny(3, "u8"); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("out of bounds err = %v, want ErrOutOfBounds", err)
+	}
+	if _, err := s.readAny(0, FieldLayout{FieldDef: FieldDef{Name: "bad", Type: FieldType(99)}}); err == nil {
+		t.Error("readAny with unknown type: expected error")
+	}
+}
+
+func TestStore_GetRecordMap(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	defer s.Close()
+
+	if err := s.SetAny(0, "u64", 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetAny(0, "name", "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := s.GetRecordMap(0)
+	if err != nil {
+		t.Fatalf("GetRecordMap: %v", err)
+	}
+	if len(m) != len(s.layout.Fields) {
+		t.Errorf("len(map) = %d, want %d", len(m), len(s.layout.Fields))
+	}
+	if m["u64"] != uint64(42) || m["name"] != "abc" || m["flag"] != false {
+		t.Errorf("GetRecordMap = %v", m)
+	}
+
+	if _, err := s.GetRecordMap(1); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("out of bounds err = %v, want ErrOutOfBounds", err)
+	}
+}
+
+func TestStore_Dynamic_Closed(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	if _, err := s.GetAny(-1, "u8"); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("GetAny(-1) err = %v, want ErrOutOfBounds", err)
+	}
+	if _, err := s.GetRecordMap(1 << 30); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("GetRecordMap far out of bounds err = %v, want ErrOutOfBounds", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetAny(0, "u8"); !errors.Is(err, ErrClosed) {
+		t.Errorf("GetAny err = %v, want ErrClosed", err)
+	}
+	if _, err := s.GetRecordMap(0); !errors.Is(err, ErrClosed) {
+		t.Errorf("GetRecordMap err = %v, want ErrClosed", err)
+	}
+	if err := s.SetAny(0, "u8", 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("SetAny err = %v, want ErrClosed", err)
+	}
+}
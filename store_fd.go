@@ -0,0 +1,44 @@
+package mmapforge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Fd returns the file descriptor of the store's file, for handing the
+// store to another process (over a Unix socket with SCM_RIGHTS, or as an
+// inherited descriptor of a child). It returns -1 if the store is closed
+// or not backed by a file.
+//
+// The descriptor stays owned by the store and is closed by Close.
+func (s *Store) Fd() int {
+	r, ok := s.region.(*Region)
+	if !ok {
+		return -1
+	}
+	return int(r.file.Fd())
+}
+
+// OpenStoreFromFd opens the store in the file referred to by fd, such as a
+// memfd received from another process, and validates the schema hash. The
+// store takes ownership of fd and closes it on Close, or right away if
+// opening fails; dup it first to keep using it.
+//
+// WithOneWriter is not supported: there is no path for the sidecar lock.
+func OpenStoreFromFd(fd int, layout *RecordLayout, opts ...StoreOption) (*Store, error) {
+	cfg := applyOptions(opts)
+	f := os.NewFile(uintptr(fd), "fd:"+strconv.Itoa(fd))
+	if f == nil {
+		return nil, fmt.Errorf("mmapforge: open fd %d: invalid descriptor", fd)
+	}
+	if cfg.oneWriter {
+		closeErr := f.Close()
+		return nil, errors.Join(
+			fmt.Errorf("mmapforge: open %s: WithOneWriter needs a store file on disk", f.Name()),
+			fmt.Errorf("mmapforge: close %s: %w", f.Name(), closeErr),
+		)
+	}
+	return openStoreFile(f, layout, cfg)
+}
@@ -0,0 +1,105 @@
+package mmapforge
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestStore_Fd(t *testing.T) {
+	s := mustCreateStore(t)
+	if s.Fd() < 0 {
+		t.Errorf("file-backed Fd = %d", s.Fd())
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Fd() != -1 {
+		t.Errorf("Fd after Close = %d, want -1", s.Fd())
+	}
+
+	m, err := CreateStoreInMemory(testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if m.Fd() != -1 {
+		t.Errorf("in-memory Fd = %d, want -1", m.Fd())
+	}
+}
+
+func TestOpenStoreFromFd(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	idx, _ := s.Append()
+	if err := s.WriteUint64(idx, 8, 99); err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := syscall.Dup(s.Fd())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := OpenStoreFromFd(fd, testLayout(), WithReadOnly())
+	if err != nil {
+		t.Fatalf("OpenStoreFromFd: %v", err)
+	}
+	if v, err := s2.ReadUint64(idx, 8); err != nil || v != 99 {
+		t.Errorf("ReadUint64 = %d, %v", v, err)
+	}
+	if err := s2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The store owned the dup, not the original descriptor.
+	if _, err := s.ReadUint64(idx, 8); err != nil {
+		t.Errorf("original store broken after closing the dup: %v", err)
+	}
+}
+
+func TestOpenStoreFromFd_Errors(t *testing.T) {
+	if _, err := OpenStoreFromFd(-1, testLayout()); err == nil || !strings.Contains(err.Error(), "invalid descriptor") {
+		t.Errorf("fd -1: err = %v", err)
+	}
+
+	s := mustCreateStore(t)
+	defer s.Close()
+	fd, err := syscall.Dup(s.Fd())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenStoreFromFd(fd, testLayout(), WithOneWriter()); err == nil || !strings.Contains(err.Error(), "WithOneWriter needs a store file on disk") {
+		t.Errorf("one writer: err = %v", err)
+	}
+	if err := syscall.Close(fd); err != syscall.EBADF {
+		t.Errorf("fd should be closed on error, close err = %v", err)
+	}
+
+	var p [2]int
+	if err := syscall.Pipe(p[:]); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(p[1])
+	if _, err := OpenStoreFromFd(p[0], testLayout()); err == nil || !strings.Contains(err.Error(), "too small") {
+		t.Errorf("pipe: err = %v", err)
+	}
+}
+
+func TestOpenStoreFromFd_Writer(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	fd, err := syscall.Dup(s.Fd())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A reader handed only the descriptor still sees the writer record.
+	s2, err := OpenStoreFromFd(fd, testLayout(), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	if w, err := s2.Writer(); err != nil || w.PID != os.Getpid() || !w.Alive {
+		t.Errorf("Writer = %+v, %v", w, err)
+	}
+}
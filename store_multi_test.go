@@ -0,0 +1,240 @@
+package mmapforge
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestMultiWriter_ConcurrentIncrements(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithMultiWriter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := s.layout.Fields[0].Offset
+
+	const writers, perWriter = 8, 500
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWriter {
+				s.SeqBeginWrite(idx)
+				v, _ := s.ReadUint64(idx, off)
+				_ = s.WriteUint64(idx, off, v+1)
+				s.SeqEndWrite(idx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := s.ReadUint64(idx, off); v != writers*perWriter {
+		t.Errorf("counter = %d, want %d", v, writers*perWriter)
+	}
+	if seq := s.SeqReadBegin(idx); seq != 2*writers*perWriter {
+		t.Errorf("seq = %d, want %d", seq, 2*writers*perWriter)
+	}
+}
+
+func TestSeqTryBeginWrite(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SeqTryBeginWrite(idx); err != nil {
+		t.Fatalf("free record: %v", err)
+	}
+	if err := s.SeqTryBeginWrite(idx); !errors.Is(err, ErrRecordBusy) {
+		t.Errorf("held record: err = %v, want ErrRecordBusy", err)
+	}
+	s.SeqEndWrite(idx)
+	if err := s.SeqTryBeginWrite(idx); err != nil {
+		t.Errorf("released record: %v", err)
+	}
+	s.SeqEndWrite(idx)
+	if seq := s.SeqReadBegin(idx); seq != 4 {
+		t.Errorf("seq = %d, want 4", seq)
+	}
+}
+
+func TestSeqTryBeginWrite_ReadOnly(t *testing.T) {
+	path := tempPath(t)
+	w, err := CreateStore(path, testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Append(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenStore(path, testLayout(), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.SeqTryBeginWrite(0); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("err = %v, want ErrReadOnly", err)
+	}
+	if _, err := r.RecoverSeqlocks(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("RecoverSeqlocks: err = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestMultiWriter_OpenSkipsRecovery(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := s.Append(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.SeqBeginWrite(1) // a write left in flight by another writer
+	defer s.Close()
+
+	m, err := OpenStore(path, testLayout(), WithMultiWriter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Stats().RecoveredSeqlocks; got != 0 {
+		t.Errorf("RecoveredSeqlocks = %d, want 0", got)
+	}
+	if seq := m.SeqReadBegin(1); seq&1 != 1 {
+		t.Errorf("seq = %d, in-flight write was reset", seq)
+	}
+
+	n, err := m.RecoverSeqlocks()
+	if err != nil || n != 1 {
+		t.Errorf("RecoverSeqlocks = %d, %v; want 1", n, err)
+	}
+	if seq := m.SeqReadBegin(1); seq != 2 {
+		t.Errorf("seq after recovery = %d, want 2", seq)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.RecoverSeqlocks(); !errors.Is(err, ErrClosed) {
+		t.Errorf("after Close: err = %v, want ErrClosed", err)
+	}
+}
+
+func TestMultiWriter_OneWriterRecovers(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append(); err != nil {
+		t.Fatal(err)
+	}
+	s.SeqBeginWrite(0)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore(path, testLayout(), WithMultiWriter(), WithOneWriter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := s.Stats().RecoveredSeqlocks; got != 1 {
+		t.Errorf("RecoveredSeqlocks = %d, want 1", got)
+	}
+}
+
+func TestMultiWriter_ModeIsPersisted(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1, WithMultiWriter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append(); err != nil {
+		t.Fatal(err)
+	}
+	s.SeqBeginWrite(0) // another writer's write in flight
+	defer s.Close()
+
+	o, err := OpenStore(path, testLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	if !o.multi {
+		t.Error("store opened without WithMultiWriter ignored the persisted mode")
+	}
+	if got := o.Stats().RecoveredSeqlocks; got != 0 {
+		t.Errorf("RecoveredSeqlocks = %d, want 0", got)
+	}
+	if err := o.SeqTryBeginWrite(0); !errors.Is(err, ErrRecordBusy) {
+		t.Errorf("SeqTryBeginWrite: err = %v, want ErrRecordBusy", err)
+	}
+}
+
+func TestMultiWriter_OpenPersistsMode(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := OpenStore(path, testLayout(), WithMultiWriter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	h, err := DecodeHeader(m.region.Slice(0, HeaderSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Flags&FlagMultiWriter == 0 {
+		t.Errorf("flags = %#x, want FlagMultiWriter set", h.Flags)
+	}
+
+	r, err := OpenStore(path, testLayout(), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if !r.multi {
+		t.Error("reader did not pick up the persisted mode")
+	}
+}
+
+type closeRecordingBacking struct {
+	Backing
+	closed bool
+}
+
+func (b *closeRecordingBacking) Close() error {
+	b.closed = true
+	return b.Backing.Close()
+}
+
+func TestOpenStoreWithBacking_ReadOnlyOneWriterClosesBacking(t *testing.T) {
+	s, err := CreateStoreInMemory(testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &closeRecordingBacking{Backing: s.region}
+	if _, err := OpenStoreWithBacking(b, testLayout(), WithReadOnly(), WithOneWriter()); err == nil {
+		t.Fatal("expected error for WithReadOnly and WithOneWriter")
+	}
+	if !b.closed {
+		t.Error("backing was not closed")
+	}
+}
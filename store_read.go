@@ -15,7 +15,14 @@ func (s *Store) ReadBool(idx int, offset uint32) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return b[0] == 1, nil
+	switch b[0] {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	default:
+		return false, s.fieldError(idx, offset, 0, 0, fmt.Errorf("%w (got %#x)", ErrInvalidBool, b[0]))
+	}
 }
 
 // ReadInt8 reads an int8 from record idx at the given byte offset.
@@ -118,7 +125,7 @@ func (s *Store) ReadString(idx int, offset, fieldSize, maxSize uint32) (string,
 	}
 	strLen := binary.LittleEndian.Uint32(b[:4])
 	if strLen > maxSize {
-		return "", fmt.Errorf("mmapforge: field at offset %d: %w (len=%d max=%d)", offset, ErrCorrupted, strLen, maxSize)
+		return "", s.fieldError(idx, offset, int(strLen), maxSize, ErrCorrupted)
 	}
 	if strLen == 0 {
 		return "", nil
@@ -136,7 +143,7 @@ func (s *Store) ReadBytes(idx int, offset, fieldSize, maxSize uint32) ([]byte, e
 	}
 	byteLen := binary.LittleEndian.Uint32(b[:4])
 	if byteLen > maxSize {
-		return nil, fmt.Errorf("mmapforge: field at offset %d: %w (len=%d max=%d)", offset, ErrCorrupted, byteLen, maxSize)
+		return nil, s.fieldError(idx, offset, int(byteLen), maxSize, ErrCorrupted)
 	}
 	return b[4 : 4+byteLen], nil
 }
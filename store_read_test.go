@@ -4,6 +4,7 @@ package mmapforge
 
 import (
 	"encoding/binary"
+	"errors"
 	"testing"
 )
 
@@ -48,6 +49,31 @@ func TestReadBool_OutOfBounds(t *testing.T) {
 	}
 }
 
+func TestReadBool_Invalid(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+
+	idx, appendErr := s.Append()
+	if appendErr != nil {
+		t.Fatal(appendErr)
+	}
+	if writeErr := s.WriteUint8(idx, 8, 2); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	_, err := s.ReadBool(idx, 8)
+	if !errors.Is(err, ErrInvalidBool) {
+		t.Fatalf("ReadBool err = %v, want ErrInvalidBool", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("ReadBool err = %T, want *FieldError", err)
+	}
+	if fe.Field != "id" || fe.Index != idx || fe.Offset != 8 {
+		t.Errorf("FieldError = %+v, want field id, index %d, offset 8", fe, idx)
+	}
+}
+
 func TestReadInt8(t *testing.T) {
 	s := mustCreateStore(t)
 	defer s.Close()
@@ -433,6 +459,19 @@ func TestReadString_Corrupted(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for corrupted string length")
 	}
+	if !errors.Is(err, ErrCorrupted) {
+		t.Errorf("err = %v, want ErrCorrupted", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("err = %T, want *FieldError", err)
+	}
+	if fe.Field != "name" || fe.GoName != "Name" || fe.Index != idx {
+		t.Errorf("FieldError = %+v, want field name/Name at index %d", fe, idx)
+	}
+	if fe.Len != int(nameField.MaxSize)+1 || fe.Max != nameField.MaxSize {
+		t.Errorf("FieldError len/max = %d/%d, want %d/%d", fe.Len, fe.Max, nameField.MaxSize+1, nameField.MaxSize)
+	}
 }
 
 func TestReadString_OutOfBounds(t *testing.T) {
@@ -549,6 +588,23 @@ func TestReadBytes_OutOfBounds(t *testing.T) {
 	}
 }
 
+func TestReadUint64_OutOfBounds_FieldError(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+
+	_, err := s.ReadUint64(5, 8)
+	if !errors.Is(err, ErrOutOfBounds) {
+		t.Fatalf("err = %v, want ErrOutOfBounds", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("err = %T, want *FieldError", err)
+	}
+	if fe.Field != "id" || fe.Index != 5 {
+		t.Errorf("FieldError = %+v, want field id at index 5", fe)
+	}
+}
+
 func TestReadBool_Closed(t *testing.T) {
 	s := mustCreateStore(t)
 	if _, appendErr := s.Append(); appendErr != nil {
@@ -1,6 +1,9 @@
 package mmapforge
 
 import (
+	"context"
+	"fmt"
+	"runtime"
 	"sync/atomic"
 	"unsafe"
 )
@@ -11,15 +14,16 @@ import (
 // The protocol is a classic seqlock:
 //
 //	Writer:
-//	  1. SeqBeginWrite  → counter becomes odd  (write in progress)
+//	  1. SeqBeginWrite  → counter becomes odd  (write in progress; with
+//	                      WithMultiWriter, waits for other writers first)
 //	  2. write field(s)
 //	  3. SeqEndWrite    → counter becomes even (write complete)
 //
 //	Reader:
 //	  1. seq = SeqReadBegin  → load counter
-//	  2. if seq is odd, spin (writer active)
+//	  2. if seq is odd, SeqReadRetry and goto 1 (writer active)
 //	  3. read field(s)
-//	  4. if !SeqReadValid(seq), goto 1 (writer intervened, retry)
+//	  4. if !SeqReadValid(seq), SeqReadRetry and goto 1 (writer intervened)
 //
 // Memory ordering guarantees:
 //
@@ -40,40 +44,180 @@ import (
 // This means readers never see a torn write, even without explicit memory
 // fences — the atomics provide the required ordering. The one caveat is
 // process crash: if the writer dies between Begin and End, the counter stays
-// odd permanently. See Store.RecoverSeqlocks for crash recovery.
+// odd permanently. OpenStore resets stuck counters; readers that are already
+// running give up with ErrWriterStalled (see SeqReadRetry).
+//
+// On a closed store, or for a record outside the mapping, the Seq* methods
+// touch nothing: the begin and end calls do nothing, SeqReadBegin returns 0
+// and SeqReadValid accepts any even seq, so the field access they bracket
+// is what reports ErrClosed or ErrOutOfBounds.
 
 // SeqBeginWrite marks the start of a write to record idx.
 // Increments the 8-byte sequence counter at offset 0 of the record to an odd value.
 // Caller must call SeqEndWrite when the write is complete.
+//
+// On a WithMultiWriter store the counter is moved from even to odd with a
+// compare-and-swap, so SeqBeginWrite waits, spinning and then yielding like
+// a reader, for as long as another writer holds the record. The wait has no
+// budget and no context: a writer that died holding the record blocks it
+// until RecoverSeqlocks. Writers that must not block use SeqTryBeginWrite
+// and retry on ErrRecordBusy.
 func (s *Store) SeqBeginWrite(idx int) {
 	if !s.writable {
 		panic("mmapforge: SeqBeginWrite called on read-only store")
 	}
-	off := HeaderSize + idx*s.recordSize
-	ptr := (*atomic.Uint64)(unsafe.Pointer(s.region.base + uintptr(off)))
-	ptr.Add(1)
+	ptr := s.seqPtr(idx)
+	if ptr == nil {
+		return
+	}
+	if !s.multi {
+		ptr.Add(1)
+		return
+	}
+	s.seqAcquire(ptr)
+}
+
+// seqAcquire moves the seqlock counter at ptr from even to odd with a
+// compare-and-swap, spinning and then yielding while it is odd, and
+// returns the even value it replaced.
+func (s *Store) seqAcquire(ptr *atomic.Uint64) uint64 {
+	for attempt := 0; ; attempt++ {
+		if seq := ptr.Load(); seq&1 == 0 && ptr.CompareAndSwap(seq, seq+1) {
+			return seq
+		}
+		if attempt >= s.backoff.Spins {
+			runtime.Gosched()
+		}
+	}
+}
+
+// SeqTryBeginWrite is SeqBeginWrite that never waits: it takes record idx's
+// seqlock with a compare-and-swap, or returns an error wrapping
+// ErrRecordBusy if another writer holds it. Safe in either writer mode.
+func (s *Store) SeqTryBeginWrite(idx int) error {
+	if s.region == nil {
+		return fmt.Errorf("mmapforge: write record %d: %w", idx, ErrClosed)
+	}
+	if !s.writable {
+		return fmt.Errorf("mmapforge: write record %d: %w", idx, ErrReadOnly)
+	}
+	ptr := s.seqPtr(idx)
+	if ptr == nil {
+		return fmt.Errorf("mmapforge: write record %d: %w", idx, ErrOutOfBounds)
+	}
+	// Only an odd counter means busy; a CAS lost to a writer that has
+	// already released the record is retried.
+	for {
+		seq := ptr.Load()
+		if seq&1 != 0 {
+			return fmt.Errorf("mmapforge: write record %d: %w", idx, ErrRecordBusy)
+		}
+		if ptr.CompareAndSwap(seq, seq+1) {
+			return nil
+		}
+	}
 }
 
 // SeqEndWrite marks the end of a write to record idx.
-// Increments the sequence counter to an even value.
+// Increments the sequence counter to an even value, and marks the record's
+// pages dirty if the store tracks them (WithDirtyTracking).
 func (s *Store) SeqEndWrite(idx int) {
-	off := HeaderSize + idx*s.recordSize
-	ptr := (*atomic.Uint64)(unsafe.Pointer(s.region.base + uintptr(off)))
+	ptr := s.seqPtr(idx)
+	if ptr == nil {
+		return
+	}
 	ptr.Add(1)
+	if s.dirty != nil {
+		s.dirty.mark(s.dataOff+idx*s.recordSize, s.recordSize)
+	}
 }
 
 // SeqReadBegin loads the sequence counter for record idx.
 // If the value is odd, a write is in progress and the caller should spin.
 func (s *Store) SeqReadBegin(idx int) uint64 {
-	off := HeaderSize + idx*s.recordSize
-	ptr := (*atomic.Uint64)(unsafe.Pointer(s.region.base + uintptr(off)))
+	ptr := s.seqPtr(idx)
+	if ptr == nil {
+		return 0
+	}
 	return ptr.Load()
 }
 
 // SeqReadValid returns true if seq is even (no write in progress) and
 // the current counter still matches seq (no write happened during the read).
 func (s *Store) SeqReadValid(idx int, seq uint64) bool {
-	off := HeaderSize + idx*s.recordSize
-	ptr := (*atomic.Uint64)(unsafe.Pointer(s.region.base + uintptr(off)))
+	ptr := s.seqPtr(idx)
+	if ptr == nil {
+		return seq&1 == 0
+	}
 	return seq&1 == 0 && ptr.Load() == seq
 }
+
+// seqPtr returns record idx's sequence counter, or nil if the store is
+// closed or idx lies outside the mapping.
+func (s *Store) seqPtr(idx int) *atomic.Uint64 {
+	if s.region == nil || idx < 0 || s.dataOff+(idx+1)*s.recordSize > s.region.Mapped() {
+		return nil
+	}
+	off := s.dataOff + idx*s.recordSize
+	return (*atomic.Uint64)(unsafe.Pointer(s.base + uintptr(off)))
+}
+
+// ReadBackoff controls how seqlock readers wait out a writer.
+type ReadBackoff struct {
+	// Spins is how many times a reader retries back to back before it
+	// starts yielding the processor between retries.
+	Spins int
+
+	// MaxRetries is the retry budget: once a read has been retried this
+	// many times it fails with ErrWriterStalled. 0 retries until the
+	// context is done.
+	MaxRetries int
+}
+
+// DefaultReadBackoff is the backoff of stores opened without
+// WithReadBackoff. The budget amounts to well over 100ms of retrying.
+var DefaultReadBackoff = ReadBackoff{Spins: 64, MaxRetries: 1 << 22}
+
+// writerCheckEvery is how many yielding retries pass between checks of
+// the writer record (Store.Writer).
+const writerCheckEvery = 1 << 12
+
+// SeqReadRetry is called by a reader each time its read of record idx has
+// to be retried, with attempt counting from 0. It spins, then yields the
+// processor, following the store's ReadBackoff. It returns ctx's error
+// once ctx is done, and an error wrapping ErrWriterStalled once the retry
+// budget is spent or the writer recorded in the header is dead (see
+// Store.Writer).
+//
+//	for attempt := 0; ; attempt++ {
+//		seq := s.SeqReadBegin(idx)
+//		if seq&1 == 0 {
+//			// read field(s)
+//			if s.SeqReadValid(idx, seq) {
+//				return v, nil
+//			}
+//		}
+//		if err := s.SeqReadRetry(ctx, idx, attempt); err != nil {
+//			return zero, err
+//		}
+//	}
+func (s *Store) SeqReadRetry(ctx context.Context, idx, attempt int) error {
+	s.seqRetries.add(idx)
+	b := s.backoff
+	if attempt < b.Spins {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("mmapforge: read record %d: %w", idx, err)
+	}
+	if b.MaxRetries > 0 && attempt >= b.MaxRetries {
+		return fmt.Errorf("mmapforge: read record %d: gave up after %d retries: %w", idx, attempt, ErrWriterStalled)
+	}
+	if (attempt-b.Spins+1)%writerCheckEvery == 0 {
+		if w, err := s.Writer(); err == nil && w.PID != 0 && !w.Alive {
+			return fmt.Errorf("mmapforge: read record %d: writer (pid %d) is dead: %w", idx, w.PID, ErrWriterStalled)
+		}
+	}
+	runtime.Gosched()
+	return nil
+}
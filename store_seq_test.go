@@ -1,6 +1,7 @@
 package mmapforge
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -162,3 +163,49 @@ func TestSeqBeginWrite_PanicsOnReadOnly(t *testing.T) {
 	}()
 	ro.SeqBeginWrite(idx)
 }
+
+func TestSeq_AfterClose(t *testing.T) {
+	s := mustCreateStore(t)
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := s.layout.Fields[0].Offset
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SeqBeginWrite(idx)
+	s.SeqEndWrite(idx)
+	if seq := s.SeqReadBegin(idx); seq != 0 || !s.SeqReadValid(idx, seq) {
+		t.Errorf("SeqReadBegin = %d on a closed store, want 0 and valid", seq)
+	}
+	if err := s.SeqTryBeginWrite(idx); !errors.Is(err, ErrClosed) {
+		t.Errorf("SeqTryBeginWrite: err = %v, want ErrClosed", err)
+	}
+	if _, err := s.AtomicAddUint64(idx, off, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("AtomicAddUint64: err = %v, want ErrClosed", err)
+	}
+	if err := s.WriteUint64(idx, off, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("WriteUint64: err = %v, want ErrClosed", err)
+	}
+	if n, c := s.Len(), s.Cap(); n != 0 || c != 0 {
+		t.Errorf("Len, Cap = %d, %d after Close, want 0, 0", n, c)
+	}
+}
+
+func TestSeq_OutsideMapping(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+
+	far := s.Cap() + 1<<20
+	s.SeqBeginWrite(far)
+	s.SeqEndWrite(far)
+	s.SeqBeginWrite(-1)
+	if seq := s.SeqReadBegin(far); seq != 0 {
+		t.Errorf("SeqReadBegin(%d) = %d, want 0", far, seq)
+	}
+	if err := s.SeqTryBeginWrite(far); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("SeqTryBeginWrite: err = %v, want ErrOutOfBounds", err)
+	}
+}
@@ -0,0 +1,119 @@
+package mmapforge
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// StoreStats is a point-in-time snapshot of a store's counters.
+type StoreStats struct {
+	// Records and Capacity are Len and Cap.
+	Records  int
+	Capacity int
+
+	// MappedBytes is how much of the backing is mapped, ReservedBytes the
+	// size of its VA reservation (StoreReserveVA for file stores) and
+	// ReserveRemaining how far the store can still grow before Append
+	// fails.
+	MappedBytes      int
+	ReservedBytes    int
+	ReserveRemaining int
+
+	// Grows counts capacity doublings since the store was opened, and
+	// LastGrowDuration is how long the most recent one took.
+	Grows            uint64
+	LastGrowDuration time.Duration
+
+	// SeqlockRetries counts reads retried because a writer got in the way
+	// (SeqReadRetry calls). RecoveredSeqlocks is how many stuck counters
+	// OpenStore reset.
+	SeqlockRetries    uint64
+	RecoveredSeqlocks int
+
+	// DirtyPages is DirtyPages, 0 without WithDirtyTracking.
+	DirtyPages int
+
+	// Syncs counts Sync, SyncRange, SyncAsync and SyncDirty calls,
+	// including the ones made by WithAutoSync; SyncErrors counts the
+	// ones that failed.
+	Syncs      uint64
+	SyncErrors uint64
+
+	// LastSync is when the most recent sync started, LastSyncDuration how
+	// long it took and LastSyncErr what it returned.
+	LastSync         time.Time
+	LastSyncDuration time.Duration
+	LastSyncErr      error
+
+	// AutoSyncInterval is the WithAutoSync interval, or 0 if the store
+	// does not sync in the background.
+	AutoSyncInterval time.Duration
+}
+
+// Stats returns a snapshot of the store's counters. Safe to call
+// concurrently with anything, Close included. After Close the size fields
+// are 0.
+func (s *Store) Stats() StoreStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	st := s.stats
+	st.SeqlockRetries = s.seqRetries.load()
+	st.DirtyPages = s.DirtyPages()
+	if s.region == nil {
+		return st
+	}
+	st.Records = s.Len()
+	st.Capacity = s.Cap()
+	st.MappedBytes = s.region.Mapped()
+	st.ReservedBytes = reservedSize(s.region)
+	st.ReserveRemaining = max(st.ReservedBytes-st.MappedBytes, 0)
+	return st
+}
+
+// retryShards is how many counters SeqlockRetries is spread over. It must
+// be a power of two: retryCounters.add masks the record index with it.
+const retryShards = 16
+
+// retryCounters counts SeqReadRetry calls, sharded by record index so that
+// readers retrying on different records do not all write one cache line.
+type retryCounters [retryShards]struct {
+	n atomic.Uint64
+	_ [120]byte // pad each shard to its own pair of cache lines
+}
+
+// add counts a retry of a read of record idx.
+func (c *retryCounters) add(idx int) {
+	c[idx&(retryShards-1)].n.Add(1)
+}
+
+// load returns the total over all shards.
+func (c *retryCounters) load() uint64 {
+	var n uint64
+	for i := range c {
+		n += c[i].n.Load()
+	}
+	return n
+}
+
+// recordSync notes a sync that started at start and returned err.
+func (s *Store) recordSync(start time.Time, err error) {
+	d := time.Since(start)
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	s.stats.Syncs++
+	if err != nil {
+		s.stats.SyncErrors++
+	}
+	s.stats.LastSync = start
+	s.stats.LastSyncDuration = d
+	s.stats.LastSyncErr = err
+}
+
+// recordGrow notes a grow that started at start.
+func (s *Store) recordGrow(start time.Time) {
+	d := time.Since(start)
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	s.stats.Grows++
+	s.stats.LastGrowDuration = d
+}
@@ -0,0 +1,115 @@
+package mmapforge
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStore_Stats(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithDirtyTracking())
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, s, initialCapacity*2+1)
+	s.MarkDirty(0, 1)
+
+	st := s.Stats()
+	if st.Records != initialCapacity*2+1 || st.Capacity != initialCapacity*4 {
+		t.Errorf("Records/Capacity = %d/%d", st.Records, st.Capacity)
+	}
+	if st.Grows != 2 || st.LastGrowDuration <= 0 {
+		t.Errorf("Grows = %d, LastGrowDuration = %v", st.Grows, st.LastGrowDuration)
+	}
+	if st.MappedBytes != s.region.Mapped() || st.ReservedBytes != StoreReserveVA {
+		t.Errorf("MappedBytes/ReservedBytes = %d/%d", st.MappedBytes, st.ReservedBytes)
+	}
+	if st.ReserveRemaining != StoreReserveVA-st.MappedBytes {
+		t.Errorf("ReserveRemaining = %d", st.ReserveRemaining)
+	}
+	if st.DirtyPages != 1 {
+		t.Errorf("DirtyPages = %d", st.DirtyPages)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	st = s.Stats()
+	if st.Records != 0 || st.MappedBytes != 0 || st.Grows != 2 || st.Syncs != 0 {
+		t.Errorf("stats after Close = %+v", st)
+	}
+}
+
+func TestStore_StatsSeqlocks(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, s, 3)
+
+	seq := s.SeqReadBegin(1)
+	s.SeqBeginWrite(1)
+	if s.SeqReadValid(1, seq) {
+		t.Fatal("read valid across a write")
+	}
+	if err := s.SeqReadRetry(context.Background(), 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Stats().SeqlockRetries; got != 1 {
+		t.Errorf("SeqlockRetries = %d, want 1", got)
+	}
+	for _, idx := range []int{-1, 2, 1 + retryShards} {
+		if err := s.SeqReadRetry(context.Background(), idx, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := s.Stats().SeqlockRetries; got != 4 {
+		t.Errorf("SeqlockRetries = %d, want 4 summed over shards", got)
+	}
+	s.SeqBeginWrite(2) // leave two writes stuck
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore(path, testLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := s.Stats().RecoveredSeqlocks; got != 2 {
+		t.Errorf("RecoveredSeqlocks = %d, want 2", got)
+	}
+}
+
+func TestStore_StatsInMemory(t *testing.T) {
+	s, err := CreateStoreInMemory(testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if st := s.Stats(); st.ReservedBytes != StoreReserveVA || st.ReserveRemaining <= 0 {
+		t.Errorf("in-memory stats = %+v", st)
+	}
+}
+
+func TestStore_StatsDuringClose(t *testing.T) {
+	s := mustCreateStore(t)
+	if _, err := s.Append(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 1000 {
+			_ = s.Stats()
+		}
+	}()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if st := s.Stats(); st.Records != 0 || st.MappedBytes != 0 {
+		t.Errorf("Stats after Close = %+v, want zero sizes", st)
+	}
+}
@@ -0,0 +1,225 @@
+package mmapforge
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// dirtyTracker is a bitmap with one bit per page of a store's VA
+// reservation. Writers set bits lock-free; SyncDirty swaps whole words
+// out, so a page dirtied mid-sync is simply picked up by the next one.
+type dirtyTracker struct {
+	words []atomic.Uint64
+	// all is set when a write lands beyond the bitmap (a backing that
+	// reserves more than it reported); the next sync then covers everything.
+	all atomic.Bool
+}
+
+// pageSpan is a run of dirty bytes, page-aligned, in mapping offsets.
+type pageSpan struct {
+	off, n int
+}
+
+func newDirtyTracker(size int) *dirtyTracker {
+	pages := pageAlign(size) / pageSize
+	return &dirtyTracker{words: make([]atomic.Uint64, (pages+63)/64)}
+}
+
+// mark records the pages covering [off, off+n) as dirty.
+func (d *dirtyTracker) mark(off, n int) {
+	if n <= 0 {
+		return
+	}
+	for p := off / pageSize; p <= (off+n-1)/pageSize; p++ {
+		w := p / 64
+		if w >= len(d.words) {
+			d.all.Store(true)
+			return
+		}
+		bit := uint64(1) << (p % 64)
+		// Skip the read-modify-write when the page is already dirty: hot
+		// records are rewritten far more often than they are synced.
+		if d.words[w].Load()&bit == 0 {
+			d.words[w].Or(bit)
+		}
+	}
+}
+
+// take clears the bitmap and returns the dirty pages as contiguous spans.
+// all reports whether a write fell outside the bitmap.
+func (d *dirtyTracker) take() (spans []pageSpan, all bool) {
+	all = d.all.Swap(false)
+	start := -1
+	for w := range d.words {
+		word := d.words[w].Swap(0)
+		for p := w * 64; p < (w+1)*64; p++ {
+			if word == 0 && start < 0 {
+				break
+			}
+			dirty := word&1 != 0
+			word >>= 1
+			switch {
+			case dirty && start < 0:
+				start = p
+			case !dirty && start >= 0:
+				spans = append(spans, pageSpan{off: start * pageSize, n: (p - start) * pageSize})
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, pageSpan{off: start * pageSize, n: (len(d.words)*64 - start) * pageSize})
+	}
+	return spans, all
+}
+
+// restore re-marks spans that could not be synced.
+func (d *dirtyTracker) restore(spans []pageSpan, all bool) {
+	if all {
+		d.all.Store(true)
+	}
+	for _, sp := range spans {
+		d.mark(sp.off, sp.n)
+	}
+}
+
+// pending returns how many pages are currently marked dirty.
+func (d *dirtyTracker) pending() int {
+	n := 0
+	for w := range d.words {
+		n += bits.OnesCount64(d.words[w].Load())
+	}
+	return n
+}
+
+// reservedSize returns how many bytes b can ever map, so the dirty bitmap
+// never has to grow under concurrent writers.
+func reservedSize(b Backing) int {
+	switch r := b.(type) {
+	case *Region:
+		return r.maxVA
+	case *MemoryRegion:
+		return r.maxVA
+	default:
+		return b.Mapped()
+	}
+}
+
+// MarkDirty records the records [from, to) as dirty for SyncDirty. Writes
+// that go through SeqBeginWrite/SeqEndWrite (generated setters, Set,
+// SetAny) are tracked already; call it after raw Write* calls. No-op
+// unless the store was opened WithDirtyTracking.
+func (s *Store) MarkDirty(from, to int) {
+	if s.dirty == nil || from >= to {
+		return
+	}
+	s.dirty.mark(s.dataOff+from*s.recordSize, (to-from)*s.recordSize)
+}
+
+// SyncRange flushes the header and the records [from, to) to disk and
+// blocks until they are on stable storage. Only the pages those records
+// sit on are written back, which on a large store is far cheaper than
+// Sync. Stores without a file mapping fall back to a full Sync.
+func (s *Store) SyncRange(from, to int) error {
+	if s.region == nil {
+		return fmt.Errorf("mmapforge: sync %s: %w", s.path, ErrClosed)
+	}
+	if n := s.Len(); from < 0 || from > to || to > n {
+		return fmt.Errorf("mmapforge: sync %s: records [%d, %d): %w (count=%d)", s.path, from, to, ErrOutOfBounds, n)
+	}
+	start := time.Now()
+	err := s.syncSpans([]pageSpan{{off: s.dataOff + from*s.recordSize, n: (to - from) * s.recordSize}})
+	s.recordSync(start, err)
+	return err
+}
+
+// SyncAsync flushes the header and schedules write-back of every dirty
+// page (MS_ASYNC) without waiting for it. A crash right after it returns
+// can still lose writes; use Sync or SyncRange where that matters.
+func (s *Store) SyncAsync() error {
+	if s.region == nil {
+		return fmt.Errorf("mmapforge: sync %s: %w", s.path, ErrClosed)
+	}
+	start := time.Now()
+	err := s.syncAsync()
+	s.recordSync(start, err)
+	return err
+}
+
+func (s *Store) syncAsync() error {
+	if err := s.flushHeader(); err != nil {
+		return err
+	}
+	r, ok := s.region.(*Region)
+	if !ok {
+		return s.region.Sync()
+	}
+	return r.SyncAsync()
+}
+
+// SyncDirty flushes the header and every page written since the last
+// SyncDirty, blocking until they are on stable storage. Pages that fail
+// to sync stay dirty for the next call. Without WithDirtyTracking it is
+// the same as Sync.
+func (s *Store) SyncDirty() error {
+	if s.region == nil {
+		return fmt.Errorf("mmapforge: sync %s: %w", s.path, ErrClosed)
+	}
+	start := time.Now()
+	err := s.syncDirty()
+	s.recordSync(start, err)
+	return err
+}
+
+func (s *Store) syncDirty() error {
+	if s.dirty == nil {
+		return s.syncAll()
+	}
+
+	spans, all := s.dirty.take()
+	var err error
+	if all {
+		err = s.syncAll()
+	} else {
+		err = s.syncSpans(spans)
+	}
+	if err != nil {
+		s.dirty.restore(spans, all)
+	}
+	return err
+}
+
+// DirtyPages returns how many pages are waiting for SyncDirty, or 0 if
+// the store was not opened WithDirtyTracking.
+func (s *Store) DirtyPages() int {
+	if s.dirty == nil {
+		return 0
+	}
+	return s.dirty.pending()
+}
+
+// syncSpans flushes the header and msyncs the header page plus spans,
+// clamped to the current mapping.
+func (s *Store) syncSpans(spans []pageSpan) error {
+	if err := s.flushHeader(); err != nil {
+		return err
+	}
+	r, ok := s.region.(*Region)
+	if !ok {
+		return s.region.Sync()
+	}
+
+	mapped := r.Mapped()
+	errs := []error{r.SyncRange(0, s.dataOff)}
+	for _, sp := range spans {
+		end := min(sp.off+sp.n, mapped)
+		if sp.off >= end {
+			continue
+		}
+		errs = append(errs, r.SyncRange(sp.off, end-sp.off))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,386 @@
+package mmapforge
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+type msyncCall struct {
+	off, n int
+	flags  uintptr
+}
+
+// recordMsync replaces msyncSyscall with one that records each call as an
+// offset into s's mapping, then forwards to the real msync.
+func recordMsync(t *testing.T, s *Store) *[]msyncCall {
+	t.Helper()
+	orig := msyncSyscall
+	t.Cleanup(func() { msyncSyscall = orig })
+	calls := &[]msyncCall{}
+	msyncSyscall = func(addr, length, flags uintptr) error {
+		*calls = append(*calls, msyncCall{off: int(addr - s.base), n: int(length), flags: flags})
+		return orig(addr, length, flags)
+	}
+	return calls
+}
+
+// appendN appends records until the store holds n of them.
+func appendN(t *testing.T, s *Store, n int) {
+	t.Helper()
+	for s.Len() < n {
+		if _, err := s.Append(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// recordPage returns the mapping offset of the page record idx starts on.
+func recordPage(s *Store, idx int) int {
+	return (HeaderSize + idx*s.recordSize) / pageSize * pageSize
+}
+
+func TestDirtyTracker_MarkTake(t *testing.T) {
+	d := newDirtyTracker(pageSize * 200)
+	d.mark(0, 1)
+	d.mark(pageSize*2+10, pageSize) // pages 2 and 3
+	d.mark(pageSize*63, pageSize*2) // pages 63 and 64 straddle a word
+	d.mark(pageSize*199, 1)
+	d.mark(5, 0)
+
+	if got := d.pending(); got != 6 {
+		t.Errorf("pending = %d, want 6", got)
+	}
+
+	spans, all := d.take()
+	if all {
+		t.Error("all set without an overflow")
+	}
+	want := []pageSpan{
+		{off: 0, n: pageSize},
+		{off: pageSize * 2, n: pageSize * 2},
+		{off: pageSize * 63, n: pageSize * 2},
+		{off: pageSize * 199, n: pageSize},
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("spans = %v, want %v", spans, want)
+	}
+	for i := range want {
+		if spans[i] != want[i] {
+			t.Errorf("span %d = %v, want %v", i, spans[i], want[i])
+		}
+	}
+
+	if spans, all := d.take(); len(spans) != 0 || all {
+		t.Errorf("second take = %v, %v; want nothing", spans, all)
+	}
+}
+
+func TestDirtyTracker_RunToEnd(t *testing.T) {
+	d := newDirtyTracker(pageSize * 64)
+	d.mark(pageSize*62, pageSize*2)
+	spans, _ := d.take()
+	if len(spans) != 1 || spans[0] != (pageSpan{off: pageSize * 62, n: pageSize * 2}) {
+		t.Errorf("spans = %v", spans)
+	}
+}
+
+func TestDirtyTracker_OverflowAndRestore(t *testing.T) {
+	d := newDirtyTracker(pageSize)
+	d.mark(pageSize*100, 8)
+	spans, all := d.take()
+	if !all || len(spans) != 0 {
+		t.Fatalf("take = %v, %v; want all", spans, all)
+	}
+
+	d.restore([]pageSpan{{off: 0, n: 1}}, true)
+	if d.pending() != 1 {
+		t.Errorf("pending after restore = %d", d.pending())
+	}
+	if _, all := d.take(); !all {
+		t.Error("restore dropped the overflow flag")
+	}
+}
+
+func TestStore_SyncRange(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithCapacity(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	appendN(t, s, 4096)
+	calls := recordMsync(t, s)
+
+	if err := s.SyncRange(4000, 4001); err != nil {
+		t.Fatalf("SyncRange: %v", err)
+	}
+	page := recordPage(s, 4000)
+	if len(*calls) != 2 {
+		t.Fatalf("msync calls = %v, want header page and one record page", *calls)
+	}
+	if c := (*calls)[0]; c.off != 0 || c.n != pageSize || c.flags != syscall.MS_SYNC {
+		t.Errorf("header msync = %+v", c)
+	}
+	if c := (*calls)[1]; c.off != page || c.flags != syscall.MS_SYNC {
+		t.Errorf("record msync = %+v, want page at %d", c, page)
+	}
+
+	*calls = nil
+	if err := s.SyncRange(10, 10); err != nil {
+		t.Fatalf("empty SyncRange: %v", err)
+	}
+	if len(*calls) != 1 {
+		t.Errorf("empty range should only sync the header: %v", *calls)
+	}
+}
+
+func TestStore_SyncRange_Errors(t *testing.T) {
+	s := mustCreateStore(t)
+	appendN(t, s, 2)
+
+	if err := s.SyncRange(1, 3); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("past Len: err = %v, want ErrOutOfBounds", err)
+	}
+	if err := s.SyncRange(-1, 1); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("negative: err = %v, want ErrOutOfBounds", err)
+	}
+
+	orig := msyncSyscall
+	msyncSyscall = func(_, _, _ uintptr) error { return syscall.EIO }
+	err := s.SyncRange(0, 2)
+	msyncSyscall = orig
+	if !errors.Is(err, syscall.EIO) {
+		t.Errorf("msync failure: err = %v, want EIO", err)
+	}
+
+	saved := saveFuncs()
+	encodeHeaderFunc = func([]byte, *Header) error { return errors.New("boom") }
+	err = s.SyncRange(0, 1)
+	restoreAllFuncs(saved)
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("flush header failure: err = %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SyncRange(0, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("after Close: err = %v, want ErrClosed", err)
+	}
+}
+
+func TestStore_SyncAsync(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	appendN(t, s, 3)
+	calls := recordMsync(t, s)
+
+	if err := s.SyncAsync(); err != nil {
+		t.Fatalf("SyncAsync: %v", err)
+	}
+	if len(*calls) != 1 || (*calls)[0].flags != syscall.MS_ASYNC || (*calls)[0].n != s.region.Mapped() {
+		t.Errorf("msync calls = %+v, want one MS_ASYNC over the mapping", *calls)
+	}
+
+	msyncSyscall = func(_, _, _ uintptr) error { return syscall.EIO }
+	if err := s.SyncAsync(); !errors.Is(err, syscall.EIO) || !strings.Contains(err.Error(), "sync async") {
+		t.Errorf("msync failure: err = %v", err)
+	}
+}
+
+func TestStore_SyncAsync_Closed(t *testing.T) {
+	s := mustCreateStore(t)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SyncAsync(); !errors.Is(err, ErrClosed) {
+		t.Errorf("err = %v, want ErrClosed", err)
+	}
+}
+
+func TestStore_SyncDirty(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithCapacity(4096), WithDirtyTracking())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	appendN(t, s, 4096)
+	calls := recordMsync(t, s)
+
+	// Append only touches the header, so nothing is dirty yet.
+	if n := s.DirtyPages(); n != 0 {
+		t.Errorf("DirtyPages after Append = %d, want 0", n)
+	}
+
+	s.SeqBeginWrite(4000)
+	if err := s.WriteUint64(4000, 8, 99); err != nil {
+		t.Fatal(err)
+	}
+	s.SeqEndWrite(4000)
+	if n := s.DirtyPages(); n == 0 {
+		t.Fatal("SeqEndWrite did not mark the record dirty")
+	}
+
+	if err := s.SyncDirty(); err != nil {
+		t.Fatalf("SyncDirty: %v", err)
+	}
+	var synced bool
+	for _, c := range *calls {
+		if c.off <= recordPage(s, 4000) && recordPage(s, 4000) < c.off+c.n {
+			synced = true
+		}
+		if c.n > pageSize*2 {
+			t.Errorf("SyncDirty synced %d bytes at %d; want only the dirty pages", c.n, c.off)
+		}
+	}
+	if !synced {
+		t.Errorf("dirty record page not synced: %+v", *calls)
+	}
+	if n := s.DirtyPages(); n != 0 {
+		t.Errorf("DirtyPages after SyncDirty = %d", n)
+	}
+
+	*calls = nil
+	if err := s.SyncDirty(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 || (*calls)[0].off != 0 {
+		t.Errorf("clean SyncDirty should only sync the header: %+v", *calls)
+	}
+}
+
+func TestStore_SyncDirty_FailureKeepsPagesDirty(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithDirtyTracking())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	appendN(t, s, 10)
+	s.MarkDirty(0, 10)
+	before := s.DirtyPages()
+
+	orig := msyncSyscall
+	msyncSyscall = func(_, _, _ uintptr) error { return syscall.EIO }
+	err = s.SyncDirty()
+	msyncSyscall = orig
+	if !errors.Is(err, syscall.EIO) {
+		t.Fatalf("err = %v, want EIO", err)
+	}
+	if got := s.DirtyPages(); got != before {
+		t.Errorf("DirtyPages after failed sync = %d, want %d", got, before)
+	}
+
+	s.dirty.all.Store(true)
+	msyncSyscall = func(_, _, _ uintptr) error { return syscall.EIO }
+	err = s.SyncDirty()
+	msyncSyscall = orig
+	if !errors.Is(err, syscall.EIO) {
+		t.Fatalf("overflow sync: err = %v, want EIO", err)
+	}
+	if !s.dirty.all.Load() {
+		t.Error("failed full sync dropped the overflow flag")
+	}
+	if err := s.SyncDirty(); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if s.DirtyPages() != 0 || s.dirty.all.Load() {
+		t.Error("retry did not clear the tracker")
+	}
+}
+
+func TestStore_SyncDirty_Untracked(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	s.MarkDirty(0, 1)
+	if s.DirtyPages() != 0 {
+		t.Error("untracked store reports dirty pages")
+	}
+	calls := recordMsync(t, s)
+	if err := s.SyncDirty(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 || (*calls)[0].n != s.region.Mapped() {
+		t.Errorf("untracked SyncDirty should be a full Sync: %+v", *calls)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SyncDirty(); !errors.Is(err, ErrClosed) {
+		t.Errorf("after Close: err = %v, want ErrClosed", err)
+	}
+}
+
+func TestStore_DirtyTracking_ReadOnly(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ro, err := OpenStore(path, testLayout(), WithReadOnly(), WithDirtyTracking())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	if ro.dirty != nil {
+		t.Error("read-only store got a dirty tracker")
+	}
+}
+
+func TestStore_SyncInMemory(t *testing.T) {
+	s, err := CreateStoreInMemory(testLayout(), 1, WithDirtyTracking())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	idx, _ := s.Append()
+	s.SeqBeginWrite(idx)
+	s.SeqEndWrite(idx)
+
+	if err := s.SyncRange(0, 1); err != nil {
+		t.Errorf("SyncRange: %v", err)
+	}
+	if err := s.SyncAsync(); err != nil {
+		t.Errorf("SyncAsync: %v", err)
+	}
+	if err := s.SyncDirty(); err != nil {
+		t.Errorf("SyncDirty: %v", err)
+	}
+}
+
+func TestRegion_SyncRange(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	r := s.region.(*Region)
+
+	if err := r.SyncRange(0, r.Mapped()+1); err == nil || !strings.Contains(err.Error(), "outside mapping") {
+		t.Errorf("past end: err = %v", err)
+	}
+	if err := r.SyncRange(-1, 1); err == nil {
+		t.Error("negative offset accepted")
+	}
+	if err := r.SyncRange(5, 0); err != nil {
+		t.Errorf("empty range: %v", err)
+	}
+	if err := r.SyncRange(HeaderSize+1, 3); err != nil {
+		t.Errorf("unaligned offset: %v", err)
+	}
+}
+
+func TestRegion_SyncClosed(t *testing.T) {
+	s := mustCreateStore(t)
+	r := s.region.(*Region)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SyncRange(0, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("SyncRange: err = %v, want ErrClosed", err)
+	}
+	if err := r.SyncAsync(); !errors.Is(err, ErrClosed) {
+		t.Errorf("SyncAsync: err = %v, want ErrClosed", err)
+	}
+}
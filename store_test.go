@@ -2,10 +2,12 @@ package mmapforge
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"testing"
@@ -38,7 +40,7 @@ func mustCreateStore(t *testing.T) *Store {
 }
 
 type savedFuncs struct {
-	mf func(uintptr, int, *os.File, bool) error
+	mf func(uintptr, int, *os.File, bool, int, int) error
 	ma func(uintptr, int, int) error
 	ms func(uintptr, uintptr, uintptr) error
 	sf func(*os.File) (os.FileInfo, error)
@@ -75,6 +77,53 @@ func TestCreateStore(t *testing.T) {
 	}
 }
 
+func TestCreateStore_WithCapacity(t *testing.T) {
+	path := tempPath(t)
+	layout := testLayout()
+	s, err := CreateStore(path, layout, 1, WithCapacity(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if s.Cap() != 1000 {
+		t.Errorf("Cap = %d, want 1000", s.Cap())
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(HeaderSize) + 1000*int64(layout.RecordSize); info.Size() != want {
+		t.Errorf("file size = %d, want %d", info.Size(), want)
+	}
+	for i := 0; i < 1000; i++ {
+		if _, err := s.Append(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Cap() != 1000 {
+		t.Errorf("Cap = %d after 1000 appends, want 1000 (no grow)", s.Cap())
+	}
+}
+
+func TestCreateStore_WithCapacityBelowDefault(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithCapacity(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Cap() != initialCapacity {
+		t.Errorf("Cap = %d, want %d", s.Cap(), initialCapacity)
+	}
+}
+
+func TestCreateStore_WithCapacityOverflow(t *testing.T) {
+	_, err := CreateStore(tempPath(t), testLayout(), 1, WithCapacity(math.MaxInt))
+	if err == nil || !strings.Contains(err.Error(), "overflows") {
+		t.Errorf("err = %v, want overflow error", err)
+	}
+}
+
 func TestCreateStore_AlreadyExists(t *testing.T) {
 	path := tempPath(t)
 	layout := testLayout()
@@ -102,7 +151,7 @@ func TestCreateStore_MapFails(t *testing.T) {
 	saved := saveFuncs()
 	defer restoreAllFuncs(saved)
 
-	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool) error {
+	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool, _, _ int) error {
 		return syscall.ENOMEM
 	}
 
@@ -239,6 +288,100 @@ func TestOpenStore_BadMagic(t *testing.T) {
 	}
 }
 
+// writeV1File writes a format version 1 store (64-byte header) holding
+// records whose id field is ids[i].
+func writeV1File(t *testing.T, path string, ids ...uint64) {
+	t.Helper()
+	layout := testLayout()
+	h := &Header{
+		Magic:         Magic,
+		FormatVersion: 1,
+		SchemaHash:    SchemaHash(layout.Descriptors()),
+		SchemaVersion: 1,
+		RecordSize:    layout.RecordSize,
+		RecordCount:   uint64(len(ids)),
+		Capacity:      uint64(len(ids)),
+	}
+	buf := make([]byte, HeaderSizeV1+len(ids)*int(layout.RecordSize))
+	if err := EncodeHeader(buf, h); err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range ids {
+		off := HeaderSizeV1 + i*int(layout.RecordSize) + int(layout.Fields[0].Offset)
+		binary.LittleEndian.PutUint64(buf[off:], id)
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenStore_FormatV1(t *testing.T) {
+	path := tempPath(t)
+	writeV1File(t, path, 7, 8)
+
+	s, err := OpenStore(path, testLayout())
+	if err != nil {
+		t.Fatalf("OpenStore v1: %v", err)
+	}
+	for i, want := range []uint64{7, 8} {
+		if v, err := s.ReadUint64(i, 8); err != nil || v != want {
+			t.Errorf("record %d id = %d, %v; want %d", i, v, err, want)
+		}
+	}
+	// Grow past the two records the file was written with.
+	for i := 0; i < 5; i++ {
+		idx, err := s.Append()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.WriteUint64(idx, 8, uint64(100+idx)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := DecodeHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.FormatVersion != 1 || h.RecordCount != 7 {
+		t.Errorf("header after writes: version %d, count %d; want 1, 7", h.FormatVersion, h.RecordCount)
+	}
+
+	s, err = OpenStore(path, testLayout(), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if v, err := s.ReadUint64(0, 8); err != nil || v != 7 {
+		t.Errorf("record 0 id = %d, %v", v, err)
+	}
+	if v, err := s.ReadUint64(6, 8); err != nil || v != 106 {
+		t.Errorf("record 6 id = %d, %v", v, err)
+	}
+}
+
+func TestOpenStore_FormatV1MultiWriter(t *testing.T) {
+	path := tempPath(t)
+	writeV1File(t, path, 1)
+	if _, err := OpenStore(path, testLayout(), WithMultiWriter()); err == nil || !strings.Contains(err.Error(), "WithMultiWriter needs format version 2") {
+		t.Errorf("err = %v", err)
+	}
+	s, err := OpenStore(path, testLayout(), WithMultiWriter(), WithReadOnly())
+	if err != nil {
+		t.Fatalf("read-only open: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestOpenStore_BadFormatVersion(t *testing.T) {
 	path := tempPath(t)
 	layout := testLayout()
@@ -300,7 +443,7 @@ func TestOpenStore_MapFails(t *testing.T) {
 	saved := saveFuncs()
 	defer restoreAllFuncs(saved)
 
-	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool) error {
+	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool, _, _ int) error {
 		return syscall.ENOMEM
 	}
 
@@ -598,13 +741,15 @@ func TestAppend_GrowFails(t *testing.T) {
 		s.Close()
 	}()
 
-	for i := 0; i < initialCapacity; i++ {
+	// Fill the store until the next grow needs a new page: growth within
+	// the last mapped page maps nothing.
+	for s.Len() < s.Cap() || s.dataOff+2*s.Cap()*s.recordSize <= pageAlign(s.region.Mapped()) {
 		if _, err := s.Append(); err != nil {
-			t.Fatalf("Append %d: %v", i, err)
+			t.Fatalf("Append %d: %v", s.Len(), err)
 		}
 	}
 
-	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool) error {
+	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool, _, _ int) error {
 		return syscall.ENOMEM
 	}
 
@@ -665,7 +810,9 @@ func TestGrow_RegionGrowFails(t *testing.T) {
 		s.Close()
 	}()
 
-	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool) error {
+	// Big enough that the grow needs pages beyond the mapping.
+	s.capacityPtr.Store(uint64(pageSize))
+	mmapFixedFunc = func(_ uintptr, _ int, _ *os.File, _ bool, _, _ int) error {
 		return syscall.ENOMEM
 	}
 
@@ -1367,3 +1514,247 @@ func TestOpenStore_WithOneWriter_AcquireLockFails(t *testing.T) {
 		t.Fatal("expected error when lock file cannot be created")
 	}
 }
+
+// sharedBacking is a Backing whose Close leaves the memory mapped, so a
+// test can reopen a store in the same memory.
+type sharedBacking struct {
+	*MemoryRegion
+}
+
+func (sharedBacking) Close() error { return nil }
+
+func TestCreateStoreInMemory(t *testing.T) {
+	s, err := CreateStoreInMemory(testLayout(), 1)
+	if err != nil {
+		t.Fatalf("CreateStoreInMemory: %v", err)
+	}
+	defer s.Close()
+
+	// Grow past the initial capacity; slices taken before stay valid.
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := s.WriteUint64(idx, 8, 42); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < initialCapacity*2; i++ {
+		if _, err := s.Append(); err != nil {
+			t.Fatalf("Append %d: %v", i, err)
+		}
+	}
+	if s.Cap() < initialCapacity*2 {
+		t.Errorf("Cap = %d, want grown", s.Cap())
+	}
+	if v, err := s.ReadUint64(idx, 8); err != nil || v != 42 {
+		t.Errorf("ReadUint64 = %d, %v", v, err)
+	}
+	if err := s.Sync(); err != nil {
+		t.Errorf("Sync: %v", err)
+	}
+}
+
+func TestCreateStoreInMemory_Errors(t *testing.T) {
+	if _, err := CreateStoreInMemory(testLayout(), 1, WithReadOnly()); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("read-only: err = %v", err)
+	}
+	if _, err := CreateStoreInMemory(testLayout(), 1, WithOneWriter()); err == nil || !strings.Contains(err.Error(), "needs a file-backed store") {
+		t.Errorf("one writer: err = %v", err)
+	}
+	if _, err := CreateStoreInMemory(testLayout(), 1, WithCapacity(math.MaxInt)); err == nil || !strings.Contains(err.Error(), "overflows address space") {
+		t.Errorf("capacity overflow: err = %v", err)
+	}
+	if _, err := CreateStoreInMemory(testLayout(), 1, WithCapacity(StoreReserveVA)); err == nil || !strings.Contains(err.Error(), "exceeds max VA reservation") {
+		t.Errorf("capacity above reservation: err = %v", err)
+	}
+
+	orig := mapMemoryFunc
+	defer func() { mapMemoryFunc = orig }()
+	mapMemoryFunc = func(int, ...int) (*MemoryRegion, error) { return nil, syscall.ENOMEM }
+	if _, err := CreateStoreInMemory(testLayout(), 1); !errors.Is(err, syscall.ENOMEM) {
+		t.Errorf("map memory: err = %v", err)
+	}
+}
+
+func TestCreateStoreWithBacking_EncodeHeaderFails(t *testing.T) {
+	saved := saveFuncs()
+	defer restoreAllFuncs(saved)
+	encodeHeaderFunc = func([]byte, *Header) error { return errors.New("encode failed") }
+
+	r, err := MapMemory(pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateStoreWithBacking(r, testLayout(), 1); err == nil || !strings.Contains(err.Error(), "encode header") {
+		t.Errorf("err = %v", err)
+	}
+	if r.Mapped() != 0 {
+		t.Error("backing should be closed when creation fails")
+	}
+}
+
+func TestOpenStoreWithBacking(t *testing.T) {
+	r, err := MapMemory(pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b := sharedBacking{r}
+
+	s, err := CreateStoreWithBacking(b, testLayout(), 3)
+	if err != nil {
+		t.Fatalf("CreateStoreWithBacking: %v", err)
+	}
+	idx, _ := s.Append()
+	if err := s.WriteFloat64(idx, 16, 1.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := OpenStoreWithBacking(b, testLayout(), WithReadOnly())
+	if err != nil {
+		t.Fatalf("OpenStoreWithBacking: %v", err)
+	}
+	defer s2.Close()
+	if s2.Len() != 1 || s2.header.SchemaVersion != 3 {
+		t.Errorf("Len = %d, header = %+v", s2.Len(), s2.header)
+	}
+	if v, err := s2.ReadFloat64(idx, 16); err != nil || v != 1.5 {
+		t.Errorf("ReadFloat64 = %v, %v", v, err)
+	}
+	if _, err := s2.Append(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Append on read-only: err = %v", err)
+	}
+
+	other, _ := ComputeLayout([]FieldDef{{Name: "x", Type: FieldInt32}})
+	if _, err := OpenStoreWithBacking(b, other); err == nil || !strings.Contains(err.Error(), "schema hash mismatch") {
+		t.Errorf("schema mismatch: err = %v", err)
+	}
+}
+
+func TestOpenStoreWithBacking_Errors(t *testing.T) {
+	r, err := MapMemory(pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenStoreWithBacking(r, testLayout()); err == nil || !strings.Contains(err.Error(), "decode header") {
+		t.Errorf("empty memory: err = %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenStoreWithBacking(r, testLayout()); err == nil || !strings.Contains(err.Error(), "memory is too small (0 bytes)") {
+		t.Errorf("closed memory: err = %v", err)
+	}
+}
+
+func TestStore_Hints(t *testing.T) {
+	stubHints(t, nil, syscall.EPERM)
+
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1, WithPrefault(), WithMlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Hints(); got != (MapHints{Prefault: true}) {
+		t.Errorf("Hints = %+v, want only Prefault honoured", got)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore(path, testLayout(), WithTransparentHugePages())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, want := s.Hints(), (MapHints{HugePages: madvHugepage >= 0}); got != want {
+		t.Errorf("Hints = %+v, want %+v", got, want)
+	}
+
+	m, err := CreateStoreInMemory(testLayout(), 1, WithMlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if m.Hints() != (MapHints{}) {
+		t.Errorf("in-memory Hints = %+v, want none", m.Hints())
+	}
+}
+
+func TestStore_Advise(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithAccessPattern(Sequential))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		idx, _ := s.Append()
+		if err := s.WriteUint64(idx, 8, uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Advise(2, 8, DontNeed); err != nil {
+		t.Fatalf("Advise: %v", err)
+	}
+	if v, err := s.ReadUint64(5, 8); err != nil || v != 5 {
+		t.Errorf("ReadUint64 after DontNeed = %d, %v", v, err)
+	}
+	if err := s.Advise(0, 10, WillNeed); err != nil {
+		t.Errorf("Advise whole store: %v", err)
+	}
+	for _, r := range [][2]int{{-1, 2}, {5, 4}, {0, 11}} {
+		if err := s.Advise(r[0], r[1], WillNeed); !errors.Is(err, ErrOutOfBounds) {
+			t.Errorf("Advise(%d, %d): err = %v, want ErrOutOfBounds", r[0], r[1], err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Advise(0, 1, WillNeed); !errors.Is(err, ErrClosed) {
+		t.Errorf("after Close: err = %v", err)
+	}
+
+	if _, err := CreateStore(tempPath(t), testLayout(), 1, WithAccessPattern(DontNeed)); err == nil {
+		t.Error("expected error for DontNeed as the store's access pattern")
+	}
+
+	m, err := CreateStoreInMemory(testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := m.Advise(0, 0, WillNeed); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("in memory: err = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestStore_SyncKeepsGrownCapacity(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, s, initialCapacity+1)
+	grown := s.Cap()
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Cap() != grown {
+		t.Errorf("Cap after Sync = %d, want %d", s.Cap(), grown)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore(path, testLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Cap() != grown || s.Len() != initialCapacity+1 {
+		t.Errorf("reopened Len/Cap = %d/%d, want %d/%d", s.Len(), s.Cap(), initialCapacity+1, grown)
+	}
+}
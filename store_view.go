@@ -0,0 +1,127 @@
+//go:build unix
+
+package mmapforge
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// RecordView is a zero-copy, read-only window onto one record, passed to
+// ViewRecord callbacks. Its accessors take field offsets from the store's
+// layout and decode straight from the mapping: nothing is copied or
+// allocated, and nothing is checked. A bool byte other than 0 reads as
+// true, and a string or bytes length prefix over maxSize is clamped to
+// maxSize. A view, and any string or slice it returns, is only valid
+// inside the callback.
+type RecordView struct {
+	b []byte
+}
+
+// Raw returns the record's bytes, seqlock counter included.
+func (v RecordView) Raw() []byte {
+	return v.b
+}
+
+// Bool reads the bool at offset.
+func (v RecordView) Bool(offset uint32) bool {
+	return v.b[offset] != 0
+}
+
+// Int8 reads the int8 at offset.
+func (v RecordView) Int8(offset uint32) int8 {
+	return int8(v.b[offset])
+}
+
+// Uint8 reads the uint8 at offset.
+func (v RecordView) Uint8(offset uint32) uint8 {
+	return v.b[offset]
+}
+
+// Int16 reads the int16 at offset.
+func (v RecordView) Int16(offset uint32) int16 {
+	return int16(binary.LittleEndian.Uint16(v.b[offset:]))
+}
+
+// Uint16 reads the uint16 at offset.
+func (v RecordView) Uint16(offset uint32) uint16 {
+	return binary.LittleEndian.Uint16(v.b[offset:])
+}
+
+// Int32 reads the int32 at offset.
+func (v RecordView) Int32(offset uint32) int32 {
+	return int32(binary.LittleEndian.Uint32(v.b[offset:]))
+}
+
+// Uint32 reads the uint32 at offset.
+func (v RecordView) Uint32(offset uint32) uint32 {
+	return binary.LittleEndian.Uint32(v.b[offset:])
+}
+
+// Int64 reads the int64 at offset.
+func (v RecordView) Int64(offset uint32) int64 {
+	return int64(binary.LittleEndian.Uint64(v.b[offset:]))
+}
+
+// Uint64 reads the uint64 at offset.
+func (v RecordView) Uint64(offset uint32) uint64 {
+	return binary.LittleEndian.Uint64(v.b[offset:])
+}
+
+// Float32 reads the float32 at offset.
+func (v RecordView) Float32(offset uint32) float32 {
+	return math.Float32frombits(binary.LittleEndian.Uint32(v.b[offset:]))
+}
+
+// Float64 reads the float64 at offset.
+func (v RecordView) Float64(offset uint32) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(v.b[offset:]))
+}
+
+// String returns the string at offset without copying it.
+func (v RecordView) String(offset, maxSize uint32) string {
+	b := v.Bytes(offset, maxSize)
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b))
+}
+
+// Bytes returns the byte slice at offset without copying it.
+func (v RecordView) Bytes(offset, maxSize uint32) []byte {
+	n := min(binary.LittleEndian.Uint32(v.b[offset:]), maxSize)
+	start := offset + 4
+	return v.b[start : start+n : start+n]
+}
+
+// ViewRecord calls fn with a view of record idx under the seqlock, and
+// returns fn's error once a read has gone through without a writer getting
+// in the way. fn runs again after every read a writer spoiled, so it must
+// not act on what it reads, or keep the view, until ViewRecord returns;
+// errors from spoiled reads are discarded with them. Like the generated
+// getters, it gives up with ctx's error or ErrWriterStalled (see
+// SeqReadRetry).
+func (s *Store) ViewRecord(ctx context.Context, idx int, fn func(v RecordView) error) error {
+	if s.region == nil {
+		return fmt.Errorf("mmapforge: view record %d: %w", idx, ErrClosed)
+	}
+	if count := s.recordCountPtr.Load(); idx < 0 || uint64(idx) >= count {
+		return fmt.Errorf("mmapforge: view record %d: %w (count=%d)", idx, ErrOutOfBounds, count)
+	}
+	v := RecordView{b: s.region.Slice(s.dataOff+idx*s.recordSize, s.recordSize)}
+	for attempt := 0; ; attempt++ {
+		seq := s.SeqReadBegin(idx)
+		if seq&1 == 0 {
+			err := fn(v)
+			if s.SeqReadValid(idx, seq) {
+				return err
+			}
+		}
+		if err := s.SeqReadRetry(ctx, idx, attempt); err != nil {
+			return err
+		}
+	}
+}
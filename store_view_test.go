@@ -0,0 +1,161 @@
+package mmapforge
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestViewRecord_Fields(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	defer s.Close()
+
+	values := map[string]any{
+		"flag": true, "i8": int8(-8), "u8": uint8(8), "i16": int16(-16), "u16": uint16(16),
+		"i32": int32(-32), "u32": uint32(32), "i64": int64(-64), "u64": uint64(64),
+		"f32": float32(3.5), "f64": 6.25, "name": "gopher", "data": []byte{1, 2},
+	}
+	for name, v := range values {
+		if err := s.SetAny(0, name, v); err != nil {
+			t.Fatalf("SetAny(%s): %v", name, err)
+		}
+	}
+	off := func(name string) uint32 {
+		f, _ := s.Field(name)
+		return f.Offset
+	}
+
+	err := s.ViewRecord(context.Background(), 0, func(v RecordView) error {
+		got := map[string]any{
+			"flag": v.Bool(off("flag")), "i8": v.Int8(off("i8")), "u8": v.Uint8(off("u8")),
+			"i16": v.Int16(off("i16")), "u16": v.Uint16(off("u16")),
+			"i32": v.Int32(off("i32")), "u32": v.Uint32(off("u32")),
+			"i64": v.Int64(off("i64")), "u64": v.Uint64(off("u64")),
+			"f32": v.Float32(off("f32")), "f64": v.Float64(off("f64")),
+			"name": v.String(off("name"), 8),
+		}
+		for name, want := range values {
+			if name == "data" {
+				continue
+			}
+			if got[name] != want {
+				t.Errorf("%s = %v, want %v", name, got[name], want)
+			}
+		}
+		if b := v.Bytes(off("data"), 8); !bytes.Equal(b, []byte{1, 2}) || cap(b) != 2 {
+			t.Errorf("data = %v (cap %d), want [1 2] capped", b, cap(b))
+		}
+		if len(v.Raw()) != int(s.layout.RecordSize) {
+			t.Errorf("Raw is %d bytes, want %d", len(v.Raw()), s.layout.RecordSize)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestViewRecord_ClampsLengths(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	defer s.Close()
+	name, _ := s.Field("name")
+	data, _ := s.Field("data")
+	rec := s.region.Slice(HeaderSize, s.recordSize)
+	binary.LittleEndian.PutUint32(rec[name.Offset:], 1000)
+	binary.LittleEndian.PutUint32(rec[data.Offset:], 1000)
+
+	_ = s.ViewRecord(context.Background(), 0, func(v RecordView) error {
+		if got := v.String(name.Offset, name.MaxSize); len(got) != int(name.MaxSize) {
+			t.Errorf("len(name) = %d, want %d", len(got), name.MaxSize)
+		}
+		if got := v.Bytes(data.Offset, data.MaxSize); len(got) != int(data.MaxSize) {
+			t.Errorf("len(data) = %d, want %d", len(got), data.MaxSize)
+		}
+		if got := v.String(data.Offset+4, 0); got != "" {
+			t.Errorf("empty string = %q", got)
+		}
+		return nil
+	})
+}
+
+func TestViewRecord_RetriesSpoiledReads(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	if _, err := s.Append(); err != nil {
+		t.Fatal(err)
+	}
+
+	errTorn := errors.New("torn")
+	calls := 0
+	err := s.ViewRecord(context.Background(), 0, func(RecordView) error {
+		calls++
+		if calls == 1 {
+			s.SeqBeginWrite(0)
+			s.SeqEndWrite(0)
+			return errTorn
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("err = %v after %d calls, want nil after 2", err, calls)
+	}
+
+	errStop := errors.New("stop")
+	if err := s.ViewRecord(context.Background(), 0, func(RecordView) error { return errStop }); !errors.Is(err, errStop) {
+		t.Errorf("err = %v, want the callback's error", err)
+	}
+}
+
+func TestViewRecord_Errors(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithReadBackoff(ReadBackoff{MaxRetries: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noop := func(RecordView) error { return nil }
+	if err := s.ViewRecord(context.Background(), 0, noop); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("empty store: err = %v, want ErrOutOfBounds", err)
+	}
+	if _, err := s.Append(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ViewRecord(context.Background(), -1, noop); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("negative index: err = %v, want ErrOutOfBounds", err)
+	}
+
+	s.SeqBeginWrite(0)
+	if err := s.ViewRecord(context.Background(), 0, noop); !errors.Is(err, ErrWriterStalled) {
+		t.Errorf("stuck writer: err = %v, want ErrWriterStalled", err)
+	}
+	s.SeqEndWrite(0)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ViewRecord(context.Background(), 0, noop); !errors.Is(err, ErrClosed) {
+		t.Errorf("closed: err = %v, want ErrClosed", err)
+	}
+}
+
+func TestViewRecord_NoAllocs(t *testing.T) {
+	s := mustCreateDynamicStore(t)
+	defer s.Close()
+	if err := s.SetAny(0, "name", "gopher"); err != nil {
+		t.Fatal(err)
+	}
+	name, _ := s.Field("name")
+	u64, _ := s.Field("u64")
+	ctx := context.Background()
+
+	var n int
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = s.ViewRecord(ctx, 0, func(v RecordView) error {
+			n += len(v.String(name.Offset, name.MaxSize)) + int(v.Uint64(u64.Offset))
+			return nil
+		})
+	})
+	if allocs != 0 {
+		t.Errorf("ViewRecord allocated %v times per call", allocs)
+	}
+}
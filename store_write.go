@@ -4,7 +4,6 @@ package mmapforge
 
 import (
 	"encoding/binary"
-	"fmt"
 	"math"
 	"unsafe"
 )
@@ -123,6 +122,26 @@ func (s *Store) WriteFloat64(idx int, offset uint32, val float64) error {
 	return nil
 }
 
+// CheckWrite returns the error a Write* call of an n-byte value to the
+// field at offset of record idx would fail with, without writing anything:
+// ErrClosed, ErrOutOfBounds, or ErrStringTooLong/ErrBytesTooLong when n
+// exceeds the field's maxSize. Fixed-size fields pass n and maxSize as 0.
+// Multi-field writes check every field before SeqBeginWrite, so a bad
+// value cannot leave a record half written.
+func (s *Store) CheckWrite(idx int, offset uint32, n int, maxSize uint32) error {
+	if _, err := s.fieldSlice(idx, offset, 0); err != nil {
+		return err
+	}
+	if n > int(maxSize) {
+		tooLong := ErrStringTooLong
+		if f, ok := s.layout.fieldAt(offset); ok && f.Type == FieldBytes {
+			tooLong = ErrBytesTooLong
+		}
+		return s.fieldError(idx, offset, n, maxSize, tooLong)
+	}
+	return nil
+}
+
 // maxLenThreshold is the upper bound for string/byte lengths representable
 // by a 4-byte LE prefix. Defaults to math.MaxUint32; tests override it to
 // exercise the overflow path without allocating alot here
@@ -133,7 +152,7 @@ func (s *Store) WriteString(idx int, offset, fieldSize, maxSize uint32, val stri
 	n := len(val)
 	if n <= maxLenThreshold {
 		if n > int(maxSize) {
-			return fmt.Errorf("mmapforge: field at offset %d: %w (len=%d max=%d)", offset, ErrStringTooLong, len(val), maxSize)
+			return s.fieldError(idx, offset, len(val), maxSize, ErrStringTooLong)
 		}
 		b, err := s.fieldSlice(idx, offset, fieldSize)
 		if err != nil {
@@ -148,7 +167,7 @@ func (s *Store) WriteString(idx int, offset, fieldSize, maxSize uint32, val stri
 		return nil
 	}
 
-	return fmt.Errorf("mmapforge: field at offset %d: %w (len=%d max=%d)", offset, ErrStringTooLong, len(val), maxSize)
+	return s.fieldError(idx, offset, len(val), maxSize, ErrStringTooLong)
 }
 
 // WriteBytes writes a length-prefixed byte slice into the field, zero-padding the remainder.
@@ -156,7 +175,7 @@ func (s *Store) WriteBytes(idx int, offset, fieldSize, maxSize uint32, val []byt
 	n := len(val)
 	if n >= 0 && n <= maxLenThreshold {
 		if n > int(maxSize) {
-			return fmt.Errorf("mmapforge: field at offset %d: %w (len=%d max=%d)", offset, ErrBytesTooLong, len(val), maxSize)
+			return s.fieldError(idx, offset, len(val), maxSize, ErrBytesTooLong)
 		}
 		b, err := s.fieldSlice(idx, offset, fieldSize)
 		if err != nil {
@@ -171,5 +190,5 @@ func (s *Store) WriteBytes(idx int, offset, fieldSize, maxSize uint32, val []byt
 		return nil
 	}
 
-	return fmt.Errorf("mmapforge: field at offset %d: %w (len=%d max=%d)", offset, ErrBytesTooLong, len(val), maxSize)
+	return s.fieldError(idx, offset, len(val), maxSize, ErrBytesTooLong)
 }
@@ -3,6 +3,7 @@
 package mmapforge
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -382,8 +383,16 @@ func TestWriteString_TooLong(t *testing.T) {
 	for i := range long {
 		long[i] = 'x'
 	}
-	if err := s.WriteString(idx, f.Offset, f.Size, f.MaxSize, string(long)); err == nil {
-		t.Fatal("expected error for string too long")
+	err = s.WriteString(idx, f.Offset, f.Size, f.MaxSize, string(long))
+	if !errors.Is(err, ErrStringTooLong) {
+		t.Fatalf("err = %v, want ErrStringTooLong", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("err = %T, want *FieldError", err)
+	}
+	if fe.Field != "name" || fe.Index != idx || fe.Len != len(long) || fe.Max != f.MaxSize {
+		t.Errorf("FieldError = %+v, want field name, index %d, len %d, max %d", fe, idx, len(long), f.MaxSize)
 	}
 }
 
@@ -530,3 +539,38 @@ func TestWriteBool_Closed(t *testing.T) {
 		t.Fatal("expected error writing to closed store")
 	}
 }
+
+func TestCheckWrite(t *testing.T) {
+	layout := testStringLayout()
+	s, err := CreateStore(tempPath(t), layout, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, data := layout.Fields[0], layout.Fields[1]
+
+	if err := s.CheckWrite(0, 0, 0, 0); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("empty store: err = %v, want ErrOutOfBounds", err)
+	}
+	idx, err := s.Append()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckWrite(idx, name.Offset, int(name.MaxSize), name.MaxSize); err != nil {
+		t.Errorf("string at max size: %v", err)
+	}
+	err = s.CheckWrite(idx, name.Offset, int(name.MaxSize)+1, name.MaxSize)
+	var fe *FieldError
+	if !errors.Is(err, ErrStringTooLong) || !errors.As(err, &fe) || fe.Field != "name" {
+		t.Errorf("long string: err = %v, want a *FieldError for name wrapping ErrStringTooLong", err)
+	}
+	if err := s.CheckWrite(idx, data.Offset, int(data.MaxSize)+1, data.MaxSize); !errors.Is(err, ErrBytesTooLong) {
+		t.Errorf("long bytes: err = %v, want ErrBytesTooLong", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckWrite(idx, 0, 0, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("closed: err = %v, want ErrClosed", err)
+	}
+}
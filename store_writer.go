@@ -0,0 +1,110 @@
+package mmapforge
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// The writer record lives in the format version 2 header, bytes 72-96,
+// little-endian: PID uint64, last heartbeat in Unix nanoseconds int64,
+// heartbeat interval in nanoseconds int64. A zero PID means no writer.
+// Each word is read and written atomically in place, so readers in other
+// processes see it however they opened the store (path, memfd, a
+// descriptor passed over SCM_RIGHTS); EncodeHeader never touches it.
+// Format version 1 headers have no room for it.
+
+// writerRecord is the writer record as it sits in the mapped header.
+type writerRecord struct {
+	pid      atomic.Uint64
+	beat     atomic.Uint64
+	interval atomic.Uint64
+}
+
+// noWriter stands in for the writer record of stores that have none
+// (format version 1, or closed), so Writer reads PID 0.
+var noWriter writerRecord
+
+// functions can be overridden for testing
+var processAliveFunc = processAlive
+
+// WriterInfo describes the process recorded as a store's writer.
+type WriterInfo struct {
+	// PID is the writer's process ID, 0 if no writer is recorded.
+	PID int
+
+	// Heartbeat is the writer's last heartbeat and Interval how often it
+	// beats. Heartbeat is when the writer opened the store unless it
+	// opened WithWriterHeartbeat; Interval is zero then.
+	Heartbeat time.Time
+	Interval  time.Duration
+
+	// Alive is false once the process has exited, or its heartbeat is
+	// more than three intervals old.
+	Alive bool
+}
+
+// Writer reads the writer record from the store header. Readers in other
+// processes use it to tell a slow writer from a dead one. A writable store
+// records itself when it opens and clears the record on Close.
+//
+// PID 0 means no writer is recorded: none has the store open, or it is a
+// WithMultiWriter store, whose several writers keep no record, or a format
+// version 1 file. Liveness is checked by PID, so it only means something
+// to readers in the writer's PID namespace.
+func (s *Store) Writer() (WriterInfo, error) {
+	if s.region == nil {
+		return WriterInfo{}, fmt.Errorf("mmapforge: writer %s: %w", s.path, ErrClosed)
+	}
+	rec := s.writer
+	w := WriterInfo{PID: int(rec.pid.Load())}
+	if w.PID == 0 {
+		return w, nil
+	}
+	w.Heartbeat = time.Unix(0, int64(rec.beat.Load()))
+	w.Interval = time.Duration(rec.interval.Load())
+	w.Alive = processAliveFunc(w.PID)
+	if w.Alive && w.Interval > 0 && time.Since(w.Heartbeat) > 3*w.Interval {
+		w.Alive = false
+	}
+	return w, nil
+}
+
+// claimWriter records this process as the store's writer, refreshing its
+// heartbeat every interval if interval > 0. No-op for stores that keep no
+// writer record.
+func (s *Store) claimWriter(interval time.Duration) {
+	if !s.writable || s.multi || s.writer == &noWriter {
+		return
+	}
+	s.writer.interval.Store(uint64(max(interval, 0)))
+	s.writer.beat.Store(uint64(time.Now().UnixNano()))
+	s.writer.pid.Store(uint64(os.Getpid()))
+	s.ownsWriter = true
+	if interval > 0 {
+		s.heartbeat = startLoop(interval, s.beat)
+	}
+}
+
+// releaseWriter stops the heartbeat and clears the writer record, unless
+// another writer has claimed it since.
+func (s *Store) releaseWriter() {
+	if s.heartbeat != nil {
+		s.heartbeat.halt()
+		s.heartbeat = nil
+	}
+	if !s.ownsWriter {
+		return
+	}
+	s.ownsWriter = false
+	if s.writer.pid.CompareAndSwap(uint64(os.Getpid()), 0) {
+		s.writer.beat.Store(0)
+		s.writer.interval.Store(0)
+	}
+}
+
+// beat refreshes the heartbeat in the writer record.
+func (s *Store) beat() {
+	s.writer.beat.Store(uint64(time.Now().UnixNano()))
+}
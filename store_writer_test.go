@@ -0,0 +1,302 @@
+package mmapforge
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeqReadRetry_Backoff(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	s.backoff = ReadBackoff{Spins: 2, MaxRetries: 5}
+	ctx := context.Background()
+
+	for attempt := range 5 {
+		if err := s.SeqReadRetry(ctx, 0, attempt); err != nil {
+			t.Fatalf("attempt %d: %v", attempt, err)
+		}
+	}
+	err := s.SeqReadRetry(ctx, 0, 5)
+	if !errors.Is(err, ErrWriterStalled) || !strings.Contains(err.Error(), "gave up after 5 retries") {
+		t.Errorf("past budget: err = %v", err)
+	}
+	if got := s.Stats().SeqlockRetries; got != 6 {
+		t.Errorf("SeqlockRetries = %d, want 6", got)
+	}
+}
+
+func TestSeqReadRetry_Context(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+	s.backoff = ReadBackoff{Spins: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.SeqReadRetry(ctx, 3, 0); err != nil {
+		t.Errorf("spinning attempts should not check ctx: %v", err)
+	}
+	if err := s.SeqReadRetry(ctx, 3, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if err := s.SeqReadRetry(context.Background(), 3, 1<<30); err != nil {
+		t.Errorf("MaxRetries 0 should never give up: %v", err)
+	}
+}
+
+func TestSeqReadRetry_DeadWriter(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testLayout(), 1, WithOneWriter(), WithReadBackoff(ReadBackoff{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	orig := processAliveFunc
+	defer func() { processAliveFunc = orig }()
+	processAliveFunc = func(int) bool { return false }
+
+	if err := s.SeqReadRetry(context.Background(), 0, writerCheckEvery-2); err != nil {
+		t.Errorf("writer checked too early: %v", err)
+	}
+	err = s.SeqReadRetry(context.Background(), 0, writerCheckEvery-1)
+	if !errors.Is(err, ErrWriterStalled) || !strings.Contains(err.Error(), "is dead") {
+		t.Errorf("err = %v, want a dead writer", err)
+	}
+}
+
+func TestStalledReaders(t *testing.T) {
+	s, err := CreateStore(tempPath(t), testDynamicLayout(), 1, WithReadBackoff(ReadBackoff{Spins: 1, MaxRetries: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.Append(); err != nil {
+		t.Fatal(err)
+	}
+	s.SeqBeginWrite(0)
+	defer s.SeqEndWrite(0)
+
+	if _, err := s.GetAny(0, "name"); !errors.Is(err, ErrWriterStalled) {
+		t.Errorf("GetAny: err = %v", err)
+	}
+	if _, err := s.GetRecordMap(0); !errors.Is(err, ErrWriterStalled) {
+		t.Errorf("GetRecordMap: err = %v", err)
+	}
+	if _, err := s.recordBytes(0, nil); !errors.Is(err, ErrWriterStalled) {
+		t.Errorf("recordBytes: err = %v", err)
+	}
+}
+
+func TestDiffStores_StalledWriter(t *testing.T) {
+	a, b := diffPair(t, 1)
+	b.backoff = ReadBackoff{MaxRetries: 1}
+	b.SeqBeginWrite(0)
+	defer b.SeqEndWrite(0)
+	for d := range DiffStores(a, b) {
+		if !errors.Is(d.Err, ErrWriterStalled) {
+			t.Errorf("diff = %+v, want ErrWriterStalled", d)
+		}
+	}
+	a.backoff = ReadBackoff{MaxRetries: 1}
+	for d := range DiffStores(b, a) {
+		if !errors.Is(d.Err, ErrWriterStalled) {
+			t.Errorf("reversed diff = %+v, want ErrWriterStalled", d)
+		}
+	}
+}
+
+func TestTypedStore_GetCtx(t *testing.T) {
+	type tick struct {
+		Price float64 `mmap:"price"`
+	}
+	s, err := Open[tick](tempPath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	idx, err := s.Append(&tick{Price: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SeqBeginWrite(idx)
+	defer s.SeqEndWrite(idx)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.GetCtx(ctx, idx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestWithReadBackoff_ClampsNegative(t *testing.T) {
+	cfg := applyOptions([]StoreOption{WithReadBackoff(ReadBackoff{Spins: -1, MaxRetries: -5})})
+	if cfg.backoff != (ReadBackoff{}) {
+		t.Errorf("backoff = %+v", cfg.backoff)
+	}
+	if applyOptions(nil).backoff != DefaultReadBackoff {
+		t.Error("default backoff not applied")
+	}
+}
+
+func TestStore_Writer(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := OpenStore(path, testLayout(), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	w, err := reader.Writer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.PID != os.Getpid() || !w.Alive || w.Heartbeat.IsZero() || w.Interval != 0 {
+		t.Errorf("Writer = %+v", w)
+	}
+
+	orig := processAliveFunc
+	processAliveFunc = func(int) bool { return false }
+	w, _ = reader.Writer()
+	processAliveFunc = orig
+	if w.Alive {
+		t.Error("dead process reported alive")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w, err := reader.Writer(); err != nil || w.PID != 0 || !w.Heartbeat.IsZero() {
+		t.Errorf("after the writer closed: %+v, %v", w, err)
+	}
+	if _, err := s.Writer(); !errors.Is(err, ErrClosed) {
+		t.Errorf("closed store: err = %v, want ErrClosed", err)
+	}
+}
+
+func TestStore_WriterHeartbeat(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1, WithWriterHeartbeat(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Writer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Interval != time.Millisecond {
+		t.Errorf("Interval = %v", first.Interval)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w, err := s.Writer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Heartbeat.After(first.Heartbeat) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("heartbeat never advanced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s.heartbeat != nil {
+		t.Error("Close left the heartbeat running")
+	}
+}
+
+func TestStore_WriterStaleHeartbeat(t *testing.T) {
+	s := mustCreateStore(t)
+	defer s.Close()
+
+	s.writer.beat.Store(uint64(time.Now().Add(-time.Minute).UnixNano()))
+	s.writer.interval.Store(uint64(time.Second))
+	if w, err := s.Writer(); err != nil || w.Alive {
+		t.Errorf("stale heartbeat: %+v, %v", w, err)
+	}
+}
+
+func TestStore_WriterInHeader(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1, WithWriterHeartbeat(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid := binary.LittleEndian.Uint64(data[72:80]); pid != uint64(os.Getpid()) {
+		t.Errorf("header pid = %d, want %d", pid, os.Getpid())
+	}
+	if iv := binary.LittleEndian.Uint64(data[88:96]); iv != uint64(time.Hour) {
+		t.Errorf("header interval = %d", iv)
+	}
+
+	// A writer that took over the record keeps it when this one closes.
+	s.writer.pid.Store(1 << 30)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid := binary.LittleEndian.Uint64(data[72:80]); pid != 1<<30 {
+		t.Errorf("header pid after close = %d, want the other writer's", pid)
+	}
+
+	// The next writer replaces a record left behind by a dead one.
+	s, err = OpenStore(path, testLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if w, err := s.Writer(); err != nil || w.PID != os.Getpid() || w.Interval != 0 {
+		t.Errorf("Writer after reopen = %+v, %v", w, err)
+	}
+}
+
+func TestStore_WriterNone(t *testing.T) {
+	m, err := CreateStore(tempPath(t), testLayout(), 1, WithMultiWriter(), WithWriterHeartbeat(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if w, err := m.Writer(); err != nil || w.PID != 0 || m.heartbeat != nil {
+		t.Errorf("multi-writer store: %+v, %v", w, err)
+	}
+
+	path := tempPath(t)
+	writeV1File(t, path, 1)
+	v1, err := OpenStore(path, testLayout(), WithWriterHeartbeat(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v1.Close()
+	if w, err := v1.Writer(); err != nil || w.PID != 0 || v1.heartbeat != nil {
+		t.Errorf("format version 1 store: %+v, %v", w, err)
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !processAlive(os.Getpid()) {
+		t.Error("own process reported dead")
+	}
+	if processAlive(1 << 30) {
+		t.Error("impossible pid reported alive")
+	}
+}
@@ -0,0 +1,261 @@
+package mmapforge
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"unsafe"
+)
+
+// TypedStore is a generic Store for a plain Go struct T, built by reflection
+// at open time instead of by code generation. T's fields follow the same
+// rules as the generator: every named field is stored, `mmap:"name,max_size"`
+// sets the field name and max size, and string and []byte fields require a
+// max size.
+type TypedStore[T any] struct {
+	*Store
+	fields []typedField
+}
+
+// typedField maps one stored field to its location inside T.
+type typedField struct {
+	FieldLayout
+	goOffset uintptr
+}
+
+// Open opens the store for T at path, creating it if it does not exist.
+// The layout is derived from T's struct tags once; Get, Set and Append then
+// bounds-check each record once and copy fields straight from their
+// precomputed offsets, producing the same bytes as generated stores.
+//
+// Fields are copied in host byte order, so Open fails with an error
+// wrapping errors.ErrUnsupported on big-endian hosts.
+func Open[T any](path string, opts ...StoreOption) (*TypedStore[T], error) {
+	rt := reflect.TypeFor[T]()
+	if !littleEndian {
+		return nil, fmt.Errorf("mmapforge: typed store %v: big-endian host: %w", rt, errors.ErrUnsupported)
+	}
+	layout, goOffsets, err := layoutOf(rt)
+	if err != nil {
+		return nil, err
+	}
+
+	var s *Store
+	if _, statErr := os.Stat(path); errors.Is(statErr, os.ErrNotExist) {
+		cfg := applyOptions(opts)
+		s, err = CreateStore(path, layout, cfg.schemaVersion, opts...)
+	} else {
+		s, err = OpenStore(path, layout, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]typedField, len(layout.Fields))
+	for i, f := range layout.Fields {
+		fields[i] = typedField{FieldLayout: f, goOffset: goOffsets[i]}
+	}
+	return &TypedStore[T]{Store: s, fields: fields}, nil
+}
+
+// Get reads all fields atomically for the record at idx.
+// String and []byte fields are zero-copy and valid only until Close.
+func (t *TypedStore[T]) Get(idx int) (T, error) {
+	return t.GetCtx(context.Background(), idx)
+}
+
+// GetCtx is Get, giving up with ctx's error if ctx is done while a writer
+// holds the record.
+func (t *TypedStore[T]) GetCtx(ctx context.Context, idx int) (T, error) {
+	var v T
+	for attempt := 0; ; attempt++ {
+		seq := t.SeqReadBegin(idx)
+		if seq&1 == 0 {
+			if err := t.read(idx, unsafe.Pointer(&v)); err != nil {
+				var zero T
+				return zero, err
+			}
+			if t.SeqReadValid(idx, seq) {
+				return v, nil
+			}
+		}
+		if err := t.SeqReadRetry(ctx, idx, attempt); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+}
+
+// Set writes all fields atomically for the record at idx.
+func (t *TypedStore[T]) Set(idx int, v *T) error {
+	src := unsafe.Pointer(v)
+	for i := range t.fields {
+		f := &t.fields[i]
+		if n := f.varLen(src); n > int(f.MaxSize) {
+			return t.fieldError(idx, f.Offset, n, f.MaxSize, f.tooLong())
+		}
+	}
+	rec, err := t.fieldSlice(idx, 0, uint32(t.recordSize))
+	if err != nil {
+		return err
+	}
+	if !t.writable {
+		return fmt.Errorf("mmapforge: typed store set %s: %w", t.path, ErrReadOnly)
+	}
+
+	t.SeqBeginWrite(idx)
+	defer t.SeqEndWrite(idx)
+	t.write(rec, src)
+	return nil
+}
+
+// Append adds a new record holding v and returns its index.
+func (t *TypedStore[T]) Append(v *T) (int, error) {
+	idx, err := t.Store.Append()
+	if err != nil {
+		return 0, err
+	}
+	return idx, t.Set(idx, v)
+}
+
+// read copies record idx into the T at dst. The record is bounds-checked
+// once; fixed-size fields are then copied straight from their offsets and
+// string and []byte fields alias the mapping, as ReadString and ReadBytes do.
+func (t *TypedStore[T]) read(idx int, dst unsafe.Pointer) error {
+	rec, err := t.fieldSlice(idx, 0, uint32(t.recordSize))
+	if err != nil {
+		return err
+	}
+	for i := range t.fields {
+		f := &t.fields[i]
+		p := unsafe.Add(dst, f.goOffset)
+		b := rec[f.Offset : f.Offset+f.Size]
+		switch f.Type {
+		case FieldString, FieldBytes:
+			n := binary.LittleEndian.Uint32(b)
+			if n > f.MaxSize {
+				return t.fieldError(idx, f.Offset, int(n), f.MaxSize, ErrCorrupted)
+			}
+			if f.Type == FieldBytes {
+				*(*[]byte)(p) = b[4 : 4+n]
+			} else if n == 0 {
+				*(*string)(p) = ""
+			} else {
+				*(*string)(p) = unsafe.String(&b[4], int(n))
+			}
+		case FieldBool:
+			if b[0] > 1 {
+				return t.fieldError(idx, f.Offset, 0, 0, fmt.Errorf("%w (got %#x)", ErrInvalidBool, b[0]))
+			}
+			*(*byte)(p) = b[0]
+		default:
+			copy(unsafe.Slice((*byte)(p), f.Size), b)
+		}
+	}
+	return nil
+}
+
+// write copies the T at src into rec, the bytes of one record. Lengths of
+// string and []byte fields must already have been checked against MaxSize.
+func (t *TypedStore[T]) write(rec []byte, src unsafe.Pointer) {
+	for i := range t.fields {
+		f := &t.fields[i]
+		p := unsafe.Add(src, f.goOffset)
+		b := rec[f.Offset : f.Offset+f.Size]
+		switch f.Type {
+		case FieldString, FieldBytes:
+			var n int
+			if f.Type == FieldString {
+				n = copy(b[4:], *(*string)(p))
+			} else {
+				n = copy(b[4:], *(*[]byte)(p))
+			}
+			// Length prefix via unsafe as in WriteString: n <= MaxSize, so
+			// its low 32 bits are the value on a little-endian host.
+			*(*uint32)(unsafe.Pointer(&b[0])) = *(*uint32)(unsafe.Pointer(&n))
+			clear(b[4+n:])
+		default:
+			copy(b, unsafe.Slice((*byte)(p), f.Size))
+		}
+	}
+}
+
+// varLen returns the length of a string or []byte field in src, or 0 for
+// fixed-size fields.
+func (f *typedField) varLen(src unsafe.Pointer) int {
+	p := unsafe.Add(src, f.goOffset)
+	switch f.Type {
+	case FieldString:
+		return len(*(*string)(p))
+	case FieldBytes:
+		return len(*(*[]byte)(p))
+	default:
+		return 0
+	}
+}
+
+// tooLong returns the sentinel for a string or []byte exceeding MaxSize.
+func (f *typedField) tooLong() error {
+	if f.Type == FieldBytes {
+		return ErrBytesTooLong
+	}
+	return ErrStringTooLong
+}
+
+// layoutOf reflects over the struct type rt and returns its RecordLayout
+// together with the in-memory offset of each stored field.
+func layoutOf(rt reflect.Type) (*RecordLayout, []uintptr, error) {
+	if rt.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("mmapforge: typed store: %v is not a struct", rt)
+	}
+
+	defs := make([]FieldDef, 0, rt.NumField())
+	offsets := make([]uintptr, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.Anonymous {
+			continue
+		}
+
+		ft, err := ParseGoType(goTypeString(sf.Type))
+		if err != nil {
+			return nil, nil, fmt.Errorf("mmapforge: typed store: %v field %s: %w", rt, sf.Name, err)
+		}
+
+		name, maxSize, err := ParseFieldTag(sf.Tag.Get("mmap"), sf.Name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("mmapforge: typed store: %v field %s: %w", rt, sf.Name, err)
+		}
+		if (ft == FieldString || ft == FieldBytes) && maxSize == 0 {
+			return nil, nil, fmt.Errorf("mmapforge: typed store: %v field %s: max_size required for %v", rt, sf.Name, sf.Type)
+		}
+
+		defs = append(defs, FieldDef{
+			Name:    name,
+			GoName:  sf.Name,
+			Type:    ft,
+			MaxSize: maxSize,
+		})
+		offsets = append(offsets, sf.Offset)
+	}
+
+	layout, err := ComputeLayout(defs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mmapforge: typed store: %v: %w", rt, err)
+	}
+	return layout, offsets, nil
+}
+
+// goTypeString spells the reflected type t the way it appears in source,
+// so ParseGoType treats it exactly as the generator treats the AST: named
+// types such as `type Price float64` keep their package-qualified name and
+// are rejected.
+func goTypeString(t reflect.Type) string {
+	if t.Kind() == reflect.Slice && t.Name() == "" && t.Elem() == reflect.TypeFor[byte]() {
+		return "[]byte"
+	}
+	return t.String()
+}
@@ -0,0 +1,416 @@
+package mmapforge
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type typedAll struct {
+	Flag   bool
+	I8     int8
+	U8     uint8
+	I16    int16
+	U16    uint16
+	I32    int32
+	U32    uint32
+	I64    int64
+	U64    uint64
+	F32    float32
+	F64    float64
+	Name   string `mmap:"name,16"`
+	Data   []byte `mmap:"data,8"`
+	Status uint8  `mmap:"state"`
+}
+
+func typedAllValue() typedAll {
+	return typedAll{
+		Flag: true, I8: -8, U8: 200, I16: -1234, U16: 54321,
+		I32: -100000, U32: 3000000000, I64: -9000000000, U64: 18000000000000,
+		F32: 1.5, F64: 2.5, Name: "hello", Data: []byte{1, 2, 3}, Status: 7,
+	}
+}
+
+func assertTypedAll(t *testing.T, got, want typedAll) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get = %+v, want %+v", got, want)
+	}
+}
+
+func TestOpen_CreatesAndRoundTrips(t *testing.T) {
+	path := tempPath(t)
+	s, err := Open[typedAll](path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	want := typedAllValue()
+	idx, err := s.Append(&want)
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	got, err := s.Get(idx)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertTypedAll(t, got, want)
+}
+
+func TestOpen_Reopen(t *testing.T) {
+	path := tempPath(t)
+	s, err := Open[typedAll](path, WithSchemaVersion(3))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	want := typedAllValue()
+	if _, err := s.Append(&want); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	s, err = Open[typedAll](path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+
+	if s.header.SchemaVersion != 3 {
+		t.Errorf("SchemaVersion = %d, want 3", s.header.SchemaVersion)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", s.Len())
+	}
+	got, err := s.Get(0)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertTypedAll(t, got, want)
+}
+
+func TestOpen_CompatibleWithComputeLayout(t *testing.T) {
+	type tick struct {
+		Symbol string  `mmap:"symbol,8"`
+		Price  float64 `mmap:"price"`
+		Volume uint32
+	}
+
+	layout, err := ComputeLayout([]FieldDef{
+		{Name: "symbol", GoName: "Symbol", Type: FieldString, MaxSize: 8},
+		{Name: "price", GoName: "Price", Type: FieldFloat64},
+		{Name: "volume", GoName: "Volume", Type: FieldUint32},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := tempPath(t)
+	base, err := CreateStore(path, layout, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, _ := base.Append()
+	sym := layout.Fields[0]
+	if err := base.WriteString(idx, sym.Offset, sym.Size, sym.MaxSize, "AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	if err := base.WriteFloat64(idx, layout.Fields[1].Offset, 189.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := base.WriteUint32(idx, layout.Fields[2].Offset, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := base.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open[tick](path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	got, err := s.Get(0)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got != (tick{Symbol: "AAPL", Price: 189.5, Volume: 42}) {
+		t.Errorf("Get = %+v", got)
+	}
+}
+
+func TestOpen_SkipsEmbeddedFields(t *testing.T) {
+	type inner struct{ X int }
+	type withEmbed struct {
+		inner
+		ID uint64
+	}
+
+	s, err := Open[withEmbed](tempPath(t))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	if len(s.layout.Fields) != 1 || s.layout.Fields[0].Name != "id" {
+		t.Errorf("fields = %+v, want only id", s.layout.Fields)
+	}
+}
+
+type (
+	typedPrice float64
+	typedBlob  []byte
+)
+
+func TestOpen_InvalidTypes(t *testing.T) {
+	cases := []struct {
+		name string
+		open func(string) error
+		want string
+	}{
+		{"not struct", func(p string) error { _, err := Open[int](p); return err }, "is not a struct"},
+		{"unsupported", func(p string) error {
+			_, err := Open[struct{ N int }](p)
+			return err
+		}, `unsupported type "int"`},
+		{"named scalar", func(p string) error {
+			_, err := Open[struct{ P typedPrice }](p)
+			return err
+		}, `unsupported type "mmapforge.typedPrice"`},
+		{"named bytes", func(p string) error {
+			_, err := Open[struct {
+				B typedBlob `mmap:"b,8"`
+			}](p)
+			return err
+		}, `unsupported type "mmapforge.typedBlob"`},
+		{"no max size", func(p string) error {
+			_, err := Open[struct{ S string }](p)
+			return err
+		}, "max_size required"},
+		{"bad tag", func(p string) error {
+			_, err := Open[struct {
+				S string `mmap:"s,abc"`
+			}](p)
+			return err
+		}, "invalid max_size"},
+		{"duplicate", func(p string) error {
+			_, err := Open[struct {
+				A uint8 `mmap:"x"`
+				B uint8 `mmap:"x"`
+			}](p)
+			return err
+		}, "duplicate field name"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.open(tempPath(t))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestOpen_SchemaMismatch(t *testing.T) {
+	path := tempPath(t)
+	s, err := CreateStore(path, testLayout(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if _, err := Open[typedAll](path); err == nil {
+		t.Fatal("expected schema mismatch error")
+	}
+}
+
+func TestTypedStore_SetTooLong(t *testing.T) {
+	s, err := Open[typedAll](tempPath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	v := typedAllValue()
+	idx, err := s.Append(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	long := v
+	long.Name = strings.Repeat("x", 17)
+	err = s.Set(idx, &long)
+	var fe *FieldError
+	if !errors.Is(err, ErrStringTooLong) || !errors.As(err, &fe) || fe.Field != "name" {
+		t.Errorf("Set long string err = %v, want FieldError for name", err)
+	}
+
+	long = v
+	long.Data = make([]byte, 9)
+	if err := s.Set(idx, &long); !errors.Is(err, ErrBytesTooLong) {
+		t.Errorf("Set long bytes err = %v, want ErrBytesTooLong", err)
+	}
+
+	got, err := s.Get(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTypedAll(t, got, v)
+}
+
+func TestTypedStore_OutOfBounds(t *testing.T) {
+	s, err := Open[typedAll](tempPath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Get(0); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("Get err = %v, want ErrOutOfBounds", err)
+	}
+	v := typedAllValue()
+	if err := s.Set(0, &v); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("Set err = %v, want ErrOutOfBounds", err)
+	}
+}
+
+func TestTypedStore_AppendReadOnly(t *testing.T) {
+	path := tempPath(t)
+	s, err := Open[typedAll](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := typedAllValue()
+	if _, err := s.Append(&v); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = Open[typedAll](path, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Append(&v); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Append err = %v, want ErrReadOnly", err)
+	}
+	if err := s.Set(0, &v); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Set err = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestTypedStore_GetCorrupted(t *testing.T) {
+	s, err := Open[typedAll](tempPath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	v := typedAllValue()
+	idx, err := s.Append(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := s.region.Slice(s.dataOff+idx*s.recordSize, s.recordSize)
+
+	name := s.fields[11]
+	binary.LittleEndian.PutUint32(rec[name.Offset:], name.MaxSize+1)
+	if _, err := s.Get(idx); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Get err = %v, want ErrCorrupted", err)
+	}
+	binary.LittleEndian.PutUint32(rec[name.Offset:], 0)
+
+	rec[s.fields[0].Offset] = 2
+	if _, err := s.Get(idx); !errors.Is(err, ErrInvalidBool) {
+		t.Errorf("Get err = %v, want ErrInvalidBool", err)
+	}
+	rec[s.fields[0].Offset] = 1
+
+	got, err := s.Get(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "" {
+		t.Errorf("Name = %q, want empty", got.Name)
+	}
+}
+
+func TestTypedStore_SetShorterClearsTail(t *testing.T) {
+	s, err := Open[typedAll](tempPath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	v := typedAllValue()
+	idx, err := s.Append(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Name = "ab"
+	v.Data = v.Data[:0]
+	if err := s.Set(idx, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := s.region.Slice(s.dataOff+idx*s.recordSize, s.recordSize)
+	for _, name := range []string{"name", "data"} {
+		f, _ := s.Field(name)
+		b := rec[f.Offset : f.Offset+f.Size]
+		n := int(binary.LittleEndian.Uint32(b))
+		for i, c := range b[4+n:] {
+			if c != 0 {
+				t.Fatalf("%s byte %d after the value = %#x, want 0", name, 4+n+i, c)
+			}
+		}
+	}
+	got, err := s.Get(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTypedAll(t, got, v)
+}
+
+func TestTypedStore_LittleEndianOnDisk(t *testing.T) {
+	s, err := Open[typedAll](tempPath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	v := typedAllValue()
+	idx, err := s.Append(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := s.region.Slice(s.dataOff+idx*s.recordSize, s.recordSize)
+	at := func(name string) []byte {
+		f, _ := s.Field(name)
+		return rec[f.Offset:]
+	}
+
+	le := binary.LittleEndian
+	if got := int16(le.Uint16(at("i16"))); got != v.I16 {
+		t.Errorf("i16 on disk = %d, want %d", got, v.I16)
+	}
+	if got := int32(le.Uint32(at("i32"))); got != v.I32 {
+		t.Errorf("i32 on disk = %d, want %d", got, v.I32)
+	}
+	if got := int64(le.Uint64(at("i64"))); got != v.I64 {
+		t.Errorf("i64 on disk = %d, want %d", got, v.I64)
+	}
+	if got := le.Uint32(at("u32")); got != v.U32 {
+		t.Errorf("u32 on disk = %d, want %d", got, v.U32)
+	}
+	if got := le.Uint32(at("name")); got != uint32(len(v.Name)) {
+		t.Errorf("name length prefix = %d, want %d", got, len(v.Name))
+	}
+	if got := le.Uint32(at("data")); got != uint32(len(v.Data)) {
+		t.Errorf("data length prefix = %d, want %d", got, len(v.Data))
+	}
+}